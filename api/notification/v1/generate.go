@@ -0,0 +1,4 @@
+// Package notificationv1 holds the grpc api. the go code is generated from notification.proto
+package notificationv1
+
+//go:generate protoc -I ../.. --go_out=../.. --go_opt=paths=source_relative --go-grpc_out=../.. --go-grpc_opt=paths=source_relative notification/v1/notification.proto
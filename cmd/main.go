@@ -4,24 +4,30 @@ import (
 	// "os"
 
 	"context"
+	"fmt"
 	"os"
 	"os/signal"
+	"syscall"
 
 	"github.com/joho/godotenv"
 	"github.com/joseCarlosAndrade/notification-server/internal/core/config"
 	log "github.com/joseCarlosAndrade/notification-server/internal/core/domain/logger"
 	"github.com/joseCarlosAndrade/notification-server/internal/di"
-	"github.com/kelseyhightower/envconfig"
 	"go.uber.org/zap"
 )
 
 
 func loadEnv() {
 	_ = godotenv.Overload(".env")
-	
-	if err := envconfig.Process("app", &config.App); err != nil {
-		panic(err)
+
+	// env vars and the optional config file. every invalid setting is reported at once
+	app, err := config.Load()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
 	}
+
+	config.App = app
 }
 
 func main() {
@@ -36,13 +42,13 @@ func main() {
 	
 	log.L(ctx).Info("starting app")
 
-	ctx, cancel := signal.NotifyContext(ctx, os.Interrupt)
+	ctx, cancel := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM) // sigterm is how orchestrators stop the container
 	defer cancel()
 
 	container := di.NewContainer(ctx)
 
 	if err := container.Run(ctx); err != nil  { // application somehow exited with failures
-		log.L(context.Background()).Warn("container was closed due to errors")
+		log.L(context.Background()).Warn("container was closed due to errors", zap.Error(err))
 	}
 
 	log.L(context.Background()).Info("app exited")
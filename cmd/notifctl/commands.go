@@ -0,0 +1,311 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain/models"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain/port"
+	"github.com/joseCarlosAndrade/notification-server/internal/di"
+)
+
+// timeFlag is an RFC 3339 timestamp, or a duration before now (24h)
+type timeFlag struct {
+	time.Time
+}
+
+func (f *timeFlag) String() string {
+	if f.IsZero() {
+		return ""
+	}
+
+	return f.Format(time.RFC3339)
+}
+
+func (f *timeFlag) Set(value string) error {
+	if d, err := time.ParseDuration(value); err == nil {
+		f.Time = domain.NewNowTime().Add(-d)
+		return nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return errors.New("must be an RFC 3339 timestamp or a duration before now")
+	}
+
+	f.Time = t
+
+	return nil
+}
+
+func list(fs *flag.FlagSet) runFunc {
+	var since, until timeFlag
+
+	service := fs.String("service", "", "service of the notifications. required")
+	recipient := fs.String("recipient", "", "only the notifications of this recipient")
+	search := fs.String("search", "", "only the notifications whose title or message contain this text")
+	unread := fs.Bool("unread", false, "only the unread notifications")
+	hidden := fs.Bool("hidden", false, "also the scheduled, digest and suppressed notifications")
+	archived := fs.Bool("archived", false, "also the archived notifications")
+	limit := fs.Int("limit", 50, "maximum number of notifications. 0 lists all")
+	offset := fs.Int("offset", 0, "notifications to skip")
+	fs.Var(&since, "since", "only the notifications sent at or after this time")
+	fs.Var(&until, "until", "only the notifications sent before this time")
+	format := outputFlag(fs)
+
+	return func(ctx context.Context, tools *di.Tools, args []string) error {
+		if *service == "" {
+			return errors.New("-service is required")
+		}
+
+		notifications, err := tools.Storage.ListNotifications(ctx, models.NotificationFilter{
+			Service:         *service,
+			Recipient:       *recipient,
+			Since:           since.Time,
+			Until:           until.Time,
+			UnreadOnly:      *unread,
+			Search:          *search,
+			IncludeHidden:   *hidden,
+			IncludeArchived: *archived,
+			Limit:           *limit,
+			Offset:          *offset,
+		})
+		if err != nil {
+			return err
+		}
+
+		return write(*format, notifications, func(t *table) {
+			t.row("ID", "SENT AT", "RECIPIENT", "STATUS", "READ", "ARCHIVED", "TITLE", "MESSAGE")
+
+			for _, n := range notifications {
+				t.row(n.ID, n.SentAt.Format(time.RFC3339), n.Recipient, string(n.Status), yesNo(n.IsRead),
+					yesNo(n.ArchivedAt != nil), truncate(n.Title, 30), truncate(n.Message, 60))
+			}
+		})
+	}
+}
+
+func markRead(fs *flag.FlagSet) runFunc {
+	return func(ctx context.Context, tools *di.Tools, ids []string) error {
+		if len(ids) == 0 {
+			return errors.New("no notification id given")
+		}
+
+		var errs []error
+
+		for _, id := range ids {
+			if err := tools.Storage.MarkNotificationAsRead(ctx, id); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", id, err))
+				continue
+			}
+
+			fmt.Printf("%s marked as read\n", id)
+		}
+
+		return errors.Join(errs...)
+	}
+}
+
+func deleteNotifications(fs *flag.FlagSet) runFunc {
+	var since, until timeFlag
+
+	service := fs.String("service", "", "service of the notifications. required")
+	recipient := fs.String("recipient", "", "only the notifications of this recipient")
+	fs.Var(&since, "since", "only the notifications sent at or after this time")
+	fs.Var(&until, "until", "only the notifications sent before this time")
+	yes := fs.Bool("yes", false, "confirm the deletion. notifications are deleted whatever their status")
+
+	return func(ctx context.Context, tools *di.Tools, args []string) error {
+		if *service == "" {
+			return errors.New("-service is required")
+		}
+
+		if !*yes {
+			return errors.New("deleting can't be undone. pass -yes to confirm")
+		}
+
+		deleted, err := tools.Storage.DeleteNotifications(ctx, models.DeleteFilter{
+			Service:   *service,
+			Recipient: *recipient,
+			Since:     since.Time,
+			Until:     until.Time,
+		})
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("%d notifications deleted\n", deleted)
+
+		return nil
+	}
+}
+
+func stats(fs *flag.FlagSet) runFunc {
+	format := outputFlag(fs)
+
+	return func(ctx context.Context, tools *di.Tools, args []string) error {
+		stats, err := tools.Storage.NotificationStats(ctx)
+		if err != nil {
+			return err
+		}
+
+		return write(*format, stats, func(t *table) {
+			t.row("SERVICE", "TOTAL", "UNREAD", "PENDING", "DIGEST", "SUPPRESSED", "OLDEST", "NEWEST")
+
+			for _, s := range stats {
+				t.row(s.Service, s.Total, s.Unread, s.ByStatus[models.StatusPending],
+					s.ByStatus[models.StatusDigestPending]+s.ByStatus[models.StatusDigested],
+					s.ByStatus[models.StatusSuppressed], s.Oldest.Format(time.RFC3339), s.Newest.Format(time.RFC3339))
+			}
+		})
+	}
+}
+
+func migrate(fs *flag.FlagSet) runFunc {
+	dropStale := fs.Bool("drop-stale", false, "also drop the indexes the storage no longer uses")
+	format := outputFlag(fs)
+
+	return func(ctx context.Context, tools *di.Tools, args []string) error {
+		migrator, ok := tools.Storage.(port.IndexMigrator)
+		if !ok {
+			return errors.New("the configured storage has no indexes")
+		}
+
+		migration, err := migrator.MigrateIndexes(ctx, *dropStale)
+		if err != nil {
+			return err
+		}
+
+		return write(*format, migration, func(t *table) {
+			t.row("INDEX", "ACTION")
+
+			for _, changes := range []struct {
+				action  string
+				indexes []string
+			}{{"created", migration.Created}, {"dropped", migration.Dropped}, {"kept", migration.Kept}} {
+				for _, index := range changes.indexes {
+					t.row(index, changes.action)
+				}
+			}
+		})
+	}
+}
+
+func checkHealth(fs *flag.FlagSet) runFunc {
+	format := outputFlag(fs)
+
+	return func(ctx context.Context, tools *di.Tools, args []string) error {
+		report := tools.Health(ctx)
+
+		err := write(*format, report, func(t *table) {
+			t.row("DEPENDENCY", "STATUS", "CRITICAL", "ERROR")
+
+			for _, name := range sortedKeys(report.Dependencies) {
+				d := report.Dependencies[name]
+				t.row(name, string(d.Status), yesNo(d.Critical), d.LastError)
+			}
+		})
+		if err != nil {
+			return err
+		}
+
+		if report.Status == models.HealthDown {
+			return errors.New("a critical dependency is down")
+		}
+
+		return nil
+	}
+}
+
+func export(fs *flag.FlagSet) runFunc {
+	var since, until timeFlag
+
+	service := fs.String("service", "", "service of the notifications. required")
+	recipient := fs.String("recipient", "", "only the notifications of this recipient")
+	fs.Var(&since, "since", "only the notifications sent at or after this time")
+	fs.Var(&until, "until", "only the notifications sent before this time")
+	format := fs.String("format", string(models.ExportNDJSON), "ndjson, which can be imported back, or csv")
+	out := fs.String("out", "-", "file to write. - writes to stdout")
+
+	return func(ctx context.Context, tools *di.Tools, args []string) error {
+		w := os.Stdout
+		if *out != "-" {
+			f, err := os.Create(*out)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+
+			w = f
+		}
+
+		buffered := bufio.NewWriter(w)
+
+		err := tools.Service.ExportNotifications(ctx, models.ExportFilter{
+			Service:   *service,
+			Recipient: *recipient,
+			Since:     since.Time,
+			Until:     until.Time,
+		}, models.ExportFormat(*format), buffered)
+		if err != nil {
+			return err
+		}
+
+		if err := buffered.Flush(); err != nil {
+			return err
+		}
+
+		return w.Sync()
+	}
+}
+
+func restore(fs *flag.FlagSet) runFunc {
+	return func(ctx context.Context, tools *di.Tools, args []string) error {
+		if len(args) != 1 {
+			return errors.New("give the ndjson file to import. - reads stdin")
+		}
+
+		r := os.Stdin
+		if args[0] != "-" {
+			f, err := os.Open(args[0])
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+
+			r = f
+		}
+
+		result, err := tools.Service.ImportNotifications(ctx, r)
+		fmt.Fprintf(os.Stderr, "%d notifications imported\n", result.Imported)
+
+		return err
+	}
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+
+	return "no"
+}
+
+// truncate shortens s to n runes, on a single line
+func truncate(s string, n int) string {
+	s = strings.Join(strings.Fields(s), " ")
+
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+
+	return string(runes[:n-1]) + "…"
+}
@@ -0,0 +1,129 @@
+// notifctl operates the notification store with the configuration of the server, so it can be run from a
+// shell in the pod
+//
+//	notifctl list -service payments -search refund -since 24h
+//	notifctl read 0190d3c5-...
+//	notifctl delete -service payments -until 2024-01-01T00:00:00Z -yes
+//	notifctl stats -o json
+//	notifctl export -service payments -since 2024-01-01T00:00:00Z -format csv -out payments.csv
+//	notifctl import payments.ndjson
+//	notifctl migrate -drop-stale
+//	notifctl health
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/joho/godotenv"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/config"
+	log "github.com/joseCarlosAndrade/notification-server/internal/core/domain/logger"
+	"github.com/joseCarlosAndrade/notification-server/internal/di"
+	"go.uber.org/zap"
+)
+
+// runFunc runs a command with the arguments left after its flags. the returned error is printed and exits with 1
+type runFunc func(ctx context.Context, tools *di.Tools, args []string) error
+
+type command struct {
+	usage string
+	// setup registers the flags of the command and returns the func that runs it once they are parsed, so the
+	// flags are checked before connecting to anything
+	setup func(fs *flag.FlagSet) runFunc
+}
+
+var commands = map[string]command{
+	"list":    {"list or search the notifications of a service", list},
+	"read":    {"mark notifications as read by id", markRead},
+	"delete":  {"delete the notifications of a service sent in a time range", deleteNotifications},
+	"stats":   {"show the notification counts of every service", stats},
+	"migrate": {"create the missing storage indexes", migrate},
+	"health":  {"check every dependency", checkHealth},
+	"export":  {"export the notifications of a service as ndjson or csv", export},
+	"import":  {"restore the notifications of an ndjson export", restore},
+}
+
+func loadEnv() {
+	_ = godotenv.Overload(".env")
+
+	app, err := config.Load()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	config.App = app
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: notifctl [-v] <command> [flags]\n\ncommands:\n")
+
+	for _, name := range []string{"list", "read", "delete", "stats", "export", "import", "migrate", "health"} {
+		fmt.Fprintf(os.Stderr, "  %-8s %s\n", name, commands[name].usage)
+	}
+
+	fmt.Fprintf(os.Stderr, "\nrun notifctl <command> -h for the flags of a command\n")
+}
+
+func main() {
+	verbose := flag.Bool("v", false, "log at the configured level. only errors are logged otherwise")
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		usage()
+		os.Exit(2)
+	}
+
+	name := flag.Arg(0)
+
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", name)
+		usage()
+		os.Exit(2)
+	}
+
+	fs := flag.NewFlagSet("notifctl "+name, flag.ExitOnError)
+	runCmd := cmd.setup(fs)
+	_ = fs.Parse(flag.Args()[1:]) // exits on error
+
+	loadEnv()
+
+	// logs are written to stdout, so they would mix with the output
+	if !*verbose {
+		config.App.LogLevel = "error"
+	}
+
+	logger := log.InitLogger()
+	defer logger.Sync()
+
+	undo := zap.ReplaceGlobals(logger)
+	defer undo()
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	if err := run(ctx, runCmd, fs.Args()); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// run connects to the dependencies. they panic when they can't connect, which is reported as an error
+func run(ctx context.Context, runCmd runFunc, args []string) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+
+	tools := di.NewTools(ctx)
+	defer tools.Close(context.Background())
+
+	return runCmd(ctx, tools, args)
+}
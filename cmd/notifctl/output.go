@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+)
+
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+)
+
+// outputFlag registers the -o flag
+func outputFlag(fs *flag.FlagSet) *string {
+	return fs.String("o", formatTable, "output format: table or json")
+}
+
+// table aligns the rows in columns
+type table struct {
+	w *tabwriter.Writer
+}
+
+func (t *table) row(columns ...any) {
+	values := make([]string, len(columns))
+	for i, c := range columns {
+		values[i] = fmt.Sprint(c)
+	}
+
+	fmt.Fprintln(t.w, strings.Join(values, "\t"))
+}
+
+// write writes value as indented json or as the table built by fill
+func write(format string, value any, fill func(t *table)) error {
+	switch format {
+	case formatJSON:
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+
+		return encoder.Encode(value)
+	case formatTable:
+		t := &table{w: tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)}
+		fill(t)
+
+		return t.w.Flush()
+	}
+
+	return fmt.Errorf("unknown output format %q", format)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	return slices.Sorted(maps.Keys(m))
+}
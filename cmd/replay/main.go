@@ -0,0 +1,173 @@
+// replay re-ingests a range of a topic into the storage through the same processing path as the server,
+// without joining its consumer group. the notification ids are derived from the record positions, so
+// replaying records that were already stored overwrites them instead of duplicating
+//
+//	replay -from 2024-05-01T00:00:00Z -to 2024-05-02T00:00:00Z
+//	replay -dead-letter -dry-run
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/joseCarlosAndrade/notification-server/internal/adapter/redpanda"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/config"
+	log "github.com/joseCarlosAndrade/notification-server/internal/core/domain/logger"
+	"github.com/joseCarlosAndrade/notification-server/internal/di"
+	"go.uber.org/zap"
+)
+
+func loadEnv() {
+	_ = godotenv.Overload(".env")
+
+	app, err := config.Load()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	config.App = app
+}
+
+func main() {
+	topic := flag.String("topic", "", "topic to replay. defaults to the notification topic")
+	deadLetter := flag.Bool("dead-letter", false, "replay the dead letter topic")
+	partitions := flag.String("partitions", "", "comma separated partitions to replay. defaults to every partition")
+	fromOffset := flag.Int64("from-offset", -1, "first offset of each partition")
+	toOffset := flag.Int64("to-offset", -1, "offset each partition stops at, exclusive")
+	from := flag.String("from", "", "replay records produced at or after this RFC 3339 timestamp")
+	to := flag.String("to", "", "stop at the first record produced at or after this RFC 3339 timestamp")
+	dryRun := flag.Bool("dry-run", false, "only decode and validate the records")
+	progressEvery := flag.Duration("progress", 5*time.Second, "how often progress is printed. 0 disables it")
+	flag.Parse()
+
+	loadEnv()
+	logger := log.InitLogger()
+	defer logger.Sync()
+
+	undo := zap.ReplaceGlobals(logger)
+	defer undo()
+
+	rng, err := replayRange(*topic, *deadLetter, *partitions, *fromOffset, *toOffset, *from, *to)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	rng.DryRun = *dryRun
+	rng.ProgressEvery = *progressEvery
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	if err := run(ctx, rng); err != nil {
+		log.L(context.Background()).Error("replay failed", zap.Error(err))
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, rng redpanda.ReplayRange) error {
+	tools := di.NewTools(ctx)
+	defer tools.Close(context.Background())
+
+	replayer, err := tools.NewReplayer(ctx)
+	if err != nil {
+		return err
+	}
+	defer replayer.Close()
+
+	started := time.Now()
+
+	stats, err := replayer.Replay(ctx, rng, func(stats redpanda.ReplayStats) {
+		fmt.Fprintf(os.Stderr, "%d/%d records read, %d processed, %d failed (%s)\n",
+			stats.Read, stats.Total, stats.Processed, stats.Failed, time.Since(started).Round(time.Second))
+	})
+	if err != nil {
+		return fmt.Errorf("could not replay %s: %w", rng.Topic, err)
+	}
+
+	verb := "stored"
+	if rng.DryRun {
+		verb = "valid"
+	}
+
+	fmt.Printf("replayed %s: %d records read, %d %s, %d failed\n", rng.Topic, stats.Read, stats.Processed, verb,
+		stats.Failed)
+
+	return nil
+}
+
+// replayRange validates the flags
+func replayRange(topic string, deadLetter bool, partitions string, fromOffset, toOffset int64,
+	from, to string) (redpanda.ReplayRange, error) {
+	rng := redpanda.ReplayRange{
+		Topic:      topic,
+		FromOffset: fromOffset,
+		ToOffset:   toOffset,
+	}
+
+	switch {
+	case deadLetter && topic != "":
+		return rng, fmt.Errorf("-topic and -dead-letter are exclusive")
+	case deadLetter:
+		rng.Topic = config.App.DeadLetterTopic
+	case topic == "":
+		rng.Topic = config.App.NotificationTopic
+	}
+
+	if rng.Topic == "" {
+		return rng, fmt.Errorf("no topic to replay: set -topic or configure the topic")
+	}
+
+	if partitions != "" {
+		for _, p := range strings.Split(partitions, ",") {
+			partition, err := strconv.ParseInt(strings.TrimSpace(p), 10, 32)
+			if err != nil || partition < 0 {
+				return rng, fmt.Errorf("invalid partition %q", p)
+			}
+
+			rng.Partitions = append(rng.Partitions, int32(partition))
+		}
+	}
+
+	var err error
+	if rng.FromTime, err = parseTime(from, "-from"); err != nil {
+		return rng, err
+	}
+
+	if rng.ToTime, err = parseTime(to, "-to"); err != nil {
+		return rng, err
+	}
+
+	if !rng.FromTime.IsZero() && !rng.ToTime.IsZero() && !rng.ToTime.After(rng.FromTime) {
+		return rng, fmt.Errorf("-to must be after -from")
+	}
+
+	if fromOffset >= 0 && toOffset >= 0 && toOffset <= fromOffset {
+		return rng, fmt.Errorf("-to-offset must be greater than -from-offset")
+	}
+
+	return rng, nil
+}
+
+// parseTime returns the zero time for an empty value
+func parseTime(value, flagName string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s must be an RFC 3339 timestamp: %w", flagName, err)
+	}
+
+	return t, nil
+}
@@ -0,0 +1,214 @@
+package amqp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	log "github.com/joseCarlosAndrade/notification-server/internal/core/domain/logger"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain/port"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/eventsource"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"go.uber.org/zap"
+)
+
+const consumerTag = "notification-server"
+
+// EventsHub implements the EventsHub interface consuming an AMQP queue. deliveries are acked one by one, so
+// the ones not acked on shutdown are requeued by the broker when the channel closes. the connection is not
+// recovered: if it drops, Run fails and the container exits
+type EventsHub struct {
+	processor *eventsource.Processor
+	conn      *amqp.Connection
+	channel   *amqp.Channel
+	queue     string
+
+	deadLetterQueue string // empty when dead lettering is disabled
+
+	drainTimeout time.Duration
+	run          *eventsource.RunState // lets Close wait for Run to drain, if it was started
+}
+
+// makes sure EventsHub implements the interface
+var _ port.EventsHub = (*EventsHub)(nil)
+
+// NewEventsHub connects to the broker. at most prefetch deliveries are received before they are acked
+func NewEventsHub(ctx context.Context, processor *eventsource.Processor, url, queue, deadLetterQueue string, prefetch int,
+	drainTimeout time.Duration) (EventsHub, error) {
+	conn, err := amqp.Dial(url)
+	if err != nil {
+		return EventsHub{}, fmt.Errorf("could not connect to amqp broker: %w", err)
+	}
+
+	channel, err := conn.Channel()
+	if err != nil {
+		conn.Close()
+		return EventsHub{}, fmt.Errorf("could not open amqp channel: %w", err)
+	}
+
+	if err := channel.Qos(prefetch, 0, false); err != nil {
+		conn.Close()
+		return EventsHub{}, fmt.Errorf("could not set prefetch: %w", err)
+	}
+
+	log.L(ctx).Info("amqp started and connected",
+		zap.String("queue", queue),
+		zap.Int("prefetch", prefetch))
+
+	return EventsHub{
+		processor:       processor,
+		conn:            conn,
+		channel:         channel,
+		queue:           queue,
+		deadLetterQueue: deadLetterQueue,
+		drainTimeout:    drainTimeout,
+		run:             eventsource.NewRunState(),
+	}, nil
+}
+
+// Run implements port.Runner interface. once ctx is canceled it cancels the consumer and drains the
+// deliveries already received within the drain timeout
+func (e *EventsHub) Run(ctx context.Context) error {
+	if !e.run.Start() {
+		return nil
+	}
+	defer e.run.Stop()
+
+	ctx = log.WithComponent(ctx, log.ComponentEventsHub)
+
+	processCtx, release := eventsource.DrainContext(ctx, e.drainTimeout)
+	defer release()
+
+	deliveries, err := e.channel.Consume(e.queue, consumerTag, false, false, false, false, nil)
+	if err != nil {
+		return fmt.Errorf("could not consume queue %s: %w", e.queue, err)
+	}
+
+	// the deliveries channel is closed once the buffered deliveries are received
+	stopConsuming := context.AfterFunc(ctx, func() {
+		if err := e.channel.Cancel(consumerTag, false); err != nil {
+			log.L(ctx).Error("could not cancel consumer", zap.Error(err))
+		}
+	})
+	defer stopConsuming()
+
+	for delivery := range deliveries {
+		// drain timeout exceeded. the deliveries left are requeued when the channel closes
+		if processCtx.Err() != nil {
+			log.L(ctx).Warn("drain timeout exceeded. leaving deliveries unacked")
+			return nil
+		}
+
+		if err := e.processor.Process(processCtx, toMessage(e.queue, &delivery), e.deadLetterFunc()); err != nil {
+			if err := delivery.Nack(false, true); err != nil {
+				log.L(ctx).Error("could not nack delivery", zap.Error(err))
+			}
+
+			continue
+		}
+
+		if err := delivery.Ack(false); err != nil {
+			log.L(ctx).Error("could not ack delivery", zap.Error(err))
+		}
+	}
+
+	if ctx.Err() == nil {
+		return errors.New("amqp channel closed by the broker")
+	}
+
+	log.L(ctx).Warn("context canceled. received deliveries drained")
+
+	return nil
+}
+
+// Close implements port.Runner interface. it waits for Run to drain the received deliveries before closing
+// the connection, unless ctx expires first. it does not wait if Run was never started
+func (e *EventsHub) Close(ctx context.Context) error {
+	var err error
+
+	if !e.run.Wait(ctx) {
+		err = fmt.Errorf("could not wait for the deliveries to drain: %w", ctx.Err())
+	}
+
+	return errors.Join(err, e.conn.Close())
+}
+
+func (e *EventsHub) IsHealthy(ctx context.Context) error {
+	if e.conn.IsClosed() || e.channel.IsClosed() {
+		return errors.New("amqp connection is closed")
+	}
+
+	return nil
+}
+
+// Ping checks that the broker is reachable and that the queue exists, without consuming. used by the tools
+func Ping(ctx context.Context, url, queue string) error {
+	conn, err := amqp.Dial(url)
+	if err != nil {
+		return fmt.Errorf("could not connect to amqp broker: %w", err)
+	}
+	defer conn.Close()
+
+	channel, err := conn.Channel()
+	if err != nil {
+		return fmt.Errorf("could not open amqp channel: %w", err)
+	}
+
+	if _, err := channel.QueueDeclarePassive(queue, true, false, false, false, nil); err != nil {
+		return fmt.Errorf("could not find queue %s: %w", queue, err)
+	}
+
+	return nil
+}
+
+// toMessage translates the delivery for the processor. the correlation id property is passed as a header
+func toMessage(queue string, delivery *amqp.Delivery) *eventsource.Message {
+	message := &eventsource.Message{
+		System:   eventsource.SystemAMQP,
+		Source:   queue,
+		Position: strconv.FormatUint(delivery.DeliveryTag, 10),
+		Key:      []byte(delivery.RoutingKey),
+		Value:    delivery.Body,
+	}
+
+	for key, value := range delivery.Headers {
+		message.Headers = append(message.Headers, eventsource.Header{Key: key, Value: []byte(fmt.Sprint(value))})
+	}
+
+	if delivery.CorrelationId != "" {
+		message.Headers = append(message.Headers, eventsource.Header{Key: "correlation-id", Value: []byte(delivery.CorrelationId)})
+	}
+
+	return message
+}
+
+// deadLetterFunc is nil when dead lettering is disabled
+func (e *EventsHub) deadLetterFunc() eventsource.DeadLetterFunc {
+	if e.deadLetterQueue == "" {
+		return nil
+	}
+
+	return e.deadLetter
+}
+
+// deadLetter publishes the message to the dead letter queue with the failure reason in the headers
+func (e *EventsHub) deadLetter(ctx context.Context, message *eventsource.Message, reason error) error {
+	headers := amqp.Table{}
+	for _, h := range eventsource.DeadLetterHeaders(message, reason) {
+		headers[h.Key] = string(h.Value)
+	}
+
+	err := e.channel.PublishWithContext(ctx, "", e.deadLetterQueue, false, false, amqp.Publishing{
+		Headers:      headers,
+		ContentType:  "application/json",
+		DeliveryMode: amqp.Persistent,
+		Body:         message.Value,
+	})
+	if err != nil {
+		return fmt.Errorf("could not publish message to dead letter queue %s: %w", e.deadLetterQueue, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,191 @@
+package file
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	log "github.com/joseCarlosAndrade/notification-server/internal/core/domain/logger"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain/port"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/eventsource"
+	"go.uber.org/zap"
+)
+
+// Stdin is the path that reads the records from the standard input
+const Stdin = "-"
+
+// maxLineSize is the largest record accepted. longer lines stop the source
+const maxLineSize = 1 << 20
+
+// EventsHub implements the EventsHub interface reading newline delimited json records from a file or stdin.
+// it is meant for local development and backfills: Run returns once every line was read, and there is no
+// redelivery, so a line that could not be processed nor dead lettered stops the source
+type EventsHub struct {
+	processor *eventsource.Processor
+	path      string
+	reader    io.ReadCloser
+
+	deadLetterPath string // empty when dead lettering is disabled
+	deadLetterMu   sync.Mutex
+	deadLetterFile *os.File // opened on the first dead letter
+
+	drainTimeout time.Duration
+	run          *eventsource.RunState // lets Close wait for Run to drain, if it was started
+}
+
+// makes sure EventsHub implements the interface
+var _ port.EventsHub = (*EventsHub)(nil)
+
+// NewEventsHub opens path, or reads stdin if it is Stdin. lines that can't be processed are appended to
+// deadLetterPath as they were read, so the file can be fixed and read again
+func NewEventsHub(ctx context.Context, processor *eventsource.Processor, path, deadLetterPath string,
+	drainTimeout time.Duration) (*EventsHub, error) {
+	var reader io.ReadCloser = os.Stdin
+
+	if path != Stdin {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("could not open events file: %w", err)
+		}
+
+		reader = f
+	}
+
+	log.L(ctx).Info("file source opened", zap.String("path", path), zap.String("deadLetterPath", deadLetterPath))
+
+	return &EventsHub{
+		processor:      processor,
+		path:           path,
+		reader:         reader,
+		deadLetterPath: deadLetterPath,
+		drainTimeout:   drainTimeout,
+		run:            eventsource.NewRunState(),
+	}, nil
+}
+
+// Run implements port.Runner interface. it processes every line until the end of the file or until ctx is
+// canceled. the line being processed when ctx is canceled is finished within the drain timeout
+func (e *EventsHub) Run(ctx context.Context) error {
+	if !e.run.Start() {
+		return nil
+	}
+	defer e.run.Stop()
+
+	ctx = log.WithComponent(ctx, log.ComponentEventsHub)
+
+	processCtx, release := eventsource.DrainContext(ctx, e.drainTimeout)
+	defer release()
+
+	scanner := bufio.NewScanner(e.reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+
+		if ctx.Err() != nil {
+			log.L(ctx).Warn("context canceled. stopped reading", zap.Int("line", line))
+			return nil
+		}
+
+		value := scanner.Bytes()
+		if len(value) == 0 {
+			continue
+		}
+
+		message := &eventsource.Message{
+			System:   eventsource.SystemFile,
+			Source:   e.source(),
+			Position: strconv.Itoa(line),
+			Value:    append([]byte(nil), value...),
+
+			Replayable: e.path != Stdin, // lines of stdin may be anything the next time
+		}
+
+		if err := e.processor.Process(processCtx, message, e.deadLetterFunc()); err != nil {
+			if processCtx.Err() != nil {
+				log.L(ctx).Warn("drain timeout exceeded", zap.Int("line", line))
+				return nil
+			}
+
+			return fmt.Errorf("could not process line %d of %s: %w", line, e.source(), err)
+		}
+	}
+
+	if err := scanner.Err(); err != nil && ctx.Err() == nil {
+		return fmt.Errorf("could not read %s after line %d: %w", e.source(), line, err)
+	}
+
+	log.L(ctx).Info("finished reading events file", zap.String("path", e.source()), zap.Int("lines", line))
+
+	return nil
+}
+
+// Close implements port.Runner interface. closing the reader unblocks Run when it is waiting for stdin
+func (e *EventsHub) Close(ctx context.Context) error {
+	var err error
+
+	if !e.run.Wait(ctx) {
+		err = fmt.Errorf("could not wait for the file to be processed: %w", ctx.Err())
+	}
+
+	err = errors.Join(err, e.reader.Close())
+
+	e.deadLetterMu.Lock()
+	defer e.deadLetterMu.Unlock()
+
+	if e.deadLetterFile != nil {
+		err = errors.Join(err, e.deadLetterFile.Close())
+	}
+
+	return err
+}
+
+// IsHealthy always succeeds: a file has no connection to lose
+func (e *EventsHub) IsHealthy(ctx context.Context) error {
+	return nil
+}
+
+func (e *EventsHub) source() string {
+	if e.path == Stdin {
+		return "stdin"
+	}
+
+	return e.path
+}
+
+// deadLetterFunc is nil when dead lettering is disabled
+func (e *EventsHub) deadLetterFunc() eventsource.DeadLetterFunc {
+	if e.deadLetterPath == "" {
+		return nil
+	}
+
+	return e.deadLetter
+}
+
+// deadLetter appends the line to the dead letter file. the reason is only logged, so the file can be read again
+func (e *EventsHub) deadLetter(ctx context.Context, message *eventsource.Message, reason error) error {
+	e.deadLetterMu.Lock()
+	defer e.deadLetterMu.Unlock()
+
+	if e.deadLetterFile == nil {
+		f, err := os.OpenFile(e.deadLetterPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			return fmt.Errorf("could not open dead letter file: %w", err)
+		}
+
+		e.deadLetterFile = f
+	}
+
+	if _, err := e.deadLetterFile.Write(append(message.Value, '\n')); err != nil {
+		return fmt.Errorf("could not write to dead letter file %s: %w", e.deadLetterPath, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,112 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	notificationv1 "github.com/joseCarlosAndrade/notification-server/api/notification/v1"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/config"
+	log "github.com/joseCarlosAndrade/notification-server/internal/core/domain/logger"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain/port"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+)
+
+// Controller implements the port.Controller interface serving the grpc api
+type Controller struct {
+	notificationv1.UnimplementedNotificationServiceServer
+
+	service *port.Service
+	health  port.Health
+	server  *grpc.Server
+	addr    string
+
+	tokens      config.IngestionTokens
+	maxBatch    int
+	adminTokens config.AdminTokens
+
+	// done is closed on Close so open Subscribe streams end and the graceful stop does not wait for them
+	done chan struct{}
+}
+
+// makes sure Controller implements the interface
+var _ port.Controller = (*Controller)(nil)
+
+// NewController creates the grpc api with the health and reflection services. every notification call and
+// Subscribe require one of the ingestion tokens and Delete one of the admin tokens. they are disabled without them
+func NewController(ctx context.Context, serviceRepository *port.Service, health port.Health, tokens config.IngestionTokens,
+	maxBatch int, adminTokens config.AdminTokens, port string) *Controller {
+	c := &Controller{
+		service:     serviceRepository,
+		health:      health,
+		addr:        net.JoinHostPort("", port),
+		tokens:      tokens,
+		maxBatch:    maxBatch,
+		adminTokens: adminTokens,
+		done:        make(chan struct{}),
+	}
+
+	c.server = grpc.NewServer(
+		grpc.ChainUnaryInterceptor(instrumentUnary, c.authenticate),
+		grpc.ChainStreamInterceptor(instrumentStream, c.authenticateStream),
+	)
+
+	notificationv1.RegisterNotificationServiceServer(c.server, c)
+	healthpb.RegisterHealthServer(c.server, &healthServer{health: health, done: c.done})
+	reflection.Register(c.server)
+
+	if len(tokens) == 0 {
+		log.L(ctx).Info("grpc ingestion, notification calls and subscriptions disabled: no tokens configured")
+	}
+
+	if len(adminTokens) == 0 {
+		log.L(ctx).Info("grpc deletes disabled: no admin tokens configured")
+	}
+
+	return c
+}
+
+// Run implements port.Runner interface
+func (c *Controller) Run(ctx context.Context) error {
+	listener, err := net.Listen("tcp", c.addr)
+	if err != nil {
+		return fmt.Errorf("could not listen on %s: %w", c.addr, err)
+	}
+
+	log.L(ctx).Info("grpc api listening", zap.String("addr", c.addr))
+
+	// Serve returns nil after a graceful stop
+	return c.server.Serve(listener)
+}
+
+// Close implements port.Runner interface. waits for the in flight calls until ctx expires, then closes every
+// connection
+func (c *Controller) Close(ctx context.Context) error {
+	select {
+	case <-c.done:
+		return nil
+	default:
+		close(c.done)
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		c.server.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		c.server.Stop()
+		return fmt.Errorf("grpc calls did not finish in time: %w", ctx.Err())
+	}
+}
+
+func (c *Controller) IsHealthy(ctx context.Context) error {
+	return nil
+}
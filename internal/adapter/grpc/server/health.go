@@ -0,0 +1,85 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain/models"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain/port"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// healthWatchInterval is how often Watch checks the readiness for changes
+const healthWatchInterval = time.Second
+
+// healthServer implements the grpc health protocol with the readiness of the application, so grpc clients and
+// load balancers see the same status as /readyz. the empty service and the notification service are known
+type healthServer struct {
+	healthpb.UnimplementedHealthServer
+
+	health port.Health
+	done   <-chan struct{}
+}
+
+func (h *healthServer) Check(ctx context.Context, req *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	servingStatus, err := h.status(ctx, req.GetService())
+	if err != nil {
+		return nil, err
+	}
+
+	return &healthpb.HealthCheckResponse{Status: servingStatus}, nil
+}
+
+// Watch sends the status right away and then every time it changes
+func (h *healthServer) Watch(req *healthpb.HealthCheckRequest, stream grpc.ServerStreamingServer[healthpb.HealthCheckResponse]) error {
+	ticker := time.NewTicker(healthWatchInterval)
+	defer ticker.Stop()
+
+	last := healthpb.HealthCheckResponse_UNKNOWN
+
+	for {
+		servingStatus, err := h.status(stream.Context(), req.GetService())
+		if status.Code(err) == codes.NotFound {
+			servingStatus = healthpb.HealthCheckResponse_SERVICE_UNKNOWN
+		} else if err != nil {
+			return err
+		}
+
+		if servingStatus != last {
+			if err := stream.Send(&healthpb.HealthCheckResponse{Status: servingStatus}); err != nil {
+				return err
+			}
+
+			last = servingStatus
+		}
+
+		select {
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		case <-h.done:
+			return stream.Send(&healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_NOT_SERVING})
+		case <-ticker.C:
+		}
+	}
+}
+
+func (h *healthServer) status(ctx context.Context, service string) (healthpb.HealthCheckResponse_ServingStatus, error) {
+	if service != "" && service != serviceName {
+		return healthpb.HealthCheckResponse_SERVICE_UNKNOWN, status.Errorf(codes.NotFound, "unknown service %s", service)
+	}
+
+	select {
+	case <-h.done:
+		return healthpb.HealthCheckResponse_NOT_SERVING, nil
+	default:
+	}
+
+	if h.health.Readiness(ctx).Status == models.HealthDown {
+		return healthpb.HealthCheckResponse_NOT_SERVING, nil
+	}
+
+	return healthpb.HealthCheckResponse_SERVING, nil
+}
@@ -0,0 +1,226 @@
+package server
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	notificationv1 "github.com/joseCarlosAndrade/notification-server/api/notification/v1"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/config"
+	log "github.com/joseCarlosAndrade/notification-server/internal/core/domain/logger"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain/metrics"
+	"go.opentelemetry.io/otel"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+var tracer = otel.Tracer(config.AppTraceName)
+
+const requestIDKey = "x-request-id"
+
+type serviceKey struct{}
+
+// ingestionMethods require the bearer token of a producing service
+var ingestionMethods = map[string]bool{
+	notificationv1.NotificationService_Send_FullMethodName:      true,
+	notificationv1.NotificationService_SendBatch_FullMethodName: true,
+}
+
+// notificationMethods require the bearer token of the service that owns the notifications
+var notificationMethods = map[string]bool{
+	notificationv1.NotificationService_List_FullMethodName:     true,
+	notificationv1.NotificationService_MarkRead_FullMethodName: true,
+	notificationv1.NotificationService_Archive_FullMethodName:  true,
+	notificationv1.NotificationService_Restore_FullMethodName:  true,
+}
+
+// streamMethods require the bearer token of a producing service. the health watch stays open
+var streamMethods = map[string]bool{
+	notificationv1.NotificationService_Subscribe_FullMethodName: true,
+}
+
+// adminMethods require an admin bearer token
+var adminMethods = map[string]bool{
+	notificationv1.NotificationService_Delete_FullMethodName: true,
+}
+
+// metadataCarrier adapts the incoming metadata to propagate traces
+type metadataCarrier metadata.MD
+
+func (m metadataCarrier) Get(key string) string {
+	if values := metadata.MD(m).Get(key); len(values) > 0 {
+		return values[0]
+	}
+
+	return ""
+}
+
+func (m metadataCarrier) Set(key, value string) {
+	metadata.MD(m).Set(key, value)
+}
+
+func (m metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+
+	return keys
+}
+
+// startCall continues the trace propagated in the metadata (or starts a new one) in a server span and loads the
+// request id into the log context, like the http api does
+func startCall(ctx context.Context, method string) (context.Context, trace.Span) {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	ctx = log.WithComponent(ctx, log.ComponentAPI)
+	ctx = otel.GetTextMapPropagator().Extract(ctx, metadataCarrier(md))
+	ctx, span := tracer.Start(ctx, method,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(semconv.RPCSystemGRPC, semconv.RPCMethod(method)))
+
+	ctx = log.InitResources(ctx, metadataCarrier(md).Get(requestIDKey), zap.String("method", method))
+	_ = grpc.SetHeader(ctx, metadata.Pairs(requestIDKey, log.RequestID(ctx)))
+
+	return ctx, span
+}
+
+// endCall ends the span and observes the latency of the call by method and status code
+func endCall(span trace.Span, method string, start time.Time, err error) {
+	code := status.Code(err)
+
+	span.SetAttributes(semconv.RPCGRPCStatusCodeKey.Int(int(code)))
+	if code != codes.OK {
+		span.SetStatus(otelcodes.Error, code.String())
+	}
+
+	span.End()
+
+	metrics.R().ObserveGRPCRequest(method, code.String(), time.Since(start))
+}
+
+func instrumentUnary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (_ any, err error) {
+	start := time.Now()
+
+	ctx, span := startCall(ctx, info.FullMethod)
+	defer func() { endCall(span, info.FullMethod, start, err) }()
+
+	return handler(ctx, req)
+}
+
+// wrappedStream replaces the context of the stream
+type wrappedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *wrappedStream) Context() context.Context {
+	return s.ctx
+}
+
+func instrumentStream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	start := time.Now()
+
+	ctx, span := startCall(ss.Context(), info.FullMethod)
+	defer func() { endCall(span, info.FullMethod, start, err) }()
+
+	return handler(srv, &wrappedStream{ServerStream: ss, ctx: ctx})
+}
+
+// authenticate only lets ingestion and notification calls through with the bearer token of a producing service,
+// which is stored in the context, and admin calls with an admin token
+func (c *Controller) authenticate(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if adminMethods[info.FullMethod] {
+		return c.authenticateAdmin(ctx, req, handler)
+	}
+
+	var what string
+
+	switch {
+	case ingestionMethods[info.FullMethod]:
+		what = "ingestion"
+	case notificationMethods[info.FullMethod]:
+		what = "notification calls"
+	default:
+		return handler(ctx, req)
+	}
+
+	ctx, err := c.authenticateService(ctx, what)
+	if err != nil {
+		return nil, err
+	}
+
+	return handler(ctx, req)
+}
+
+// authenticateStream lets streams through with the bearer token of a producing service, as authenticate does
+// for ingestion calls. services can only subscribe to their own notifications
+func (c *Controller) authenticateStream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if !streamMethods[info.FullMethod] {
+		return handler(srv, ss)
+	}
+
+	ctx, err := c.authenticateService(ss.Context(), "subscriptions")
+	if err != nil {
+		return err
+	}
+
+	return handler(srv, &wrappedStream{ServerStream: ss, ctx: ctx})
+}
+
+// authenticateService stores the service that owns the bearer token in the context. what names the calls
+// disabled without tokens
+func (c *Controller) authenticateService(ctx context.Context, what string) (context.Context, error) {
+	if len(c.tokens) == 0 {
+		return nil, status.Errorf(codes.Unimplemented, "%s disabled: no tokens configured", what)
+	}
+
+	token, err := bearerToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	service, ok := c.tokens.Authenticate(token)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "invalid token")
+	}
+
+	ctx = context.WithValue(ctx, serviceKey{}, service)
+	ctx = log.WithFields(ctx, zap.String("service", service))
+
+	return ctx, nil
+}
+
+func (c *Controller) authenticateAdmin(ctx context.Context, req any, handler grpc.UnaryHandler) (any, error) {
+	if len(c.adminTokens) == 0 {
+		return nil, status.Error(codes.Unimplemented, "admin calls disabled: no admin tokens configured")
+	}
+
+	token, err := bearerToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if !c.adminTokens.Authenticate(token) {
+		return nil, status.Error(codes.PermissionDenied, "not an admin token")
+	}
+
+	return handler(ctx, req)
+}
+
+func bearerToken(ctx context.Context) (string, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	token, ok := strings.CutPrefix(metadataCarrier(md).Get("authorization"), "Bearer ")
+	if !ok || token == "" {
+		return "", status.Error(codes.Unauthenticated, "missing bearer token")
+	}
+
+	return token, nil
+}
@@ -0,0 +1,328 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	notificationv1 "github.com/joseCarlosAndrade/notification-server/api/notification/v1"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain"
+	log "github.com/joseCarlosAndrade/notification-server/internal/core/domain/logger"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain/models"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+var serviceName = notificationv1.NotificationService_ServiceDesc.ServiceName
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 500
+)
+
+// Send stores a single record, the same way it would be if it came from the topic
+func (c *Controller) Send(ctx context.Context, req *notificationv1.SendRequest) (*notificationv1.SendResponse, error) {
+	if req.GetRecord() == nil {
+		return nil, status.Error(codes.InvalidArgument, "record is required")
+	}
+
+	id, err := c.ingest(ctx, req.GetRecord())
+	if err != nil {
+		return nil, toStatus(ctx, err).Err()
+	}
+
+	return &notificationv1.SendResponse{Id: id}, nil
+}
+
+// SendBatch stores every record independently, so the response has the result of each one instead of failing
+// the whole batch
+func (c *Controller) SendBatch(ctx context.Context, req *notificationv1.SendBatchRequest) (*notificationv1.SendBatchResponse, error) {
+	records := req.GetRecords()
+
+	if len(records) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "batch is empty")
+	}
+
+	if len(records) > c.maxBatch {
+		return nil, status.Errorf(codes.InvalidArgument, "batch has %d records, max is %d", len(records), c.maxBatch)
+	}
+
+	results := make([]*notificationv1.SendResult, len(records))
+	for i, record := range records {
+		id, err := c.ingest(ctx, record)
+		if err != nil {
+			st := toStatus(ctx, err)
+			results[i] = &notificationv1.SendResult{Code: int32(st.Code()), Error: st.Message()}
+
+			continue
+		}
+
+		results[i] = &notificationv1.SendResult{Id: id, Code: int32(codes.OK)}
+	}
+
+	return &notificationv1.SendBatchResponse{Results: results}, nil
+}
+
+// ingest validates the record as the event sources do and saves it. services can only send their own
+// notifications: an empty service is the authenticated one
+func (c *Controller) ingest(ctx context.Context, req *notificationv1.NotificationRecord) (string, error) {
+	record := toRecord(req)
+
+	service, _ := ctx.Value(serviceKey{}).(string)
+	if err := record.SetProducer(service); err != nil {
+		return "", err
+	}
+
+	if err := record.Validate(); err != nil {
+		return "", err
+	}
+
+	return (*c.service).SaveNewNotification(ctx, record)
+}
+
+// List returns a page of notifications. the page token is the offset of the next page. services can only list
+// their own notifications: an empty service is the authenticated one
+func (c *Controller) List(ctx context.Context, req *notificationv1.ListRequest) (*notificationv1.ListResponse, error) {
+	pageSize := int(req.GetPageSize())
+	switch {
+	case pageSize < 0:
+		return nil, status.Error(codes.InvalidArgument, "page_size cannot be negative")
+	case pageSize == 0:
+		pageSize = defaultPageSize
+	case pageSize > maxPageSize:
+		pageSize = maxPageSize
+	}
+
+	offset := 0
+	if token := req.GetPageToken(); token != "" {
+		var err error
+		if offset, err = strconv.Atoi(token); err != nil || offset < 0 {
+			return nil, status.Error(codes.InvalidArgument, "invalid page_token")
+		}
+	}
+
+	authenticated, _ := ctx.Value(serviceKey{}).(string)
+
+	filter := models.NotificationFilter{
+		Service:         req.GetService(),
+		Recipient:       req.GetRecipient(),
+		UnreadOnly:      req.GetUnreadOnly(),
+		IncludeArchived: req.GetIncludeArchived(),
+		Limit:           pageSize + 1, // the extra one tells if there is a next page
+		Offset:          offset,
+	}
+
+	if filter.Service == "" {
+		filter.Service = authenticated
+	}
+
+	if filter.Service != authenticated {
+		return nil, status.Errorf(codes.PermissionDenied, "service %s cannot list the notifications of %s",
+			authenticated, filter.Service)
+	}
+
+	if req.GetSince() != nil {
+		filter.Since = req.GetSince().AsTime()
+	}
+
+	if req.GetUntil() != nil {
+		filter.Until = req.GetUntil().AsTime()
+	}
+
+	notifications, err := (*c.service).ListNotifications(ctx, filter)
+	if err != nil {
+		return nil, toStatus(ctx, err).Err()
+	}
+
+	resp := &notificationv1.ListResponse{}
+
+	if len(notifications) > pageSize {
+		notifications = notifications[:pageSize]
+		resp.NextPageToken = strconv.Itoa(offset + pageSize)
+	}
+
+	resp.Notifications = make([]*notificationv1.Notification, 0, len(notifications))
+	for _, n := range notifications {
+		resp.Notifications = append(resp.Notifications, toProtoNotification(n))
+	}
+
+	return resp, nil
+}
+
+func (c *Controller) MarkRead(ctx context.Context, req *notificationv1.MarkReadRequest) (*notificationv1.MarkReadResponse, error) {
+	service, _ := ctx.Value(serviceKey{}).(string)
+
+	if err := (*c.service).MarkNotificationAsRead(ctx, service, req.GetId()); err != nil {
+		return nil, toStatus(ctx, err).Err()
+	}
+
+	return &notificationv1.MarkReadResponse{}, nil
+}
+
+func (c *Controller) Archive(ctx context.Context, req *notificationv1.ArchiveRequest) (*notificationv1.ArchiveResponse, error) {
+	service, _ := ctx.Value(serviceKey{}).(string)
+
+	if err := (*c.service).ArchiveNotification(ctx, service, req.GetId()); err != nil {
+		return nil, toStatus(ctx, err).Err()
+	}
+
+	return &notificationv1.ArchiveResponse{}, nil
+}
+
+func (c *Controller) Restore(ctx context.Context, req *notificationv1.RestoreRequest) (*notificationv1.RestoreResponse, error) {
+	service, _ := ctx.Value(serviceKey{}).(string)
+
+	if err := (*c.service).UnarchiveNotification(ctx, service, req.GetId()); err != nil {
+		return nil, toStatus(ctx, err).Err()
+	}
+
+	return &notificationv1.RestoreResponse{}, nil
+}
+
+// Delete is only reached with an admin token, see authenticate
+func (c *Controller) Delete(ctx context.Context, req *notificationv1.DeleteRequest) (*notificationv1.DeleteResponse, error) {
+	if err := (*c.service).DeleteNotification(ctx, req.GetId()); err != nil {
+		return nil, toStatus(ctx, err).Err()
+	}
+
+	return &notificationv1.DeleteResponse{}, nil
+}
+
+// Subscribe streams notifications until the client leaves or the server closes. services can only subscribe to
+// their own notifications: an empty service is the authenticated one
+func (c *Controller) Subscribe(req *notificationv1.SubscribeRequest, stream grpc.ServerStreamingServer[notificationv1.Notification]) error {
+	ctx := stream.Context()
+
+	authenticated, _ := ctx.Value(serviceKey{}).(string)
+
+	filter := models.SubscriptionFilter{
+		Service:   req.GetService(),
+		Recipient: req.GetRecipient(),
+	}
+
+	if filter.Service == "" {
+		filter.Service = authenticated
+	}
+
+	if filter.Service == "" {
+		return status.Error(codes.InvalidArgument, "service is required")
+	}
+
+	if filter.Service != authenticated {
+		return status.Errorf(codes.PermissionDenied, "service %s cannot subscribe to the notifications of %s",
+			authenticated, filter.Service)
+	}
+
+	notifications, unsubscribe := (*c.service).Subscribe(ctx, filter)
+	defer unsubscribe()
+
+	log.L(ctx).Info("subscriber connected",
+		zap.String("service", filter.Service),
+		zap.String("recipient", filter.Recipient))
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.L(ctx).Info("subscriber disconnected")
+			return nil
+		case <-c.done:
+			return status.Error(codes.Unavailable, "server is shutting down")
+		case n, ok := <-notifications:
+			if !ok {
+				return nil
+			}
+
+			if err := stream.Send(toProtoNotification(n)); err != nil {
+				log.L(ctx).Warn("could not send notification to subscriber", zap.String("id", n.ID), zap.Error(err))
+				return err
+			}
+		}
+	}
+}
+
+// toStatus maps domain errors to grpc status codes. internal errors are logged and hidden from the client
+func toStatus(ctx context.Context, err error) *status.Status {
+	code := codes.Internal
+
+	switch {
+	case errors.Is(err, domain.ErrNotFound):
+		code = codes.NotFound
+	case errors.Is(err, domain.ErrConflict):
+		code = codes.AlreadyExists
+	case errors.Is(err, domain.ErrForbidden):
+		code = codes.PermissionDenied
+	case errors.Is(err, domain.ErrInvalidTemplate), errors.Is(err, domain.ErrInvalidArgument),
+		errors.Is(err, domain.ErrTemplateRender):
+		code = codes.InvalidArgument
+	case errors.Is(err, domain.ErrThrottled):
+		code = codes.ResourceExhausted
+	}
+
+	if code == codes.Internal {
+		log.L(ctx).Error("grpc call failed", zap.Error(err))
+		return status.New(code, "internal error")
+	}
+
+	return status.New(code, err.Error())
+}
+
+func toRecord(r *notificationv1.NotificationRecord) *models.NotificationRecord {
+	record := &models.NotificationRecord{
+		Service:     r.GetService(),
+		Recipient:   r.GetRecipient(),
+		Title:       r.GetTitle(),
+		Message:     r.GetMessage(),
+		Category:    r.GetCategory(),
+		Priority:    models.Priority(r.GetPriority()),
+		TemplateID:  r.GetTemplateId(),
+		Locale:      r.GetLocale(),
+		CollapseKey: r.GetCollapseKey(),
+	}
+
+	if r.GetSentAt() != nil {
+		sentAt := r.GetSentAt().AsTime()
+		record.SentAt = &sentAt
+	}
+
+	if r.GetDeliverAt() != nil {
+		deliverAt := r.GetDeliverAt().AsTime()
+		record.DeliverAt = &deliverAt
+	}
+
+	if r.GetVariables() != nil {
+		record.Variables = r.GetVariables().AsMap()
+	}
+
+	return record
+}
+
+func toProtoNotification(n *models.Notification) *notificationv1.Notification {
+	notification := &notificationv1.Notification{
+		Id:          n.ID,
+		Service:     n.Service,
+		Recipient:   n.Recipient,
+		Title:       n.Title,
+		Message:     n.Message,
+		IsRead:      n.IsRead,
+		SentAt:      timestamppb.New(n.SentAt),
+		Category:    n.Category,
+		Priority:    string(n.Priority),
+		Status:      string(n.Status),
+		CollapseKey: n.CollapseKey,
+		Count:       int32(n.Count),
+		Metadata:    n.Metadata,
+	}
+
+	if n.ReadAt != nil {
+		notification.ReadAt = timestamppb.New(*n.ReadAt)
+	}
+
+	if n.ArchivedAt != nil {
+		notification.ArchivedAt = timestamppb.New(*n.ArchivedAt)
+	}
+
+	return notification
+}
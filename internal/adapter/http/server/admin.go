@@ -0,0 +1,70 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+
+	log "github.com/joseCarlosAndrade/notification-server/internal/core/domain/logger"
+)
+
+// authenticateAdmin only lets through requests with one of the admin bearer tokens
+func (s *Controller) authenticateAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || token == "" {
+			writeJSON(w, r, http.StatusUnauthorized, errorResponse{Error: "missing bearer token"})
+			return
+		}
+
+		if !s.adminTokens.Authenticate(token) {
+			writeJSON(w, r, http.StatusForbidden, errorResponse{Error: "not an admin token"})
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// getThrottleStats shows how many notifications were throttled per producing service
+func (s *Controller) getThrottleStats(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, r, http.StatusOK, (*s.service).GetThrottleStats(r.Context()))
+}
+
+type logLevelRequest struct {
+	Level string `json:"level"`
+}
+
+// getLogLevels shows the root log level and the level of every component
+func (s *Controller) getLogLevels(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, r, http.StatusOK, log.GetLevels())
+}
+
+// setLogLevel changes the root log level at runtime. an empty level goes back to the environment default
+func (s *Controller) setLogLevel(w http.ResponseWriter, r *http.Request) {
+	var req logLevelRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	if err := log.SetLevel(req.Level); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, log.GetLevels())
+}
+
+// setComponentLogLevel changes the log level of a component. an empty level makes it follow the root level
+func (s *Controller) setComponentLogLevel(w http.ResponseWriter, r *http.Request) {
+	var req logLevelRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	if err := log.SetComponentLevel(r.PathValue("component"), req.Level); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, log.GetLevels())
+}
@@ -0,0 +1,38 @@
+package server
+
+import "net/http"
+
+// archiveNotification dismisses the notification from the recipient lists. it is kept for the audit trail.
+// services can only archive their own notifications
+func (s *Controller) archiveNotification(w http.ResponseWriter, r *http.Request) {
+	service, _ := r.Context().Value(serviceKey{}).(string)
+
+	if err := (*s.service).ArchiveNotification(r.Context(), service, r.PathValue("id")); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// restoreNotification lists an archived notification again. services can only restore their own notifications
+func (s *Controller) restoreNotification(w http.ResponseWriter, r *http.Request) {
+	service, _ := r.Context().Value(serviceKey{}).(string)
+
+	if err := (*s.service).UnarchiveNotification(r.Context(), service, r.PathValue("id")); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// deleteNotification deletes the notification for good. only reachable with an admin token
+func (s *Controller) deleteNotification(w http.ResponseWriter, r *http.Request) {
+	if err := (*s.service).DeleteNotification(r.Context(), r.PathValue("id")); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -2,33 +2,128 @@ package server
 
 import (
 	"context"
+	"errors"
+	"net"
+	"net/http"
+	"time"
 
+	"github.com/joseCarlosAndrade/notification-server/internal/core/config"
+	log "github.com/joseCarlosAndrade/notification-server/internal/core/domain/logger"
 	"github.com/joseCarlosAndrade/notification-server/internal/core/domain/port"
+	"go.uber.org/zap"
 )
 
 // Controller implements the port.Controller interface
 type Controller struct {
 	service *port.Service
+	health  port.Health
+	server  *http.Server
+
+	ingestion   IngestionConfig
+	adminTokens config.AdminTokens // empty disables every /admin endpoint, template uploads and preferences
+
+	metricsHandler http.Handler
 }
 
 // makes sure Controller implements the interface
 var _ port.Controller = (*Controller)(nil)
 
-func NewController(ctx context.Context, serviceRepository *port.Service) Controller {
-	return Controller{}
+// NewController creates the api. metricsHandler is served on /metrics and health on /livez and /readyz.
+// notifications can be posted, archived and restored only if ingestion has tokens. templates can be uploaded, and
+// recipient preferences and the /admin endpoints are served, only if there are admin tokens
+func NewController(ctx context.Context, serviceRepository *port.Service, health port.Health, ingestion IngestionConfig,
+	adminTokens config.AdminTokens, port string, metricsHandler http.Handler) Controller {
+	c := Controller{
+		service:        serviceRepository,
+		health:         health,
+		ingestion:      ingestion,
+		adminTokens:    adminTokens,
+		metricsHandler: metricsHandler,
+	}
+
+	c.server = &http.Server{
+		Addr:              net.JoinHostPort("", port),
+		Handler:           c.routes(ctx),
+		ReadHeaderTimeout: time.Second * 5,
+		BaseContext: func(net.Listener) context.Context {
+			return ctx
+		},
+	}
+
+	return c
+}
+
+// routes registers every endpoint of the api
+func (s *Controller) routes(ctx context.Context) http.Handler {
+	mux := http.NewServeMux()
+
+	// notifications
+	if len(s.ingestion.Tokens) > 0 {
+		mux.HandleFunc("POST /notifications", s.authenticate(s.saveNotification))
+		mux.HandleFunc("POST /notifications/batch", s.authenticate(s.saveNotifications))
+		mux.HandleFunc("POST /notifications/{id}/archive", s.authenticate(s.archiveNotification))
+		mux.HandleFunc("POST /notifications/{id}/restore", s.authenticate(s.restoreNotification))
+	} else {
+		log.L(ctx).Info("http ingestion disabled: no tokens configured")
+	}
+
+	// templates
+	mux.HandleFunc("GET /templates/{id}", s.listTemplateVersions)
+	mux.HandleFunc("GET /templates/{id}/locales/{locale}", s.getTemplate)
+	if len(s.adminTokens) > 0 {
+		mux.HandleFunc("POST /templates/{id}/locales/{locale}", s.authenticateAdmin(s.uploadTemplate))
+	} else {
+		log.L(ctx).Info("http template uploads disabled: no admin tokens configured")
+	}
+
+	// recipient preferences. they apply to the notifications of every service, so only admins manage them
+	if len(s.adminTokens) > 0 {
+		mux.HandleFunc("GET /recipients/{recipient}/preferences", s.authenticateAdmin(s.getPreferences))
+		mux.HandleFunc("PUT /recipients/{recipient}/preferences", s.authenticateAdmin(s.savePreferences))
+		mux.HandleFunc("DELETE /recipients/{recipient}/preferences", s.authenticateAdmin(s.deletePreferences))
+	} else {
+		log.L(ctx).Info("http recipient preferences disabled: no admin tokens configured")
+	}
+
+	// admin
+	if len(s.adminTokens) > 0 {
+		mux.HandleFunc("GET /admin/ratelimits", s.authenticateAdmin(s.getThrottleStats))
+		mux.HandleFunc("GET /admin/loglevel", s.authenticateAdmin(s.getLogLevels))
+		mux.HandleFunc("PUT /admin/loglevel", s.authenticateAdmin(s.setLogLevel))
+		mux.HandleFunc("PUT /admin/loglevel/{component}", s.authenticateAdmin(s.setComponentLogLevel))
+		mux.HandleFunc("GET /admin/notifications/export", s.authenticateAdmin(s.exportNotifications))
+		mux.HandleFunc("POST /admin/notifications/import", s.authenticateAdmin(s.importNotifications))
+		mux.HandleFunc("DELETE /admin/notifications/{id}", s.authenticateAdmin(s.deleteNotification))
+	} else {
+		log.L(ctx).Info("http admin disabled: no admin tokens configured")
+	}
+
+	// metrics
+	mux.Handle("GET /metrics", s.metricsHandler)
+
+	// health
+	mux.HandleFunc("GET /livez", s.liveness)
+	mux.HandleFunc("GET /readyz", s.readiness)
+
+	return instrument(mux)
 }
 
 // Run implements port.Runner interface
 func (s *Controller) Run(ctx context.Context) error {
-	for {}
+	log.L(ctx).Info("api listening", zap.String("addr", s.server.Addr))
+
+	err := s.server.ListenAndServe()
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
 	return nil
 }
 
 // Close implements port.Runner interface
 func (s *Controller) Close(ctx context.Context) error {
-	// todo: close connection and clean up
-
-	return nil
+	// stops accepting new connections and waits for the in flight requests
+	return s.server.Shutdown(ctx)
 }
 
 func (s *Controller) IsHealthy(ctx context.Context) error  {
@@ -0,0 +1,101 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	log "github.com/joseCarlosAndrade/notification-server/internal/core/domain/logger"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain/models"
+	"go.uber.org/zap"
+)
+
+// maxImportBytes limits the size of an import. bigger backups must be split
+const maxImportBytes = 256 << 20
+
+// streamWriter remembers if the response was started, since errors can't be reported after that
+type streamWriter struct {
+	http.ResponseWriter
+	started bool
+}
+
+func (w *streamWriter) Write(p []byte) (int, error) {
+	w.started = true
+	return w.ResponseWriter.Write(p)
+}
+
+// exportNotifications streams the notifications of ?service= sent in [?since=, ?until=) as ndjson (default) or
+// ?format=csv. the response is written as the storage is read, so exports of any size use constant memory
+func (s *Controller) exportNotifications(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	filter := models.ExportFilter{
+		Service:   query.Get("service"),
+		Recipient: query.Get("recipient"),
+	}
+
+	for name, dst := range map[string]*time.Time{"since": &filter.Since, "until": &filter.Until} {
+		if v := query.Get(name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				writeJSON(w, r, http.StatusBadRequest, errorResponse{Error: name + " must be an RFC 3339 timestamp"})
+				return
+			}
+
+			*dst = t
+		}
+	}
+
+	format := models.ExportNDJSON
+	if v := query.Get("format"); v != "" {
+		format = models.ExportFormat(v)
+	}
+
+	contentType := "application/x-ndjson"
+	if format == models.ExportCSV {
+		contentType = "text/csv"
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q",
+		fmt.Sprintf("%s-notifications.%s", filter.Service, format)))
+
+	stream := &streamWriter{ResponseWriter: w}
+
+	if err := (*s.service).ExportNotifications(r.Context(), filter, format, stream); err != nil {
+		if !stream.started {
+			w.Header().Del("Content-Disposition")
+			writeError(w, r, err)
+
+			return
+		}
+
+		// the status was already sent. aborting makes the client see a broken download instead of a short one
+		log.L(r.Context()).Error("export interrupted", zap.Error(err))
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// importNotifications restores an ndjson export. the body is read as a stream, up to maxImportBytes
+func (s *Controller) importNotifications(w http.ResponseWriter, r *http.Request) {
+	body := http.MaxBytesReader(w, r.Body, maxImportBytes)
+
+	result, err := (*s.service).ImportNotifications(r.Context(), body)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeJSON(w, r, http.StatusRequestEntityTooLarge, errorResponse{
+				Error: fmt.Sprintf("import is larger than %d bytes. %d notifications were imported, split the rest",
+					tooLarge.Limit, result.Imported),
+			})
+
+			return
+		}
+
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, result)
+}
@@ -0,0 +1,27 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain/models"
+)
+
+// liveness tells the orchestrator if the process should be restarted
+func (s *Controller) liveness(w http.ResponseWriter, r *http.Request) {
+	writeHealth(w, r, s.health.Liveness(r.Context()))
+}
+
+// readiness tells the orchestrator if the instance should receive traffic. a degraded instance (a non
+// critical dependency down) is still ready
+func (s *Controller) readiness(w http.ResponseWriter, r *http.Request) {
+	writeHealth(w, r, s.health.Readiness(r.Context()))
+}
+
+func writeHealth(w http.ResponseWriter, r *http.Request, report models.HealthReport) {
+	status := http.StatusOK
+	if report.Status == models.HealthDown {
+		status = http.StatusServiceUnavailable
+	}
+
+	writeJSON(w, r, status, report)
+}
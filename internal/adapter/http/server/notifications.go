@@ -0,0 +1,134 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/joseCarlosAndrade/notification-server/internal/core/config"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain"
+	log "github.com/joseCarlosAndrade/notification-server/internal/core/domain/logger"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain/models"
+	"go.uber.org/zap"
+)
+
+// IngestionConfig enables the http ingestion of notifications for services that can't produce to the topic
+type IngestionConfig struct {
+	Tokens   config.IngestionTokens // empty disables ingestion
+	MaxBatch int                    // max records accepted in a single batch request
+}
+
+type serviceKey struct{}
+
+type notificationResponse struct {
+	ID string `json:"id"`
+}
+
+// batchItemResponse is the result of each record of a batch, in the same order they were sent
+type batchItemResponse struct {
+	ID     string `json:"id,omitempty"`
+	Status int    `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// authenticate only lets through requests with the bearer token of a producing service, which is stored in the
+// context
+func (s *Controller) authenticate(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || token == "" {
+			writeJSON(w, r, http.StatusUnauthorized, errorResponse{Error: "missing bearer token"})
+			return
+		}
+
+		service, ok := s.ingestion.Tokens.Authenticate(token)
+		if !ok {
+			writeJSON(w, r, http.StatusUnauthorized, errorResponse{Error: "invalid token"})
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), serviceKey{}, service)
+		ctx = log.WithFields(ctx, zap.String("service", service))
+
+		next(w, r.WithContext(ctx))
+	}
+}
+
+// saveNotification stores a single record, the same way it would be if it came from the topic
+func (s *Controller) saveNotification(w http.ResponseWriter, r *http.Request) {
+	value, ok := readBody(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := s.ingest(r.Context(), value)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusCreated, notificationResponse{ID: id})
+}
+
+// saveNotifications stores a batch of records. each one is decoded and stored independently, so the response has
+// the result of every record instead of failing the whole batch
+func (s *Controller) saveNotifications(w http.ResponseWriter, r *http.Request) {
+	var records []json.RawMessage
+	if !decodeJSON(w, r, &records) {
+		return
+	}
+
+	if len(records) == 0 {
+		writeError(w, r, fmt.Errorf("%w: batch is empty", domain.ErrInvalidArgument))
+		return
+	}
+
+	if len(records) > s.ingestion.MaxBatch {
+		writeError(w, r, fmt.Errorf("%w: batch has %d records, max is %d",
+			domain.ErrInvalidArgument, len(records), s.ingestion.MaxBatch))
+		return
+	}
+
+	results := make([]batchItemResponse, len(records))
+	for i, record := range records {
+		if bytes.Equal(record, []byte("null")) {
+			results[i] = batchItemResponse{Status: http.StatusBadRequest, Error: "record cannot be null"}
+			continue
+		}
+
+		id, err := s.ingest(r.Context(), record)
+		if err != nil {
+			status := errorStatus(err)
+			if status == http.StatusInternalServerError {
+				log.L(r.Context()).Error("could not save notification", zap.Int("index", i), zap.Error(err))
+				results[i] = batchItemResponse{Status: status, Error: "internal error"}
+
+				continue
+			}
+
+			results[i] = batchItemResponse{Status: status, Error: err.Error()}
+
+			continue
+		}
+
+		results[i] = batchItemResponse{ID: id, Status: http.StatusCreated}
+	}
+
+	writeJSON(w, r, http.StatusOK, results)
+}
+
+// ingest decodes and validates the record as the event sources do and saves it. services can only send their
+// own notifications: an empty service is the authenticated one
+func (s *Controller) ingest(ctx context.Context, value []byte) (string, error) {
+	service, _ := ctx.Value(serviceKey{}).(string)
+
+	record, err := models.DecodeNotificationRecord(value, service)
+	if err != nil {
+		return "", err
+	}
+
+	return (*s.service).SaveNewNotification(ctx, record)
+}
@@ -0,0 +1,59 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain/models"
+)
+
+type preferencesRequest struct {
+	Locale          string                       `json:"locale,omitempty"`
+	TimeZone        string                       `json:"timeZone,omitempty"`
+	MutedServices   []string                     `json:"mutedServices,omitempty"`
+	MutedCategories []string                     `json:"mutedCategories,omitempty"`
+	QuietHours      *models.QuietHours           `json:"quietHours,omitempty"`
+	Channels        map[models.Priority][]string `json:"channels,omitempty"`
+}
+
+func (s *Controller) getPreferences(w http.ResponseWriter, r *http.Request) {
+	preferences, err := (*s.service).GetPreferences(r.Context(), r.PathValue("recipient"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, preferences)
+}
+
+// savePreferences replaces every preference of the recipient
+func (s *Controller) savePreferences(w http.ResponseWriter, r *http.Request) {
+	var req preferencesRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	preferences, err := (*s.service).SavePreferences(r.Context(), &models.Preferences{
+		Recipient:       r.PathValue("recipient"),
+		Locale:          req.Locale,
+		TimeZone:        req.TimeZone,
+		MutedServices:   req.MutedServices,
+		MutedCategories: req.MutedCategories,
+		QuietHours:      req.QuietHours,
+		Channels:        req.Channels,
+	})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, preferences)
+}
+
+func (s *Controller) deletePreferences(w http.ResponseWriter, r *http.Request) {
+	if err := (*s.service).DeletePreferences(r.Context(), r.PathValue("recipient")); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,84 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain"
+	log "github.com/joseCarlosAndrade/notification-server/internal/core/domain/logger"
+	"go.uber.org/zap"
+)
+
+// maxBodyBytes limits the size of request bodies
+const maxBodyBytes = 1 << 20
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func writeJSON(w http.ResponseWriter, r *http.Request, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.L(r.Context()).Error("could not write response", zap.Error(err))
+	}
+}
+
+// errorStatus maps domain errors to http status codes
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, domain.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, domain.ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, domain.ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, domain.ErrInvalidTemplate), errors.Is(err, domain.ErrInvalidArgument),
+		errors.Is(err, domain.ErrTemplateRender):
+		return http.StatusBadRequest
+	case errors.Is(err, domain.ErrThrottled):
+		return http.StatusTooManyRequests
+	}
+
+	return http.StatusInternalServerError
+}
+
+// writeError writes the status of the error. internal errors are logged and hidden from the client
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	status := errorStatus(err)
+
+	if status == http.StatusInternalServerError {
+		log.L(r.Context()).Error("request failed", zap.String("path", r.URL.Path), zap.Error(err))
+		writeJSON(w, r, status, errorResponse{Error: "internal error"})
+
+		return
+	}
+
+	writeJSON(w, r, status, errorResponse{Error: err.Error()})
+}
+
+func decodeJSON(w http.ResponseWriter, r *http.Request, dst any) bool {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(dst); err != nil {
+		writeJSON(w, r, http.StatusBadRequest, errorResponse{Error: "invalid body: " + err.Error()})
+		return false
+	}
+
+	return true
+}
+
+// readBody reads the raw request body, for payloads decoded by the domain instead of decodeJSON
+func readBody(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	value, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	if err != nil {
+		writeJSON(w, r, http.StatusBadRequest, errorResponse{Error: "invalid body: " + err.Error()})
+		return nil, false
+	}
+
+	return value, true
+}
@@ -0,0 +1,69 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain/models"
+)
+
+type uploadTemplateRequest struct {
+	Title   string         `json:"title"`
+	Body    string         `json:"body"`
+	Example map[string]any `json:"example,omitempty"`
+}
+
+// uploadTemplate validates and stores a new version of the template for the locale
+func (s *Controller) uploadTemplate(w http.ResponseWriter, r *http.Request) {
+	var req uploadTemplateRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	template, err := (*s.service).SaveTemplate(r.Context(), &models.Template{
+		ID:      r.PathValue("id"),
+		Locale:  r.PathValue("locale"),
+		Title:   req.Title,
+		Body:    req.Body,
+		Example: req.Example,
+	})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusCreated, template)
+}
+
+// getTemplate returns the latest version of the template, or the one in ?version=
+func (s *Controller) getTemplate(w http.ResponseWriter, r *http.Request) {
+	version := 0
+
+	if v := r.URL.Query().Get("version"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil || parsed <= 0 {
+			writeJSON(w, r, http.StatusBadRequest, errorResponse{Error: "version must be a positive integer"})
+			return
+		}
+
+		version = parsed
+	}
+
+	template, err := (*s.service).GetTemplate(r.Context(), r.PathValue("id"), r.PathValue("locale"), version)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, template)
+}
+
+func (s *Controller) listTemplateVersions(w http.ResponseWriter, r *http.Request) {
+	templates, err := (*s.service).ListTemplateVersions(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, templates)
+}
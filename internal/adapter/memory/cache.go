@@ -0,0 +1,83 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain/port"
+)
+
+type entry struct {
+	value     []byte
+	expiresAt time.Time // zero when the entry never expires
+}
+
+// Cache implements port.Cache in memory. expired entries are dropped when read
+type Cache struct {
+	mu      sync.Mutex
+	entries map[string]entry
+}
+
+var _ port.Cache = (*Cache)(nil)
+
+func NewCache(ctx context.Context) *Cache {
+	return &Cache{entries: make(map[string]entry)}
+}
+
+// Run implements port.Runner interface
+func (c *Cache) Run(ctx context.Context) error {
+	return nil
+}
+
+// Close implements port.Runner interface
+func (c *Cache) Close(ctx context.Context) error {
+	return nil
+}
+
+func (c *Cache) IsHealthy(ctx context.Context) error {
+	return nil
+}
+
+func (c *Cache) Get(ctx context.Context, key string) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+
+	if !e.expiresAt.IsZero() && !time.Now().Before(e.expiresAt) {
+		delete(c.entries, key)
+		return nil, domain.ErrNotFound
+	}
+
+	return slices.Clone(e.value), nil
+}
+
+// Set stores value. a ttl <= 0 keeps the entry until it is deleted
+func (c *Cache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e := entry{value: slices.Clone(value)}
+	if ttl > 0 {
+		e.expiresAt = time.Now().Add(ttl)
+	}
+
+	c.entries[key] = e
+
+	return nil
+}
+
+func (c *Cache) Delete(ctx context.Context, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.entries, key)
+
+	return nil
+}
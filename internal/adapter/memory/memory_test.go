@@ -0,0 +1,21 @@
+package memory
+
+import (
+	"context"
+	"testing"
+
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain/port"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain/port/porttest"
+)
+
+func TestStorage(t *testing.T) {
+	porttest.TestStorage(t, func(t *testing.T) port.Storage {
+		return NewStorage(context.Background())
+	})
+}
+
+func TestCache(t *testing.T) {
+	porttest.TestCache(t, func(t *testing.T) port.Cache {
+		return NewCache(context.Background())
+	})
+}
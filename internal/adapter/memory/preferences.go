@@ -0,0 +1,45 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain/models"
+)
+
+func (s *Storage) GetPreferences(ctx context.Context, recipient string) (*models.Preferences, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	p, ok := s.preferences[recipient]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+
+	found := *p
+
+	return &found, nil
+}
+
+func (s *Storage) SavePreferences(ctx context.Context, preferences *models.Preferences) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored := *preferences
+	s.preferences[preferences.Recipient] = &stored
+
+	return nil
+}
+
+func (s *Storage) DeletePreferences(ctx context.Context, recipient string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.preferences[recipient]; !ok {
+		return domain.ErrNotFound
+	}
+
+	delete(s.preferences, recipient)
+
+	return nil
+}
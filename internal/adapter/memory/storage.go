@@ -0,0 +1,493 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain"
+	log "github.com/joseCarlosAndrade/notification-server/internal/core/domain/logger"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain/models"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain/port"
+	"go.uber.org/zap"
+)
+
+// maxCollapsedIDs bounds the ids remembered per collapsed notification, as the mongo storage does
+const maxCollapsedIDs = 1000
+
+// notification is a stored notification with the scheduler lease
+type notification struct {
+	models.Notification
+
+	leaseOwner string
+	leaseUntil time.Time
+
+	collapsedIDs []string // ids of the notifications collapsed into this one, so storing them again is a no-op
+}
+
+// Storage implements port.Storage, port.TemplateStorage and port.PreferencesStorage in memory. it follows the
+// same behavior as the mongo storage, so it can replace it in tests and local development. nothing is persisted
+type Storage struct {
+	mu            sync.RWMutex
+	notifications map[string]*notification
+	templates     map[string]*models.Template // keyed by id:locale:version
+	preferences   map[string]*models.Preferences
+}
+
+var _ port.Storage = (*Storage)(nil)
+var _ port.TemplateStorage = (*Storage)(nil)
+var _ port.PreferencesStorage = (*Storage)(nil)
+
+func NewStorage(ctx context.Context) *Storage {
+	log.L(ctx).Warn("using in memory storage. notifications are lost on restart")
+
+	return &Storage{
+		notifications: make(map[string]*notification),
+		templates:     make(map[string]*models.Template),
+		preferences:   make(map[string]*models.Preferences),
+	}
+}
+
+// Run implements port.Runner interface
+func (s *Storage) Run(ctx context.Context) error {
+	return nil
+}
+
+// Close implements port.Runner interface
+func (s *Storage) Close(ctx context.Context) error {
+	return nil
+}
+
+func (s *Storage) IsHealthy(ctx context.Context) error {
+	return nil
+}
+
+func (s *Storage) StoreNewNotification(ctx context.Context, record *models.NotificationRecord, id string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// storing the same id again keeps the stored one (idempotency), so replays don't reset it
+	if stored := s.findStored(id); stored != nil {
+		return stored.ID, nil
+	}
+
+	n := record.AsNotification(id)
+	n.Metadata = maps.Clone(n.Metadata)
+
+	if n.CollapseKey != "" && n.Status == models.StatusDelivered {
+		if existing := s.findCollapsible(n); existing != nil {
+			existing.Title = n.Title
+			existing.Message = n.Message
+			existing.SentAt = n.SentAt
+			existing.Category = n.Category
+			existing.Priority = n.Priority
+			existing.Delivery = n.Delivery
+			existing.Metadata = n.Metadata
+			existing.Count++
+			existing.collapsedIDs = append(existing.collapsedIDs, id)
+			if len(existing.collapsedIDs) > maxCollapsedIDs {
+				existing.collapsedIDs = existing.collapsedIDs[1:]
+			}
+
+			log.L(ctx).Debug("successfully stored collapsed notification in memory",
+				zap.String("id", existing.ID),
+				zap.String("collapseKey", n.CollapseKey),
+				zap.Int("count", existing.Count))
+
+			return existing.ID, nil
+		}
+	}
+
+	s.notifications[id] = &notification{Notification: n}
+
+	return id, nil
+}
+
+// findStored returns the notification stored under id or the one it was collapsed into
+func (s *Storage) findStored(id string) *notification {
+	if n, ok := s.notifications[id]; ok {
+		return n
+	}
+
+	for _, n := range s.notifications {
+		if slices.Contains(n.collapsedIDs, id) {
+			return n
+		}
+	}
+
+	return nil
+}
+
+// findCollapsible returns the unread notification n must be collapsed into
+func (s *Storage) findCollapsible(n models.Notification) *notification {
+	for _, existing := range s.notifications {
+		if existing.Service == n.Service && existing.Recipient == n.Recipient && existing.CollapseKey == n.CollapseKey &&
+			!existing.IsRead && existing.Status == models.StatusDelivered {
+			return existing
+		}
+	}
+
+	return nil
+}
+
+func (s *Storage) GetNotification(ctx context.Context, notificationID string) (*models.Notification, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	n, ok := s.notifications[notificationID]
+	if !ok {
+		return nil, fmt.Errorf("notification %s: %w", notificationID, domain.ErrNotFound)
+	}
+
+	return n.copy(), nil
+}
+
+func (s *Storage) MarkNotificationAsRead(ctx context.Context, notificationID string) error {
+	if notificationID == "" {
+		return fmt.Errorf("%w: notificationID cannot be empty", domain.ErrInvalidArgument)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n, ok := s.notifications[notificationID]
+	if !ok {
+		return fmt.Errorf("notification %s: %w", notificationID, domain.ErrNotFound)
+	}
+
+	now := domain.NewNowTime()
+	n.IsRead = true
+	n.ReadAt = &now
+
+	return nil
+}
+
+func (s *Storage) ArchiveNotification(ctx context.Context, notificationID string, at time.Time) error {
+	return s.update(notificationID, func(n *notification) {
+		if n.ArchivedAt == nil {
+			n.ArchivedAt = &at
+		}
+
+		// archived notifications are read, so they are never collapsed into
+		if !n.IsRead {
+			n.IsRead = true
+			n.ReadAt = &at
+		}
+	})
+}
+
+func (s *Storage) UnarchiveNotification(ctx context.Context, notificationID string) error {
+	return s.update(notificationID, func(n *notification) {
+		n.ArchivedAt = nil
+	})
+}
+
+func (s *Storage) DeleteNotification(ctx context.Context, notificationID string) error {
+	return s.update(notificationID, func(n *notification) {
+		delete(s.notifications, notificationID)
+	})
+}
+
+// update calls fn with the notification under the write lock
+func (s *Storage) update(notificationID string, fn func(n *notification)) error {
+	if notificationID == "" {
+		return fmt.Errorf("%w: notificationID cannot be empty", domain.ErrInvalidArgument)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n, ok := s.notifications[notificationID]
+	if !ok {
+		return fmt.Errorf("notification %s: %w", notificationID, domain.ErrNotFound)
+	}
+
+	fn(n)
+
+	return nil
+}
+
+func (s *Storage) GetAllNotificationsByTime(ctx context.Context, serviceName string, filter models.LastTime) ([]*models.Notification, error) {
+	minutes := time.Duration(filter.Minutes + filter.Hours*60 + filter.Days*1440)
+	targetTimeAgo := domain.NewNowTime().Add(-minutes * time.Minute)
+
+	return s.findVisible(serviceName, 0, func(n *notification) bool {
+		return !n.SentAt.Before(targetTimeAgo)
+	}), nil
+}
+
+func (s *Storage) GetLatestNotifications(ctx context.Context, serviceName string, n int) ([]*models.Notification, error) {
+	return s.findVisible(serviceName, n, nil), nil
+}
+
+func (s *Storage) GetNonReadNotifications(ctx context.Context, serviceName string) ([]*models.Notification, error) {
+	return s.findVisible(serviceName, 0, func(n *notification) bool {
+		return !n.IsRead
+	}), nil
+}
+
+func (s *Storage) ListNotifications(ctx context.Context, filter models.NotificationFilter) ([]*models.Notification, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	found := s.filter(func(n *notification) bool {
+		return n.Service == filter.Service && (filter.IncludeHidden || n.Status.IsVisible()) &&
+			(filter.IncludeArchived || n.ArchivedAt == nil) &&
+			(filter.Recipient == "" || n.Recipient == filter.Recipient) &&
+			(!filter.UnreadOnly || !n.IsRead) &&
+			(filter.Since.IsZero() || !n.SentAt.Before(filter.Since)) &&
+			(filter.Until.IsZero() || n.SentAt.Before(filter.Until)) &&
+			(filter.Search == "" || containsFold(n.Title, filter.Search) || containsFold(n.Message, filter.Search))
+	})
+
+	sort.Slice(found, func(i, j int) bool {
+		if !found[i].SentAt.Equal(found[j].SentAt) {
+			return found[i].SentAt.After(found[j].SentAt)
+		}
+
+		return found[i].ID > found[j].ID
+	})
+
+	if filter.Offset > 0 {
+		found = found[min(filter.Offset, len(found)):]
+	}
+
+	if filter.Limit > 0 && len(found) > filter.Limit {
+		found = found[:filter.Limit]
+	}
+
+	return found, nil
+}
+
+// findVisible returns the visible notifications of the service not archived matching match, most recent first.
+// limit <= 0 returns all
+func (s *Storage) findVisible(serviceName string, limit int, match func(*notification) bool) []*models.Notification {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	found := s.filter(func(n *notification) bool {
+		return n.Service == serviceName && n.Status.IsVisible() && n.ArchivedAt == nil && (match == nil || match(n))
+	})
+
+	sort.Slice(found, func(i, j int) bool {
+		return found[i].SentAt.After(found[j].SentAt)
+	})
+
+	if limit > 0 && len(found) > limit {
+		found = found[:limit]
+	}
+
+	return found
+}
+
+// filter returns copies of the notifications matching match. the caller must hold the lock
+func (s *Storage) filter(match func(*notification) bool) []*models.Notification {
+	found := make([]*models.Notification, 0)
+
+	for _, n := range s.notifications {
+		if match(n) {
+			found = append(found, n.copy())
+		}
+	}
+
+	return found
+}
+
+func (n *notification) copy() *models.Notification {
+	c := n.Notification
+	c.DigestOf = slices.Clone(n.DigestOf)
+
+	return &c
+}
+
+func (s *Storage) ClaimDueNotifications(ctx context.Context, owner string, now time.Time, lease time.Duration, limit int) ([]*models.Notification, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	due := make([]*notification, 0)
+	for _, n := range s.notifications {
+		if n.Status == models.StatusPending && n.DeliverAt != nil && !n.DeliverAt.After(now) &&
+			(n.leaseOwner == "" || n.leaseUntil.Before(now)) {
+			due = append(due, n)
+		}
+	}
+
+	sort.Slice(due, func(i, j int) bool {
+		return due[i].DeliverAt.Before(*due[j].DeliverAt)
+	})
+
+	claimed := make([]*models.Notification, 0, min(len(due), limit))
+
+	for _, n := range due {
+		if len(claimed) >= limit {
+			break
+		}
+
+		n.leaseOwner = owner
+		n.leaseUntil = now.Add(lease)
+		claimed = append(claimed, n.copy())
+	}
+
+	return claimed, nil
+}
+
+func (s *Storage) PromoteNotification(ctx context.Context, notificationID, owner string, deliveredAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n, ok := s.notifications[notificationID]
+	if !ok || n.Status != models.StatusPending || n.leaseOwner != owner {
+		return fmt.Errorf("lease on %s lost: %w", notificationID, domain.ErrConflict)
+	}
+
+	n.Status = models.StatusDelivered
+	n.SentAt = deliveredAt
+	n.leaseOwner = ""
+	n.leaseUntil = time.Time{}
+
+	return nil
+}
+
+func (s *Storage) GetPendingDigestNotifications(ctx context.Context, rule string, before time.Time) ([]*models.Notification, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	found := s.filter(func(n *notification) bool {
+		return n.Status == models.StatusDigestPending && n.DigestRule == rule && n.SentAt.Before(before)
+	})
+
+	sort.Slice(found, func(i, j int) bool {
+		if found[i].Recipient != found[j].Recipient {
+			return found[i].Recipient < found[j].Recipient
+		}
+
+		return found[i].SentAt.Before(found[j].SentAt)
+	})
+
+	return found, nil
+}
+
+func (s *Storage) StoreDigest(ctx context.Context, digest *models.NotificationRecord, id string, originalIDs []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	summary, ok := s.notifications[id]
+	if !ok {
+		n := digest.AsNotification(id)
+		n.Status = models.StatusDelivered
+		n.DigestRule = ""
+		n.Delivery = nil
+		n.CollapseKey = ""
+
+		summary = &notification{Notification: n}
+		s.notifications[id] = summary
+	}
+
+	for _, originalID := range originalIDs {
+		if !slices.Contains(summary.DigestOf, originalID) {
+			summary.DigestOf = append(summary.DigestOf, originalID)
+		}
+
+		if original, ok := s.notifications[originalID]; ok && original.Status == models.StatusDigestPending {
+			original.Status = models.StatusDigested
+			original.DigestID = id
+		}
+	}
+
+	return nil
+}
+
+func (s *Storage) DeleteNotifications(ctx context.Context, filter models.DeleteFilter) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var deleted int64
+
+	for id, n := range s.notifications {
+		if n.Service == filter.Service &&
+			(filter.Recipient == "" || n.Recipient == filter.Recipient) &&
+			(filter.Since.IsZero() || !n.SentAt.Before(filter.Since)) &&
+			(filter.Until.IsZero() || n.SentAt.Before(filter.Until)) {
+			delete(s.notifications, id)
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
+
+func (s *Storage) NotificationStats(ctx context.Context) ([]*models.ServiceStats, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	byService := make(map[string]*models.ServiceStats)
+
+	for _, n := range s.notifications {
+		stats, ok := byService[n.Service]
+		if !ok {
+			stats = &models.ServiceStats{Service: n.Service}
+			byService[n.Service] = stats
+		}
+
+		stats.Add(n.Status, n.IsRead, 1, n.SentAt, n.SentAt)
+	}
+
+	stats := slices.Collect(maps.Values(byService))
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Service < stats[j].Service
+	})
+
+	return stats, nil
+}
+
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
+
+func (s *Storage) ExportNotifications(ctx context.Context, filter models.ExportFilter,
+	fn func(*models.Notification) error) error {
+	s.mu.RLock()
+	found := s.filter(func(n *notification) bool {
+		return n.Service == filter.Service &&
+			(filter.Recipient == "" || n.Recipient == filter.Recipient) &&
+			(filter.Since.IsZero() || !n.SentAt.Before(filter.Since)) &&
+			(filter.Until.IsZero() || n.SentAt.Before(filter.Until))
+	})
+	s.mu.RUnlock()
+
+	sort.Slice(found, func(i, j int) bool {
+		if !found[i].SentAt.Equal(found[j].SentAt) {
+			return found[i].SentAt.Before(found[j].SentAt)
+		}
+
+		return found[i].ID < found[j].ID
+	})
+
+	for _, n := range found {
+		if err := fn(n); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *Storage) RestoreNotifications(ctx context.Context, notifications []*models.Notification) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, n := range notifications {
+		restored := *n
+		restored.DigestOf = slices.Clone(n.DigestOf)
+		restored.Metadata = maps.Clone(n.Metadata)
+
+		s.notifications[n.ID] = &notification{Notification: restored}
+	}
+
+	return nil
+}
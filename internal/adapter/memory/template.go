@@ -0,0 +1,92 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain/models"
+)
+
+func templateKey(templateID, locale string, version int) string {
+	return fmt.Sprintf("%s:%s:%d", templateID, locale, version)
+}
+
+func (s *Storage) StoreTemplate(ctx context.Context, template *models.Template) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := templateKey(template.ID, template.Locale, template.Version)
+	if _, ok := s.templates[key]; ok {
+		return fmt.Errorf("template %s version %d: %w", key, template.Version, domain.ErrConflict)
+	}
+
+	stored := *template
+	s.templates[key] = &stored
+
+	return nil
+}
+
+func (s *Storage) GetLatestTemplate(ctx context.Context, templateID, locale string) (*models.Template, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var latest *models.Template
+
+	for _, t := range s.templates {
+		if t.ID == templateID && t.Locale == locale && (latest == nil || t.Version > latest.Version) {
+			latest = t
+		}
+	}
+
+	if latest == nil {
+		return nil, domain.ErrNotFound
+	}
+
+	found := *latest
+
+	return &found, nil
+}
+
+func (s *Storage) GetTemplateVersion(ctx context.Context, templateID, locale string, version int) (*models.Template, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	t, ok := s.templates[templateKey(templateID, locale, version)]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+
+	found := *t
+
+	return &found, nil
+}
+
+func (s *Storage) ListTemplateVersions(ctx context.Context, templateID string) ([]*models.Template, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	final := make([]*models.Template, 0)
+
+	for _, t := range s.templates {
+		if t.ID == templateID {
+			found := *t
+			final = append(final, &found)
+		}
+	}
+
+	if len(final) == 0 {
+		return nil, domain.ErrNotFound
+	}
+
+	sort.Slice(final, func(i, j int) bool {
+		if final[i].Locale != final[j].Locale {
+			return final[i].Locale < final[j].Locale
+		}
+
+		return final[i].Version > final[j].Version
+	})
+
+	return final, nil
+}
@@ -0,0 +1,165 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	log "github.com/joseCarlosAndrade/notification-server/internal/core/domain/logger"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain/models"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain/port"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.uber.org/zap"
+)
+
+var _ port.IndexMigrator = (*Storage)(nil)
+
+func (s *Storage) DeleteNotifications(ctx context.Context, filter models.DeleteFilter) (_ int64, err error) {
+	ctx, end := instrument(ctx, "DeleteNotifications")
+	defer end(&err)
+
+	query := bson.M{"service": filter.Service}
+
+	if filter.Recipient != "" {
+		query["recipient"] = filter.Recipient
+	}
+
+	addSentAtRange(query, filter.Since, filter.Until)
+
+	result, err := s.notificationCollection.DeleteMany(ctx, query)
+	if err != nil {
+		log.L(ctx).Error("could not delete notifications", zap.Any("filter", query), zap.Error(err))
+		return 0, fmt.Errorf("could not delete notifications: %w", err)
+	}
+
+	return result.DeletedCount, nil
+}
+
+// statsGroup is the count of the notifications of a service with the same status and read state
+type statsGroup struct {
+	Key struct {
+		Service string `bson:"service"`
+		Status  string `bson:"status"`
+		IsRead  bool   `bson:"isRead"`
+	} `bson:"_id"`
+	Count  int64     `bson:"count"`
+	Oldest time.Time `bson:"oldest"`
+	Newest time.Time `bson:"newest"`
+}
+
+func (s *Storage) NotificationStats(ctx context.Context) (_ []*models.ServiceStats, err error) {
+	ctx, end := instrument(ctx, "NotificationStats")
+	defer end(&err)
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$group", Value: bson.M{
+			"_id":    bson.M{"service": "$service", "status": "$status", "isRead": "$isRead"},
+			"count":  bson.M{"$sum": 1},
+			"oldest": bson.M{"$min": "$sentAt"},
+			"newest": bson.M{"$max": "$sentAt"},
+		}}},
+	}
+
+	cursor, err := s.notificationCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		log.L(ctx).Error("could not aggregate notification stats", zap.Error(err))
+		return nil, fmt.Errorf("could not aggregate notification stats: %w", err)
+	}
+
+	// one group per service, status and read state, so they always fit in memory
+	var groups []statsGroup
+	if err := cursor.All(ctx, &groups); err != nil {
+		log.L(ctx).Error("could not decode notification stats", zap.Error(err))
+		return nil, fmt.Errorf("could not decode notification stats: %w", err)
+	}
+
+	byService := make(map[string]*models.ServiceStats)
+	stats := make([]*models.ServiceStats, 0)
+
+	for _, group := range groups {
+		service, ok := byService[group.Key.Service]
+		if !ok {
+			service = &models.ServiceStats{Service: group.Key.Service}
+			byService[group.Key.Service] = service
+			stats = append(stats, service)
+		}
+
+		service.Add(notificationStatus(group.Key.Status), group.Key.IsRead, group.Count, group.Oldest, group.Newest)
+	}
+
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Service < stats[j].Service
+	})
+
+	return stats, nil
+}
+
+// MigrateIndexes implements port.IndexMigrator. the indexes are reported as collection.name
+func (s *Storage) MigrateIndexes(ctx context.Context, dropStale bool) (_ models.IndexMigration, err error) {
+	ctx, end := instrument(ctx, "MigrateIndexes")
+	defer end(&err)
+
+	migration := models.IndexMigration{}
+
+	collections := []*mongo.Collection{s.notificationCollection, s.templateCollection, s.preferenceCollection}
+	existing := make(map[string]map[string]bool, len(collections)) // index names by collection
+
+	for _, collection := range collections {
+		specs, err := collection.Indexes().ListSpecifications(ctx)
+		if err != nil {
+			log.L(ctx).Error("could not list indexes", zap.String("collection", collection.Name()), zap.Error(err))
+			return migration, fmt.Errorf("could not list indexes of %s: %w", collection.Name(), err)
+		}
+
+		existing[collection.Name()] = make(map[string]bool, len(specs))
+		for _, spec := range specs {
+			existing[collection.Name()][spec.Name] = true
+		}
+	}
+
+	used := make(map[string]bool)
+
+	for _, index := range s.indexes() {
+		collection := index.collection.Name()
+
+		name, err := index.collection.Indexes().CreateOne(ctx, index.model)
+		if err != nil {
+			log.L(ctx).Error("could not create index", zap.String("index", index.name), zap.Error(err))
+			return migration, fmt.Errorf("could not create %s index: %w", index.name, err)
+		}
+
+		used[collection+"."+name] = true
+
+		if existing[collection][name] {
+			migration.Kept = append(migration.Kept, collection+"."+name)
+		} else {
+			migration.Created = append(migration.Created, collection+"."+name)
+		}
+	}
+
+	if !dropStale {
+		return migration, nil
+	}
+
+	for _, collection := range collections {
+		for name := range existing[collection.Name()] {
+			qualified := collection.Name() + "." + name
+			if name == "_id_" || used[qualified] {
+				continue
+			}
+
+			if err := collection.Indexes().DropOne(ctx, name); err != nil {
+				log.L(ctx).Error("could not drop index", zap.String("index", qualified), zap.Error(err))
+				return migration, fmt.Errorf("could not drop index %s: %w", qualified, err)
+			}
+
+			migration.Dropped = append(migration.Dropped, qualified)
+		}
+	}
+
+	sort.Strings(migration.Dropped)
+
+	return migration, nil
+}
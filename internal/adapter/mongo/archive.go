@@ -0,0 +1,81 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain"
+	log "github.com/joseCarlosAndrade/notification-server/internal/core/domain/logger"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.uber.org/zap"
+)
+
+func (s *Storage) ArchiveNotification(ctx context.Context, notificationID string, at time.Time) (err error) {
+	ctx, end := instrument(ctx, "ArchiveNotification")
+	defer end(&err)
+
+	// a pipeline update keeps archivedAt and readAt when they are already set. archived notifications are read,
+	// so they leave the collapse index and are never collapsed into
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"archivedAt": bson.M{"$ifNull": bson.A{"$archivedAt", at}},
+			"readAt":     bson.M{"$ifNull": bson.A{"$readAt", at}},
+			"isRead":     true,
+		}}},
+	}
+
+	return s.updateNotification(ctx, notificationID, update, "could not archive notification")
+}
+
+func (s *Storage) UnarchiveNotification(ctx context.Context, notificationID string) (err error) {
+	ctx, end := instrument(ctx, "UnarchiveNotification")
+	defer end(&err)
+
+	update := bson.M{
+		"$unset": bson.M{"archivedAt": ""},
+	}
+
+	return s.updateNotification(ctx, notificationID, update, "could not unarchive notification")
+}
+
+func (s *Storage) DeleteNotification(ctx context.Context, notificationID string) (err error) {
+	ctx, end := instrument(ctx, "DeleteNotification")
+	defer end(&err)
+
+	if notificationID == "" {
+		return fmt.Errorf("%w: notificationID cannot be empty", domain.ErrInvalidArgument)
+	}
+
+	res, err := s.notificationCollection.DeleteOne(ctx, bson.M{"_id": notificationID})
+	if err != nil {
+		log.L(ctx).Error("could not delete notification", zap.String("id", notificationID), zap.Error(err))
+		return fmt.Errorf("could not delete notification: %w", err)
+	}
+
+	if res.DeletedCount == 0 {
+		return fmt.Errorf("notification %s: %w", notificationID, domain.ErrNotFound)
+	}
+
+	return nil
+}
+
+// updateNotification applies update to the notification. returns domain.ErrNotFound if it does not exist
+func (s *Storage) updateNotification(ctx context.Context, notificationID string, update any, msg string) error {
+	if notificationID == "" {
+		return fmt.Errorf("%w: notificationID cannot be empty", domain.ErrInvalidArgument)
+	}
+
+	res, err := s.notificationCollection.UpdateOne(ctx, bson.M{"_id": notificationID}, update)
+	if err != nil {
+		log.L(ctx).Error(msg, zap.String("id", notificationID), zap.Error(err))
+		return fmt.Errorf("%s: %w", msg, err)
+	}
+
+	if res.MatchedCount == 0 {
+		return fmt.Errorf("notification %s: %w", notificationID, domain.ErrNotFound)
+	}
+
+	return nil
+}
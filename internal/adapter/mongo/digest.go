@@ -0,0 +1,99 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	log "github.com/joseCarlosAndrade/notification-server/internal/core/domain/logger"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"go.uber.org/zap"
+)
+
+func (s *Storage) GetPendingDigestNotifications(ctx context.Context, rule string, before time.Time) (_ []*models.Notification, err error) {
+	ctx, end := instrument(ctx, "GetPendingDigestNotifications")
+	defer end(&err)
+
+	filter := bson.M{
+		"status":     string(models.StatusDigestPending),
+		"digestRule": rule,
+		"sentAt":     bson.M{"$lt": before},
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "recipient", Value: 1}, {Key: "sentAt", Value: 1}})
+
+	ctxTimeout, cancel := context.WithTimeout(ctx, time.Second*10)
+	defer cancel()
+
+	cursor, err := s.notificationCollection.Find(ctxTimeout, filter, opts)
+	if err != nil {
+		log.L(ctx).Error("could not find pending digest notifications", zap.String("rule", rule), zap.Error(err))
+		return nil, fmt.Errorf("could not find pending digest notifications: %w", err)
+	}
+
+	var results []Notification
+	if err := cursor.All(ctxTimeout, &results); err != nil {
+		log.L(ctx).Error("cursor iteration failed", zap.Error(err))
+		return nil, err
+	}
+
+	return transformNotificationsToDomain(results), nil
+}
+
+// StoreDigest upserts the summary notification and marks the originals as digested. the summary id is
+// deterministic per rule, recipient and window, so replicas collapsing the same window converge on one document
+func (s *Storage) StoreDigest(ctx context.Context, digest *models.NotificationRecord, id string, originalIDs []string) (err error) {
+	ctx, end := instrument(ctx, "StoreDigest")
+	defer end(&err)
+
+	doc := transformNotificationToMongo(digest, id)
+
+	filter := bson.M{"_id": id}
+
+	update := bson.M{
+		"$setOnInsert": bson.M{
+			"service":   doc.Service,
+			"recipient": doc.Recipient,
+			"title":     doc.Title,
+			"message":   doc.Message,
+			"isRead":    false,
+			"sentAt":    doc.SentAt,
+			"category":  doc.Category,
+			"priority":  doc.Priority,
+			"status":    string(models.StatusDelivered),
+		},
+		"$addToSet": bson.M{
+			"digestOf": bson.M{"$each": originalIDs},
+		},
+	}
+
+	_, err = s.notificationCollection.UpdateOne(ctx, filter, update, options.UpdateOne().SetUpsert(true))
+	if err != nil {
+		log.L(ctx).Error("could not store digest", zap.String("id", id), zap.Error(err))
+		return fmt.Errorf("could not store digest: %w", err)
+	}
+
+	res, err := s.notificationCollection.UpdateMany(ctx,
+		bson.M{
+			"_id":    bson.M{"$in": originalIDs},
+			"status": string(models.StatusDigestPending),
+		},
+		bson.M{
+			"$set": bson.M{
+				"status":   string(models.StatusDigested),
+				"digestId": id,
+			},
+		})
+	if err != nil {
+		log.L(ctx).Error("could not mark notifications as digested", zap.String("digestId", id), zap.Error(err))
+		return fmt.Errorf("could not mark notifications as digested: %w", err)
+	}
+
+	log.L(ctx).Debug("digest stored",
+		zap.String("id", id),
+		zap.Int64("digested", res.ModifiedCount))
+
+	return nil
+}
@@ -0,0 +1,112 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+
+	log "github.com/joseCarlosAndrade/notification-server/internal/core/domain/logger"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"go.uber.org/zap"
+)
+
+// ExportNotifications iterates the cursor instead of decoding every document at once, so only one batch of
+// the cursor is in memory at a time. the list index backs the query
+func (s *Storage) ExportNotifications(ctx context.Context, filter models.ExportFilter,
+	fn func(*models.Notification) error) (err error) {
+	ctx, end := instrument(ctx, "ExportNotifications")
+	defer end(&err)
+
+	query := bson.M{"service": filter.Service}
+
+	if filter.Recipient != "" {
+		query["recipient"] = filter.Recipient
+	}
+
+	addSentAtRange(query, filter.Since, filter.Until)
+
+	opts := options.Find().SetSort(bson.D{{Key: "sentAt", Value: 1}, {Key: "_id", Value: 1}})
+
+	cursor, err := s.notificationCollection.Find(ctx, query, opts)
+	if err != nil {
+		log.L(ctx).Error("could not export notifications", zap.Any("filter", query), zap.Error(err))
+		return fmt.Errorf("could not export notifications: %w", err)
+	}
+	defer cursor.Close(context.WithoutCancel(ctx))
+
+	for cursor.Next(ctx) {
+		var doc Notification
+		if err := cursor.Decode(&doc); err != nil {
+			log.L(ctx).Error("could not decode exported notification", zap.Error(err))
+			return fmt.Errorf("could not decode exported notification: %w", err)
+		}
+
+		if err := fn(transformNotificationToDomain(&doc)); err != nil {
+			return err
+		}
+	}
+
+	if err := cursor.Err(); err != nil {
+		log.L(ctx).Error("could not read exported notifications", zap.Error(err))
+		return fmt.Errorf("could not read exported notifications: %w", err)
+	}
+
+	return nil
+}
+
+// RestoreNotifications replaces the documents in a single unordered bulk write
+func (s *Storage) RestoreNotifications(ctx context.Context, notifications []*models.Notification) (err error) {
+	ctx, end := instrument(ctx, "RestoreNotifications")
+	defer end(&err)
+
+	if len(notifications) == 0 {
+		return nil
+	}
+
+	writes := make([]mongo.WriteModel, 0, len(notifications))
+	for _, n := range notifications {
+		writes = append(writes, mongo.NewReplaceOneModel().
+			SetFilter(bson.M{"_id": n.ID}).
+			SetReplacement(transformStoredNotificationToMongo(n)).
+			SetUpsert(true))
+	}
+
+	if _, err := s.notificationCollection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false)); err != nil {
+		log.L(ctx).Error("could not restore notifications", zap.Int("count", len(notifications)), zap.Error(err))
+		return fmt.Errorf("could not restore notifications: %w", err)
+	}
+
+	return nil
+}
+
+// transformStoredNotificationToMongo is the document of a notification that was already stored, as exported
+func transformStoredNotificationToMongo(n *models.Notification) *Notification {
+	return &Notification{
+		ID:        n.ID,
+		Service:   n.Service,
+		Recipient: n.Recipient,
+		Title:     n.Title,
+		Message:   n.Message,
+		IsRead:    n.IsRead,
+		SentAt:    n.SentAt,
+		ReadAt:    n.ReadAt,
+		Category:  n.Category,
+		Priority:  string(n.Priority),
+		Status:    string(n.Status),
+		DeliverAt: n.DeliverAt,
+
+		DigestRule: n.DigestRule,
+		DigestID:   n.DigestID,
+		DigestOf:   n.DigestOf,
+		Delivery:   transformDeliveryToMongo(n.Delivery),
+
+		CollapseKey: n.CollapseKey,
+		Count:       n.Count,
+
+		Metadata: n.Metadata,
+
+		ArchivedAt: n.ArchivedAt,
+	}
+}
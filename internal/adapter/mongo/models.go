@@ -4,10 +4,71 @@ import "time"
 
 // Notification is the document schema for mongodb notification storage
 type Notification struct {
-	ID      string     `bson:"_id"`
-	Service string     `bson:"service"`
-	Message string     `bson:"message"`
-	IsRead  bool       `bson:"isRead"`
-	SentAt  time.Time  `bson:"sentAt"`
-	ReadAt  *time.Time `bson:"readAt"` // might not exist yet
+	ID        string     `bson:"_id"`
+	Service   string     `bson:"service"`
+	Recipient string     `bson:"recipient,omitempty"`
+	Title     string     `bson:"title,omitempty"`
+	Message   string     `bson:"message"`
+	IsRead    bool       `bson:"isRead"`
+	SentAt    time.Time  `bson:"sentAt"`
+	ReadAt    *time.Time `bson:"readAt"` // might not exist yet
+	Category  string     `bson:"category,omitempty"`
+	Priority  string     `bson:"priority,omitempty"`
+
+	Status    string     `bson:"status,omitempty"`
+	DeliverAt *time.Time `bson:"deliverAt,omitempty"`
+
+	DigestRule string   `bson:"digestRule,omitempty"`
+	DigestID   string   `bson:"digestId,omitempty"`
+	DigestOf   []string `bson:"digestOf,omitempty"`
+
+	Delivery *Delivery `bson:"delivery,omitempty"`
+
+	CollapseKey  string   `bson:"collapseKey,omitempty"`
+	Count        int      `bson:"count,omitempty"`        // Count is how many notifications were collapsed into this one
+	CollapsedIDs []string `bson:"collapsedIds,omitempty"` // CollapsedIDs are the last ids collapsed into this one
+
+	Metadata map[string]string `bson:"metadata,omitempty"`
+
+	ArchivedAt *time.Time `bson:"archivedAt,omitempty"`
+
+	// lease fields are set while a scheduler replica is promoting a pending notification
+	LeaseOwner string     `bson:"leaseOwner,omitempty"`
+	LeaseUntil *time.Time `bson:"leaseUntil,omitempty"`
+}
+
+// Template is the document schema for notification templates. _id is templateId:locale:version
+type Template struct {
+	ID         string         `bson:"_id"`
+	TemplateID string         `bson:"templateId"`
+	Locale     string         `bson:"locale"`
+	Version    int            `bson:"version"`
+	Title      string         `bson:"title"`
+	Body       string         `bson:"body"`
+	Example    map[string]any `bson:"example,omitempty"`
+	CreatedAt  time.Time      `bson:"createdAt"`
+}
+
+// Delivery is the decision taken from the recipient preferences
+type Delivery struct {
+	Decision string   `bson:"decision"`
+	Reason   string   `bson:"reason,omitempty"`
+	Channels []string `bson:"channels,omitempty"`
+}
+
+// Preferences is the document schema for recipient preferences. _id is the recipient
+type Preferences struct {
+	Recipient       string              `bson:"_id"`
+	Locale          string              `bson:"locale,omitempty"`
+	TimeZone        string              `bson:"timeZone,omitempty"`
+	MutedServices   []string            `bson:"mutedServices,omitempty"`
+	MutedCategories []string            `bson:"mutedCategories,omitempty"`
+	QuietHours      *QuietHours         `bson:"quietHours,omitempty"`
+	Channels        map[string][]string `bson:"channels,omitempty"`
+	UpdatedAt       time.Time           `bson:"updatedAt"`
+}
+
+type QuietHours struct {
+	Start string `bson:"start"`
+	End   string `bson:"end"`
 }
@@ -2,17 +2,25 @@ package mongo
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"regexp"
 	"time"
 
+	"github.com/joseCarlosAndrade/notification-server/internal/core/config"
 	"github.com/joseCarlosAndrade/notification-server/internal/core/domain"
 	log "github.com/joseCarlosAndrade/notification-server/internal/core/domain/logger"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain/metrics"
 	"github.com/joseCarlosAndrade/notification-server/internal/core/domain/models"
 	"github.com/joseCarlosAndrade/notification-server/internal/core/domain/port"
 	"go.mongodb.org/mongo-driver/v2/bson"
 	"go.mongodb.org/mongo-driver/v2/mongo"
 	"go.mongodb.org/mongo-driver/v2/mongo/options"
 	"go.mongodb.org/mongo-driver/v2/mongo/readpref"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
@@ -23,11 +31,24 @@ type Storage struct {
 	collectionName string
 
 	notificationCollection *mongo.Collection
+	templateCollection     *mongo.Collection
+	preferenceCollection   *mongo.Collection
 }
 
-var _ port.Storage = (*Storage)(nil) // ensures Storage implements port.Storage
+var tracer = otel.Tracer(config.AppTraceName)
 
-func NewStorage(ctx context.Context, connectionStr, mongoDB, mongoCollection string) (Storage, error) {
+var _ port.Storage = (*Storage)(nil)         // ensures Storage implements port.Storage
+var _ port.TemplateStorage = (*Storage)(nil) // ensures Storage implements port.TemplateStorage
+var _ port.PreferencesStorage = (*Storage)(nil)
+
+// Collections holds the names of the collections used by the storage
+type Collections struct {
+	Notifications string
+	Templates     string
+	Preferences   string
+}
+
+func NewStorage(ctx context.Context, connectionStr, mongoDB string, collections Collections) (Storage, error) {
 	client, err := mongo.Connect(options.Client().ApplyURI(connectionStr))
 	if err != nil {
 		log.L(ctx).Error("invalid mongo config", zap.Error(err))
@@ -45,12 +66,107 @@ func NewStorage(ctx context.Context, connectionStr, mongoDB, mongoCollection str
 
 	log.L(ctx).Info("successfully connected to mongodb")
 
-	return Storage{
+	storage := Storage{
 		client:                 client,
 		dbName:                 mongoDB,
-		collectionName:         mongoCollection,
-		notificationCollection: client.Database(mongoDB).Collection(mongoCollection),
-	}, nil
+		collectionName:         collections.Notifications,
+		notificationCollection: client.Database(mongoDB).Collection(collections.Notifications),
+		templateCollection:     client.Database(mongoDB).Collection(collections.Templates),
+		preferenceCollection:   client.Database(mongoDB).Collection(collections.Preferences),
+	}
+
+	if err := storage.ensureIndexes(ctx); err != nil {
+		log.L(ctx).Error("could not create indexes", zap.Error(err))
+		return Storage{}, fmt.Errorf("could not create indexes: %w", err)
+	}
+
+	return storage, nil
+}
+
+// index is an index the queries rely on
+type index struct {
+	name       string
+	collection *mongo.Collection
+	model      mongo.IndexModel
+}
+
+// indexes returns every index the storage uses
+func (s *Storage) indexes() []index {
+	return []index{
+		{
+			name:       "templates",
+			collection: s.templateCollection,
+			model: mongo.IndexModel{
+				Keys:    bson.D{{Key: "templateId", Value: 1}, {Key: "locale", Value: 1}, {Key: "version", Value: -1}},
+				Options: options.Index().SetUnique(true),
+			},
+		},
+		{
+			// used by the digest worker to accumulate notifications per rule and recipient
+			name:       "digest",
+			collection: s.notificationCollection,
+			model: mongo.IndexModel{
+				Keys: bson.D{{Key: "digestRule", Value: 1}, {Key: "recipient", Value: 1}, {Key: "sentAt", Value: 1}},
+				Options: options.Index().SetPartialFilterExpression(bson.M{
+					"status": string(models.StatusDigestPending),
+				}),
+			},
+		},
+		{
+			// at most one unread notification per collapse key. also backs the collapse upsert lookup
+			name:       "collapse",
+			collection: s.notificationCollection,
+			model: mongo.IndexModel{
+				Keys: bson.D{{Key: "service", Value: 1}, {Key: "recipient", Value: 1}, {Key: "collapseKey", Value: 1}},
+				Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{
+					"collapseKey": bson.M{"$exists": true},
+					"isRead":      false,
+					"status":      string(models.StatusDelivered),
+				}),
+			},
+		},
+		{
+			// finds the notification an id was collapsed into, so storing it again is a no-op
+			name:       "collapsedIds",
+			collection: s.notificationCollection,
+			model: mongo.IndexModel{
+				Keys: bson.D{{Key: "collapsedIds", Value: 1}},
+				Options: options.Index().SetPartialFilterExpression(bson.M{
+					"collapsedIds": bson.M{"$exists": true},
+				}),
+			},
+		},
+		{
+			// used to list the notifications of a service, most recent first
+			name:       "list",
+			collection: s.notificationCollection,
+			model: mongo.IndexModel{
+				Keys: bson.D{{Key: "service", Value: 1}, {Key: "sentAt", Value: -1}, {Key: "_id", Value: -1}},
+			},
+		},
+		{
+			// used by the scheduler to find due pending notifications
+			name:       "pending",
+			collection: s.notificationCollection,
+			model: mongo.IndexModel{
+				Keys: bson.D{{Key: "status", Value: 1}, {Key: "deliverAt", Value: 1}},
+				Options: options.Index().SetPartialFilterExpression(bson.M{
+					"status": string(models.StatusPending),
+				}),
+			},
+		},
+	}
+}
+
+// ensureIndexes creates the indexes the queries rely on. creating an existing index is a no-op
+func (s *Storage) ensureIndexes(ctx context.Context) error {
+	for _, index := range s.indexes() {
+		if _, err := index.collection.Indexes().CreateOne(ctx, index.model); err != nil {
+			return fmt.Errorf("%s index: %w", index.name, err)
+		}
+	}
+
+	return nil
 }
 
 // Run implements port.Runner interface
@@ -69,47 +185,169 @@ func (s *Storage) IsHealthy(ctx context.Context) error {
 	return s.client.Ping(ctx, readpref.Primary())
 }
 
-func (s *Storage) StoreNewNotification(ctx context.Context, notification *models.NotificationRecord, id string) error {
-	// start span here
+func (s *Storage) StoreNewNotification(ctx context.Context, notification *models.NotificationRecord, id string) (_ string, err error) {
+	ctx, end := instrument(ctx, "StoreNewNotification")
+	defer end(&err)
 
 	mongoNotification := transformNotificationToMongo(notification, id)
 
+	if mongoNotification.CollapseKey != "" && mongoNotification.Status == string(models.StatusDelivered) {
+		return s.storeCollapsedNotification(ctx, mongoNotification)
+	}
+
 	filter := bson.M{"_id": id}
 
+	// only inserted: storing the same id again must not reset the read, archived or digest state (idempotency)
 	update := bson.M{
-		"$set": *mongoNotification,
+		"$setOnInsert": *mongoNotification,
 	}
 
 	opts := options.UpdateOne().SetUpsert(true)
 
 	// using upsert to avoid duplicate if the service tries to save the same notification (idempotency)
-	_, err := s.notificationCollection.UpdateOne(ctx, filter, update, opts)
+	_, err = s.notificationCollection.UpdateOne(ctx, filter, update, opts)
 	if err != nil {
 		log.L(ctx).Error("could not insert new notification",
 			zap.String("id", mongoNotification.ID),
 			zap.String("service", mongoNotification.Service),
 			zap.Error(err))
 
-		return fmt.Errorf("failed to insert in mongodb: %w", err)
+		return "", fmt.Errorf("failed to insert in mongodb: %w", err)
 	}
 
 	log.L(ctx).Debug("successfully stored new notification in mongo",
 		zap.String("id", mongoNotification.ID),
 		zap.String("service", mongoNotification.Service))
 
-	return nil
+	return id, nil
+}
+
+// maxCollapsedIDs bounds the ids remembered per collapsed notification. ids older than that count again if they
+// are replayed
+const maxCollapsedIDs = 1000
+
+// storeCollapsedNotification replaces the content of the unread notification with the same service, recipient and
+// collapse key, incrementing its counter, or inserts a new one. the unique partial index on these fields makes
+// concurrent consumers race on the insert: the loser gets a duplicate key error and retries as an update.
+// an id already stored or collapsed is not counted again
+func (s *Storage) storeCollapsedNotification(ctx context.Context, n *Notification) (string, error) {
+	var existing struct {
+		ID string `bson:"_id"`
+	}
+
+	err := s.notificationCollection.FindOne(ctx,
+		bson.M{"$or": bson.A{bson.M{"_id": n.ID}, bson.M{"collapsedIds": n.ID}}},
+		options.FindOne().SetProjection(bson.M{"_id": 1}),
+	).Decode(&existing)
+
+	switch {
+	case err == nil:
+		log.L(ctx).Debug("collapsed notification already stored",
+			zap.String("id", n.ID),
+			zap.String("storedId", existing.ID))
+
+		return existing.ID, nil
+	case !errors.Is(err, mongo.ErrNoDocuments):
+		log.L(ctx).Error("could not look up collapsed notification", zap.String("id", n.ID), zap.Error(err))
+		return "", fmt.Errorf("could not look up collapsed notification in mongodb: %w", err)
+	}
+
+	filter := bson.M{
+		"service":     n.Service,
+		"recipient":   n.Recipient,
+		"collapseKey": n.CollapseKey,
+		"isRead":      false,
+		"status":      string(models.StatusDelivered),
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"title":    n.Title,
+			"message":  n.Message,
+			"sentAt":   n.SentAt,
+			"category": n.Category,
+			"priority": n.Priority,
+			"delivery": n.Delivery,
+			"metadata": n.Metadata,
+		},
+		"$inc": bson.M{
+			"count": 1,
+		},
+		"$push": bson.M{
+			"collapsedIds": bson.M{"$each": bson.A{n.ID}, "$slice": -maxCollapsedIDs},
+		},
+		"$setOnInsert": bson.M{
+			"_id":    n.ID,
+			"readAt": nil,
+		},
+	}
+
+	opts := options.FindOneAndUpdate().
+		SetUpsert(true).
+		SetReturnDocument(options.After).
+		SetProjection(bson.M{"_id": 1, "count": 1})
+
+	var stored struct {
+		ID    string `bson:"_id"`
+		Count int    `bson:"count"`
+	}
+
+	for range 2 {
+		err = s.notificationCollection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&stored)
+		if !mongo.IsDuplicateKeyError(err) {
+			break
+		}
+	}
+
+	if err != nil {
+		log.L(ctx).Error("could not store collapsed notification",
+			zap.String("service", n.Service),
+			zap.String("collapseKey", n.CollapseKey),
+			zap.Error(err))
+
+		return "", fmt.Errorf("failed to store collapsed notification in mongodb: %w", err)
+	}
+
+	log.L(ctx).Debug("successfully stored collapsed notification in mongo",
+		zap.String("id", stored.ID),
+		zap.String("collapseKey", n.CollapseKey),
+		zap.Int("count", stored.Count))
+
+	return stored.ID, nil
+}
+
+func (s *Storage) GetNotification(ctx context.Context, notificationID string) (_ *models.Notification, err error) {
+	ctx, end := instrument(ctx, "GetNotification")
+	defer end(&err)
+
+	var doc Notification
+
+	err = s.notificationCollection.FindOne(ctx, bson.M{"_id": notificationID}).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, fmt.Errorf("notification %s: %w", notificationID, domain.ErrNotFound)
+	}
+
+	if err != nil {
+		log.L(ctx).Error("could not find notification", zap.String("id", notificationID), zap.Error(err))
+		return nil, fmt.Errorf("could not find notification: %w", err)
+	}
+
+	return transformNotificationToDomain(&doc), nil
 }
 
-func (s *Storage) MarkNotificationAsRead(ctx context.Context, notificationID string) error {
+func (s *Storage) MarkNotificationAsRead(ctx context.Context, notificationID string) (err error) {
+	ctx, end := instrument(ctx, "MarkNotificationAsRead")
+	defer end(&err)
+
 	if notificationID == "" {
-		return fmt.Errorf("notificationID canont be empty")
+		return fmt.Errorf("%w: notificationID canont be empty", domain.ErrInvalidArgument)
 	}
 
 	filter := bson.M{
 		"_id": notificationID,
 	}
 
-	now := domain.NewNowTimeString()
+	now := domain.NewNowTime()
 
 	update := bson.M{
 		"$set": bson.M{
@@ -129,7 +367,7 @@ func (s *Storage) MarkNotificationAsRead(ctx context.Context, notificationID str
 
 	if res.MatchedCount == 0 {
 		log.L(ctx).Error("no documents matched this filter", zap.String("id", notificationID))
-		return fmt.Errorf("no documents matched this filter")
+		return fmt.Errorf("notification %s: %w", notificationID, domain.ErrNotFound)
 	}
 
 	log.L(ctx).Info("notification successfully marked as read", zap.String("id", notificationID))
@@ -138,18 +376,58 @@ func (s *Storage) MarkNotificationAsRead(ctx context.Context, notificationID str
 }
 
 func transformNotificationToMongo(notification *models.NotificationRecord, id string) *Notification {
+	status := notification.InitialStatus()
+
 	return &Notification{
-		ID:      id,
-		Service: notification.Service,
-		Message: notification.Message,
-		IsRead:  false,
-		SentAt:  *notification.SentAt,
+		ID:        id,
+		Service:   notification.Service,
+		Recipient: notification.Recipient,
+		Title:     notification.Title,
+		Message:   notification.Message,
+		IsRead:    false,
+		SentAt:    *notification.SentAt,
+		Category:  notification.Category,
+		Priority:  string(notification.Priority),
+		Status:    string(status),
+		DeliverAt: notification.DeliverAt,
+
+		DigestRule: notification.DigestRule,
+		Delivery:   transformDeliveryToMongo(notification.Delivery),
+
+		CollapseKey: notification.CollapseKey,
+		Count:       1,
+
+		Metadata: notification.Metadata,
+	}
+}
+
+func transformDeliveryToMongo(delivery *models.Delivery) *Delivery {
+	if delivery == nil {
+		return nil
+	}
+
+	return &Delivery{
+		Decision: string(delivery.Decision),
+		Reason:   delivery.Reason,
+		Channels: delivery.Channels,
+	}
+}
+
+func transformDeliveryToDomain(delivery *Delivery) *models.Delivery {
+	if delivery == nil {
+		return nil
+	}
+
+	return &models.Delivery{
+		Decision: models.DeliveryDecision(delivery.Decision),
+		Reason:   delivery.Reason,
+		Channels: delivery.Channels,
 	}
 }
 
-func (s *Storage) GetAllNotificationsByTime(ctx context.Context, serviceName string, filter models.LastTime) ([]*models.Notification, error) {
-	// todo
-	// start span
+func (s *Storage) GetAllNotificationsByTime(ctx context.Context, serviceName string, filter models.LastTime) (_ []*models.Notification, err error) {
+	ctx, end := instrument(ctx, "GetAllNotificationsByTime")
+	defer end(&err)
 
 	finalMinutes := calculateFinalMinutes(filter)
 
@@ -195,18 +473,20 @@ func calculateFinalMinutes(filter models.LastTime) time.Duration {
 func assembleGetNotificationsByTimeAggr(serviceName string, targetTimeAgo time.Time) mongo.Pipeline {
 	// match service name and from targetTime ago
 	matchService := bson.D{{
-		Key: "$match" , Value : bson.M{
-			"service" : serviceName,
-			"sentAt" : bson.M {
-				"$gte" : targetTimeAgo,
+		Key: "$match", Value: bson.M{
+			"service": serviceName,
+			"sentAt": bson.M{
+				"$gte": targetTimeAgo,
 			},
+			"status":     visibleStatusFilter(),
+			"archivedAt": notArchivedFilter(),
 		},
 	}}
 
 	// sort for most recent
 	sortBySentAt := bson.D{{
 		Key: "$sort", Value: bson.M{
-			"sentAt" : -1,
+			"sentAt": -1,
 		},
 	}}
 
@@ -220,26 +500,204 @@ func assembleGetNotificationsByTimeAggr(serviceName string, targetTimeAgo time.T
 func transformNotificationsToDomain(notifications []Notification) []*models.Notification {
 	final := make([]*models.Notification, 0)
 
-	for _, n := range notifications {
-		final = append(final, &models.Notification{
-			ID: n.ID,
-			Service: n.Service,
-			Message: n.Message,
-			IsRead: n.IsRead,
-			SentAt: n.SentAt,
-			ReadAt: n.ReadAt,
-		})
+	for i := range notifications {
+		final = append(final, transformNotificationToDomain(&notifications[i]))
 	}
 
 	return final
 }
 
-func (s *Storage) GetNonReadNotifications(ctx context.Context, serviceName string) ([]*models.Notification, error) {
-	// todo
-	return nil, nil
+func transformNotificationToDomain(n *Notification) *models.Notification {
+	return &models.Notification{
+		ID:        n.ID,
+		Service:   n.Service,
+		Recipient: n.Recipient,
+		Title:     n.Title,
+		Message:   n.Message,
+		IsRead:    n.IsRead,
+		SentAt:    n.SentAt,
+		ReadAt:    n.ReadAt,
+		Category:  n.Category,
+		Priority:  models.Priority(n.Priority),
+		Status:    notificationStatus(n.Status),
+		DeliverAt: n.DeliverAt,
+
+		DigestRule: n.DigestRule,
+		DigestID:   n.DigestID,
+		DigestOf:   n.DigestOf,
+		Delivery:   transformDeliveryToDomain(n.Delivery),
+
+		CollapseKey: n.CollapseKey,
+		Count:       max(n.Count, 1),
+
+		Metadata: n.Metadata,
+
+		ArchivedAt: n.ArchivedAt,
+	}
+}
+
+// visibleStatusFilter matches notifications the recipient can see: scheduled and digest ones wait
+// until they are delivered or summarized
+func visibleStatusFilter() bson.M {
+	return bson.M{
+		"$nin": bson.A{
+			string(models.StatusPending),
+			string(models.StatusDigestPending),
+			string(models.StatusDigested),
+			string(models.StatusSuppressed),
+		},
+	}
+}
+
+// notArchivedFilter matches the notifications the recipient did not archive
+func notArchivedFilter() bson.M {
+	return bson.M{"$exists": false}
+}
+
+// addSentAtRange restricts the query to the notifications sent in [since, until). zero times are unbounded
+func addSentAtRange(query bson.M, since, until time.Time) {
+	sentAt := bson.M{}
+	if !since.IsZero() {
+		sentAt["$gte"] = since
+	}
+	if !until.IsZero() {
+		sentAt["$lt"] = until
+	}
+	if len(sentAt) > 0 {
+		query["sentAt"] = sentAt
+	}
+}
+
+// notificationStatus maps the stored status. documents stored before scheduling existed have none
+func notificationStatus(status string) models.NotificationStatus {
+	if status == "" {
+		return models.StatusDelivered
+	}
+
+	return models.NotificationStatus(status)
+}
+
+// GetNonReadNotifications returns the visible unread notifications of the service, most recent first
+func (s *Storage) GetNonReadNotifications(ctx context.Context, serviceName string) (_ []*models.Notification, err error) {
+	ctx, end := instrument(ctx, "GetNonReadNotifications")
+	defer end(&err)
+
+	filter := bson.M{
+		"service":    serviceName,
+		"isRead":     false,
+		"status":     visibleStatusFilter(),
+		"archivedAt": notArchivedFilter(),
+	}
+
+	return s.findNotifications(ctx, filter, options.Find().SetSort(bson.D{{Key: "sentAt", Value: -1}}))
+}
+
+// GetLatestNotifications returns the n most recent visible notifications of the service. n <= 0 returns all
+func (s *Storage) GetLatestNotifications(ctx context.Context, serviceName string, n int) (_ []*models.Notification, err error) {
+	ctx, end := instrument(ctx, "GetLatestNotifications")
+	defer end(&err)
+
+	filter := bson.M{
+		"service":    serviceName,
+		"status":     visibleStatusFilter(),
+		"archivedAt": notArchivedFilter(),
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "sentAt", Value: -1}})
+	if n > 0 {
+		opts.SetLimit(int64(n))
+	}
+
+	return s.findNotifications(ctx, filter, opts)
+}
+
+// ListNotifications returns the visible notifications matching the filter, most recent first
+func (s *Storage) ListNotifications(ctx context.Context, filter models.NotificationFilter) (_ []*models.Notification, err error) {
+	ctx, end := instrument(ctx, "ListNotifications")
+	defer end(&err)
+
+	query := bson.M{
+		"service": filter.Service,
+	}
+
+	if !filter.IncludeHidden {
+		query["status"] = visibleStatusFilter()
+	}
+
+	if !filter.IncludeArchived {
+		query["archivedAt"] = notArchivedFilter()
+	}
+
+	if filter.Recipient != "" {
+		query["recipient"] = filter.Recipient
+	}
+
+	if filter.UnreadOnly {
+		query["isRead"] = false
+	}
+
+	if filter.Search != "" {
+		pattern := bson.Regex{Pattern: regexp.QuoteMeta(filter.Search), Options: "i"}
+		query["$or"] = bson.A{bson.M{"title": pattern}, bson.M{"message": pattern}}
+	}
+
+	addSentAtRange(query, filter.Since, filter.Until)
+
+	opts := options.Find().SetSort(bson.D{{Key: "sentAt", Value: -1}, {Key: "_id", Value: -1}})
+	if filter.Limit > 0 {
+		opts.SetLimit(int64(filter.Limit))
+	}
+	if filter.Offset > 0 {
+		opts.SetSkip(int64(filter.Offset))
+	}
+
+	return s.findNotifications(ctx, query, opts)
+}
+
+func (s *Storage) findNotifications(ctx context.Context, filter bson.M, opts ...options.Lister[options.FindOptions]) ([]*models.Notification, error) {
+	ctxTimeout, cancel := context.WithTimeout(ctx, time.Second*10)
+	defer cancel()
+
+	cursor, err := s.notificationCollection.Find(ctxTimeout, filter, opts...)
+	if err != nil {
+		log.L(ctx).Error("could not find notifications", zap.Any("filter", filter), zap.Error(err))
+		return nil, fmt.Errorf("could not find notifications: %w", err)
+	}
+
+	var results []Notification
+	if err := cursor.All(ctxTimeout, &results); err != nil {
+		log.L(ctx).Error("cursor iteration failed", zap.Error(err))
+		return nil, err
+	}
+
+	return transformNotificationsToDomain(results), nil
 }
 
-func (s *Storage) GetLatestNotifications(ctx context.Context, serviceName string, n int) ([]*models.Notification, error) {
-	// todo
-	return nil, nil
+// instrument starts a span for a storage operation. the returned func must be deferred with the named error of
+// the operation: it ends the span and records the operation metrics
+func instrument(ctx context.Context, operation string) (context.Context, func(*error)) {
+	start := time.Now()
+	ctx = log.WithComponent(ctx, log.ComponentStorage)
+
+	ctx, span := tracer.Start(ctx, "mongo."+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameMongoDB,
+			semconv.DBOperationName(operation),
+		))
+
+	return ctx, func(err *error) {
+		result := *err
+		if errors.Is(result, domain.ErrNotFound) {
+			result = nil // missing documents are an expected outcome
+		}
+
+		if result != nil {
+			span.RecordError(result)
+			span.SetStatus(codes.Error, result.Error())
+		}
+
+		span.End()
+		metrics.R().ObserveStorage(operation, time.Since(start), result)
+	}
 }
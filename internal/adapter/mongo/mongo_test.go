@@ -0,0 +1,46 @@
+package mongo
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain/port"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain/port/porttest"
+)
+
+// MONGO_TEST_URI points the tests to a disposable mongodb. every test gets its own database, dropped at the end
+const testURIEnv = "MONGO_TEST_URI"
+
+func TestStorage(t *testing.T) {
+	uri := os.Getenv(testURIEnv)
+	if uri == "" {
+		t.Skipf("%s not set", testURIEnv)
+	}
+
+	porttest.TestStorage(t, func(t *testing.T) port.Storage {
+		ctx := context.Background()
+		db := "porttest_" + strings.ReplaceAll(uuid.NewString(), "-", "")
+
+		storage, err := NewStorage(ctx, uri, db, Collections{
+			Notifications: "notifications",
+			Templates:     "templates",
+			Preferences:   "preferences",
+		})
+		if err != nil {
+			t.Fatalf("NewStorage: %v", err)
+		}
+
+		t.Cleanup(func() {
+			if err := storage.client.Database(db).Drop(ctx); err != nil {
+				t.Errorf("could not drop %s: %v", db, err)
+			}
+
+			storage.Close(ctx)
+		})
+
+		return &storage
+	})
+}
@@ -0,0 +1,114 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain"
+	log "github.com/joseCarlosAndrade/notification-server/internal/core/domain/logger"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"go.uber.org/zap"
+)
+
+func (s *Storage) GetPreferences(ctx context.Context, recipient string) (_ *models.Preferences, err error) {
+	ctx, end := instrument(ctx, "GetPreferences")
+	defer end(&err)
+
+	var doc Preferences
+
+	err = s.preferenceCollection.FindOne(ctx, bson.M{"_id": recipient}).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, domain.ErrNotFound
+	}
+
+	if err != nil {
+		log.L(ctx).Error("could not find preferences", zap.String("recipient", recipient), zap.Error(err))
+		return nil, fmt.Errorf("could not find preferences: %w", err)
+	}
+
+	return transformPreferencesToDomain(&doc), nil
+}
+
+func (s *Storage) SavePreferences(ctx context.Context, preferences *models.Preferences) (err error) {
+	ctx, end := instrument(ctx, "SavePreferences")
+	defer end(&err)
+
+	doc := transformPreferencesToMongo(preferences)
+
+	_, err = s.preferenceCollection.ReplaceOne(ctx, bson.M{"_id": doc.Recipient}, doc, options.Replace().SetUpsert(true))
+	if err != nil {
+		log.L(ctx).Error("could not save preferences", zap.String("recipient", doc.Recipient), zap.Error(err))
+		return fmt.Errorf("could not save preferences: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Storage) DeletePreferences(ctx context.Context, recipient string) (err error) {
+	ctx, end := instrument(ctx, "DeletePreferences")
+	defer end(&err)
+
+	res, err := s.preferenceCollection.DeleteOne(ctx, bson.M{"_id": recipient})
+	if err != nil {
+		log.L(ctx).Error("could not delete preferences", zap.String("recipient", recipient), zap.Error(err))
+		return fmt.Errorf("could not delete preferences: %w", err)
+	}
+
+	if res.DeletedCount == 0 {
+		return domain.ErrNotFound
+	}
+
+	return nil
+}
+
+func transformPreferencesToMongo(p *models.Preferences) *Preferences {
+	doc := &Preferences{
+		Recipient:       p.Recipient,
+		Locale:          p.Locale,
+		TimeZone:        p.TimeZone,
+		MutedServices:   p.MutedServices,
+		MutedCategories: p.MutedCategories,
+		UpdatedAt:       p.UpdatedAt,
+	}
+
+	if p.QuietHours != nil {
+		doc.QuietHours = &QuietHours{Start: p.QuietHours.Start, End: p.QuietHours.End}
+	}
+
+	if len(p.Channels) > 0 {
+		doc.Channels = make(map[string][]string, len(p.Channels))
+		for priority, channels := range p.Channels {
+			doc.Channels[string(priority)] = channels
+		}
+	}
+
+	return doc
+}
+
+func transformPreferencesToDomain(doc *Preferences) *models.Preferences {
+	p := &models.Preferences{
+		Recipient:       doc.Recipient,
+		Locale:          doc.Locale,
+		TimeZone:        doc.TimeZone,
+		MutedServices:   doc.MutedServices,
+		MutedCategories: doc.MutedCategories,
+		UpdatedAt:       doc.UpdatedAt,
+	}
+
+	if doc.QuietHours != nil {
+		p.QuietHours = &models.QuietHours{Start: doc.QuietHours.Start, End: doc.QuietHours.End}
+	}
+
+	if len(doc.Channels) > 0 {
+		p.Channels = make(map[models.Priority][]string, len(doc.Channels))
+		for priority, channels := range doc.Channels {
+			p.Channels[models.Priority(priority)] = channels
+		}
+	}
+
+	return p
+}
@@ -0,0 +1,100 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain"
+	log "github.com/joseCarlosAndrade/notification-server/internal/core/domain/logger"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"go.uber.org/zap"
+)
+
+// ClaimDueNotifications leases up to limit pending notifications due at now to owner. each claim is a single
+// findOneAndUpdate, so two replicas never hold the same lease. expired leases (a replica died) are claimed again
+func (s *Storage) ClaimDueNotifications(ctx context.Context, owner string, now time.Time, lease time.Duration, limit int) (_ []*models.Notification, err error) {
+	ctx, end := instrument(ctx, "ClaimDueNotifications")
+	defer end(&err)
+
+	filter := bson.M{
+		"status":    string(models.StatusPending),
+		"deliverAt": bson.M{"$lte": now},
+		"$or": bson.A{
+			bson.M{"leaseUntil": bson.M{"$exists": false}},
+			bson.M{"leaseUntil": nil},
+			bson.M{"leaseUntil": bson.M{"$lt": now}},
+		},
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"leaseOwner": owner,
+			"leaseUntil": now.Add(lease),
+		},
+	}
+
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "deliverAt", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	claimed := make([]*models.Notification, 0)
+
+	for len(claimed) < limit {
+		var doc Notification
+
+		err := s.notificationCollection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&doc)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			break
+		}
+
+		if err != nil {
+			log.L(ctx).Error("could not claim pending notification", zap.String("owner", owner), zap.Error(err))
+			return claimed, fmt.Errorf("could not claim pending notification: %w", err)
+		}
+
+		claimed = append(claimed, transformNotificationToDomain(&doc))
+	}
+
+	return claimed, nil
+}
+
+// PromoteNotification delivers a pending notification leased by owner. the filter on status and owner makes the
+// promotion happen exactly once: if the lease expired and was taken by another replica, domain.ErrConflict is returned
+func (s *Storage) PromoteNotification(ctx context.Context, notificationID, owner string, deliveredAt time.Time) (err error) {
+	ctx, end := instrument(ctx, "PromoteNotification")
+	defer end(&err)
+
+	filter := bson.M{
+		"_id":        notificationID,
+		"status":     string(models.StatusPending),
+		"leaseOwner": owner,
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"status": string(models.StatusDelivered),
+			"sentAt": deliveredAt, // the notification shows up in the inbox at delivery time
+		},
+		"$unset": bson.M{
+			"leaseOwner": "",
+			"leaseUntil": "",
+		},
+	}
+
+	res, err := s.notificationCollection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		log.L(ctx).Error("could not promote notification", zap.String("id", notificationID), zap.Error(err))
+		return fmt.Errorf("could not promote notification: %w", err)
+	}
+
+	if res.MatchedCount == 0 {
+		return fmt.Errorf("lease on %s lost: %w", notificationID, domain.ErrConflict)
+	}
+
+	return nil
+}
@@ -0,0 +1,137 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain"
+	log "github.com/joseCarlosAndrade/notification-server/internal/core/domain/logger"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"go.uber.org/zap"
+)
+
+func (s *Storage) StoreTemplate(ctx context.Context, template *models.Template) (err error) {
+	ctx, end := instrument(ctx, "StoreTemplate")
+	defer end(&err)
+
+	doc := transformTemplateToMongo(template)
+
+	// plain insert: the unique index makes two replicas racing for the same version fail instead of overwriting
+	_, err = s.templateCollection.InsertOne(ctx, doc)
+	if mongo.IsDuplicateKeyError(err) {
+		return fmt.Errorf("template %s version %d: %w", doc.ID, doc.Version, domain.ErrConflict)
+	}
+
+	if err != nil {
+		log.L(ctx).Error("could not insert template", zap.String("id", doc.ID), zap.Error(err))
+		return fmt.Errorf("failed to insert template in mongodb: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Storage) GetLatestTemplate(ctx context.Context, templateID, locale string) (_ *models.Template, err error) {
+	ctx, end := instrument(ctx, "GetLatestTemplate")
+	defer end(&err)
+
+	filter := bson.M{
+		"templateId": templateID,
+		"locale":     locale,
+	}
+
+	opts := options.FindOne().SetSort(bson.D{{Key: "version", Value: -1}})
+
+	return s.findOneTemplate(ctx, filter, opts)
+}
+
+func (s *Storage) GetTemplateVersion(ctx context.Context, templateID, locale string, version int) (_ *models.Template, err error) {
+	ctx, end := instrument(ctx, "GetTemplateVersion")
+	defer end(&err)
+
+	filter := bson.M{
+		"templateId": templateID,
+		"locale":     locale,
+		"version":    version,
+	}
+
+	return s.findOneTemplate(ctx, filter)
+}
+
+func (s *Storage) findOneTemplate(ctx context.Context, filter bson.M, opts ...options.Lister[options.FindOneOptions]) (*models.Template, error) {
+	var doc Template
+
+	err := s.templateCollection.FindOne(ctx, filter, opts...).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, domain.ErrNotFound
+	}
+
+	if err != nil {
+		log.L(ctx).Error("could not find template", zap.Any("filter", filter), zap.Error(err))
+		return nil, fmt.Errorf("could not find template: %w", err)
+	}
+
+	return transformTemplateToDomain(&doc), nil
+}
+
+func (s *Storage) ListTemplateVersions(ctx context.Context, templateID string) (_ []*models.Template, err error) {
+	ctx, end := instrument(ctx, "ListTemplateVersions")
+	defer end(&err)
+
+	ctxTimeout, cancel := context.WithTimeout(ctx, time.Second*10)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "locale", Value: 1}, {Key: "version", Value: -1}})
+
+	cursor, err := s.templateCollection.Find(ctxTimeout, bson.M{"templateId": templateID}, opts)
+	if err != nil {
+		log.L(ctx).Error("could not list templates", zap.String("templateId", templateID), zap.Error(err))
+		return nil, fmt.Errorf("could not list templates: %w", err)
+	}
+
+	var docs []Template
+	if err := cursor.All(ctxTimeout, &docs); err != nil {
+		log.L(ctx).Error("cursor iteration failed", zap.Error(err))
+		return nil, err
+	}
+
+	if len(docs) == 0 {
+		return nil, domain.ErrNotFound
+	}
+
+	final := make([]*models.Template, 0, len(docs))
+	for i := range docs {
+		final = append(final, transformTemplateToDomain(&docs[i]))
+	}
+
+	return final, nil
+}
+
+func transformTemplateToMongo(t *models.Template) *Template {
+	return &Template{
+		ID:         fmt.Sprintf("%s:%s:%d", t.ID, t.Locale, t.Version),
+		TemplateID: t.ID,
+		Locale:     t.Locale,
+		Version:    t.Version,
+		Title:      t.Title,
+		Body:       t.Body,
+		Example:    t.Example,
+		CreatedAt:  t.CreatedAt,
+	}
+}
+
+func transformTemplateToDomain(t *Template) *models.Template {
+	return &models.Template{
+		ID:        t.TemplateID,
+		Locale:    t.Locale,
+		Version:   t.Version,
+		Title:     t.Title,
+		Body:      t.Body,
+		Example:   t.Example,
+		CreatedAt: t.CreatedAt,
+	}
+}
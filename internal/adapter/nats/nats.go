@@ -0,0 +1,218 @@
+package nats
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	log "github.com/joseCarlosAndrade/notification-server/internal/core/domain/logger"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain/port"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/eventsource"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"go.uber.org/zap"
+)
+
+// EventsHub implements the EventsHub interface consuming a NATS JetStream durable consumer. messages are
+// acked one by one, so the ones not acked on shutdown are delivered again after the ack wait
+type EventsHub struct {
+	processor *eventsource.Processor
+	conn      *nats.Conn
+	js        jetstream.JetStream
+	consumer  jetstream.Consumer
+
+	deadLetterSubject string // empty when dead lettering is disabled
+
+	drainTimeout time.Duration
+	run          *eventsource.RunState // lets Close wait for Run to drain, if it was started
+}
+
+// makes sure EventsHub implements the interface
+var _ port.EventsHub = (*EventsHub)(nil)
+
+// NewEventsHub connects and creates (or updates) the durable consumer of subject on stream. the stream must exist
+func NewEventsHub(ctx context.Context, processor *eventsource.Processor, url, stream, subject, durable, deadLetterSubject string,
+	drainTimeout time.Duration) (EventsHub, error) {
+	conn, err := nats.Connect(url, nats.Name("notification-server"))
+	if err != nil {
+		return EventsHub{}, fmt.Errorf("could not connect to nats: %w", err)
+	}
+
+	js, err := jetstream.New(conn)
+	if err != nil {
+		conn.Close()
+		return EventsHub{}, fmt.Errorf("could not create jetstream context: %w", err)
+	}
+
+	consumer, err := js.CreateOrUpdateConsumer(ctx, stream, jetstream.ConsumerConfig{
+		Durable:       durable,
+		FilterSubject: subject,
+		AckPolicy:     jetstream.AckExplicitPolicy,
+		DeliverPolicy: jetstream.DeliverAllPolicy,
+	})
+	if err != nil {
+		conn.Close()
+		return EventsHub{}, fmt.Errorf("could not create consumer %s on stream %s: %w", durable, stream, err)
+	}
+
+	log.L(ctx).Info("nats started and connected",
+		zap.String("url", conn.ConnectedUrlRedacted()),
+		zap.String("stream", stream),
+		zap.String("subject", subject),
+		zap.String("consumer", durable))
+
+	return EventsHub{
+		processor:         processor,
+		conn:              conn,
+		js:                js,
+		consumer:          consumer,
+		deadLetterSubject: deadLetterSubject,
+		drainTimeout:      drainTimeout,
+		run:               eventsource.NewRunState(),
+	}, nil
+}
+
+// Run implements port.Runner interface. once ctx is canceled it stops pulling and drains the messages
+// already pulled within the drain timeout
+func (e *EventsHub) Run(ctx context.Context) error {
+	if !e.run.Start() {
+		return nil
+	}
+	defer e.run.Stop()
+
+	ctx = log.WithComponent(ctx, log.ComponentEventsHub)
+
+	processCtx, release := eventsource.DrainContext(ctx, e.drainTimeout)
+	defer release()
+
+	messages, err := e.consumer.Messages()
+	if err != nil {
+		return fmt.Errorf("could not pull messages: %w", err)
+	}
+	defer messages.Stop()
+
+	stopPulling := context.AfterFunc(ctx, messages.Drain)
+	defer stopPulling()
+
+	for {
+		msg, err := messages.Next()
+		if errors.Is(err, jetstream.ErrMsgIteratorClosed) {
+			log.L(ctx).Warn("context canceled. pulled messages drained")
+			return nil
+		}
+
+		if err != nil {
+			return fmt.Errorf("could not get next message: %w", err)
+		}
+
+		// drain timeout exceeded. the messages left are not acked and will be delivered again
+		if processCtx.Err() != nil {
+			log.L(ctx).Warn("drain timeout exceeded. leaving messages unacked")
+			return nil
+		}
+
+		if err := e.processor.Process(processCtx, toMessage(msg), e.deadLetterFunc()); err != nil {
+			if err := msg.Nak(); err != nil {
+				log.L(ctx).Error("could not nak message", zap.Error(err))
+			}
+
+			continue
+		}
+
+		if err := msg.Ack(); err != nil {
+			log.L(ctx).Error("could not ack message", zap.Error(err))
+		}
+	}
+}
+
+// Close implements port.Runner interface. it waits for Run to drain the pulled messages before closing the
+// connection, unless ctx expires first. it does not wait if Run was never started
+func (e *EventsHub) Close(ctx context.Context) error {
+	var err error
+
+	if !e.run.Wait(ctx) {
+		err = fmt.Errorf("could not wait for the messages to drain: %w", ctx.Err())
+	}
+
+	e.conn.Close()
+
+	return err
+}
+
+func (e *EventsHub) IsHealthy(ctx context.Context) error {
+	if status := e.conn.Status(); status != nats.CONNECTED {
+		return fmt.Errorf("nats connection is %s", status)
+	}
+
+	return nil
+}
+
+// Ping checks that the server is reachable and that the stream exists, without consuming. used by the tools
+func Ping(ctx context.Context, url, stream string) error {
+	conn, err := nats.Connect(url, nats.Name("notification-server"))
+	if err != nil {
+		return fmt.Errorf("could not connect to nats: %w", err)
+	}
+	defer conn.Close()
+
+	js, err := jetstream.New(conn)
+	if err != nil {
+		return fmt.Errorf("could not create jetstream context: %w", err)
+	}
+
+	if _, err := js.Stream(ctx, stream); err != nil {
+		return fmt.Errorf("could not get stream %s: %w", stream, err)
+	}
+
+	return nil
+}
+
+// toMessage translates the jetstream message for the processor
+func toMessage(msg jetstream.Msg) *eventsource.Message {
+	message := &eventsource.Message{
+		System: eventsource.SystemNATS,
+		Source: msg.Subject(),
+		Value:  msg.Data(),
+	}
+
+	// the stream sequence identifies the message for good
+	if metadata, err := msg.Metadata(); err == nil {
+		message.Position = strconv.FormatUint(metadata.Sequence.Stream, 10)
+		message.Replayable = true
+	}
+
+	for key, values := range msg.Headers() {
+		for _, value := range values {
+			message.Headers = append(message.Headers, eventsource.Header{Key: key, Value: []byte(value)})
+		}
+	}
+
+	return message
+}
+
+// deadLetterFunc is nil when dead lettering is disabled
+func (e *EventsHub) deadLetterFunc() eventsource.DeadLetterFunc {
+	if e.deadLetterSubject == "" {
+		return nil
+	}
+
+	return e.deadLetter
+}
+
+// deadLetter publishes the message to the dead letter subject with the failure reason in the headers
+func (e *EventsHub) deadLetter(ctx context.Context, message *eventsource.Message, reason error) error {
+	msg := nats.NewMsg(e.deadLetterSubject)
+	msg.Data = message.Value
+
+	for _, h := range eventsource.DeadLetterHeaders(message, reason) {
+		msg.Header.Add(h.Key, string(h.Value))
+	}
+
+	if _, err := e.js.PublishMsg(ctx, msg); err != nil {
+		return fmt.Errorf("could not publish message to dead letter subject %s: %w", e.deadLetterSubject, err)
+	}
+
+	return nil
+}
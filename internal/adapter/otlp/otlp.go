@@ -0,0 +1,63 @@
+package otlp
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/joseCarlosAndrade/notification-server/internal/core/config"
+	log "github.com/joseCarlosAndrade/notification-server/internal/core/domain/logger"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.uber.org/zap"
+)
+
+// Shutdown flushes the pending spans and stops the exporter
+type Shutdown func(context.Context) error
+
+// InitTracing registers the global W3C trace context propagator and, if endpoint is set, a tracer provider
+// exporting spans over OTLP/gRPC to it (host:port), using tls unless insecure is set. without an endpoint spans
+// are not recorded, but incoming trace contexts are still propagated
+func InitTracing(ctx context.Context, endpoint string, insecure bool) (Shutdown, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	if endpoint == "" {
+		log.L(ctx).Info("otel exporter endpoint not set. spans will not be exported")
+		return func(context.Context) error { return nil }, nil
+	}
+
+	options := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(endpoint)}
+	if insecure {
+		options = append(options, otlptracegrpc.WithInsecure())
+	}
+
+	exporter, err := otlptracegrpc.New(ctx, options...)
+	if err != nil {
+		return nil, fmt.Errorf("could not create otlp exporter: %w", err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(config.AppTraceName),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("could not create otel resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+
+	otel.SetTracerProvider(provider)
+
+	log.L(ctx).Info("exporting traces", zap.String("endpoint", endpoint), zap.Bool("insecure", insecure))
+
+	return provider.Shutdown, nil
+}
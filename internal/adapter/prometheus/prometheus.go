@@ -0,0 +1,161 @@
+package prometheus
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "notification_server"
+
+// Recorder implements metrics.Recorder exporting in the prometheus text format
+type Recorder struct {
+	registry *prometheus.Registry
+
+	recordsConsumed   *prometheus.CounterVec
+	recordsFailed     *prometheus.CounterVec
+	processDuration   *prometheus.HistogramVec
+	consumerLag       *prometheus.GaugeVec
+	storageDuration   *prometheus.HistogramVec
+	storageErrors     *prometheus.CounterVec
+	httpDuration      *prometheus.HistogramVec
+	grpcDuration      *prometheus.HistogramVec
+	dependencyHealthy *prometheus.GaugeVec
+	throttled         *prometheus.CounterVec
+}
+
+// makes sure Recorder implements the interface
+var _ metrics.Recorder = (*Recorder)(nil)
+
+func NewRecorder() *Recorder {
+	r := &Recorder{
+		registry: prometheus.NewRegistry(),
+
+		recordsConsumed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "records_consumed_total",
+			Help:      "Records consumed per topic and partition.",
+		}, []string{"topic", "partition"}),
+		recordsFailed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "records_failed_total",
+			Help:      "Records that failed processing per topic and partition.",
+		}, []string{"topic", "partition"}),
+		processDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "record_process_duration_seconds",
+			Help:      "Time spent processing a consumed record.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"topic"}),
+		consumerLag: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "consumer_lag_records",
+			Help:      "Records between the last consumed offset and the high watermark per partition.",
+		}, []string{"topic", "partition"}),
+		storageDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "storage_operation_duration_seconds",
+			Help:      "Latency of storage operations.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"operation"}),
+		storageErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "storage_operation_errors_total",
+			Help:      "Failed storage operations.",
+		}, []string{"operation"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Latency of api requests by route and status.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "status"}),
+		grpcDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "grpc_request_duration_seconds",
+			Help:      "Latency of grpc calls by method and status code.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "code"}),
+		dependencyHealthy: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "dependency_healthy",
+			Help:      "Last health probe result per dependency (1 healthy, 0 unhealthy).",
+		}, []string{"dependency"}),
+		throttled: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "notifications_throttled_total",
+			Help:      "Notifications exceeding the rate limit per service and applied policy.",
+		}, []string{"service", "policy"}),
+	}
+
+	r.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		r.recordsConsumed,
+		r.recordsFailed,
+		r.processDuration,
+		r.consumerLag,
+		r.storageDuration,
+		r.storageErrors,
+		r.httpDuration,
+		r.grpcDuration,
+		r.dependencyHealthy,
+		r.throttled,
+	)
+
+	return r
+}
+
+// Handler serves the registry in the prometheus text format
+func (r *Recorder) Handler() http.Handler {
+	return promhttp.HandlerFor(r.registry, promhttp.HandlerOpts{Registry: r.registry})
+}
+
+func (r *Recorder) RecordConsumed(topic string, partition int32, err error) {
+	p := strconv.Itoa(int(partition))
+
+	r.recordsConsumed.WithLabelValues(topic, p).Inc()
+	if err != nil {
+		r.recordsFailed.WithLabelValues(topic, p).Inc()
+	}
+}
+
+func (r *Recorder) ObserveProcessing(topic string, duration time.Duration) {
+	r.processDuration.WithLabelValues(topic).Observe(duration.Seconds())
+}
+
+func (r *Recorder) SetConsumerLag(topic string, partition int32, lag int64) {
+	r.consumerLag.WithLabelValues(topic, strconv.Itoa(int(partition))).Set(float64(lag))
+}
+
+func (r *Recorder) ObserveStorage(operation string, duration time.Duration, err error) {
+	r.storageDuration.WithLabelValues(operation).Observe(duration.Seconds())
+	if err != nil {
+		r.storageErrors.WithLabelValues(operation).Inc()
+	}
+}
+
+func (r *Recorder) ObserveHTTPRequest(route string, status int, duration time.Duration) {
+	r.httpDuration.WithLabelValues(route, strconv.Itoa(status)).Observe(duration.Seconds())
+}
+
+func (r *Recorder) ObserveGRPCRequest(method, code string, duration time.Duration) {
+	r.grpcDuration.WithLabelValues(method, code).Observe(duration.Seconds())
+}
+
+func (r *Recorder) SetHealth(dependency string, healthy bool) {
+	value := 0.0
+	if healthy {
+		value = 1
+	}
+
+	r.dependencyHealthy.WithLabelValues(dependency).Set(value)
+}
+
+func (r *Recorder) RecordThrottled(service, policy string) {
+	r.throttled.WithLabelValues(service, policy).Inc()
+}
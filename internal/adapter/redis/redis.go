@@ -2,20 +2,46 @@ package redis
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"time"
 
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain"
+	log "github.com/joseCarlosAndrade/notification-server/internal/core/domain/logger"
 	"github.com/joseCarlosAndrade/notification-server/internal/core/domain/port"
+	goredis "github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
 )
 
 // Cache implements the port.Cache interface
 type Cache struct {
-
+	client *goredis.Client
 }
 
 // makes sure Cache implements the interface
 var _ port.Cache = (*Cache)(nil)
 
-func NewCache(ctx context.Context) Cache {
-	return Cache{}
+// NewCache creates the redis client. redis being unreachable is not an error: the cache is not critical
+// and the service bypasses it while the health probes report it down
+func NewCache(ctx context.Context, addr, password string, db int) Cache {
+	client := goredis.NewClient(&goredis.Options{
+		Addr:     addr,
+		Password: password,
+		DB:       db,
+	})
+
+	ctxTimeout, cancel := context.WithTimeout(ctx, time.Second*2)
+	defer cancel()
+
+	if err := client.Ping(ctxTimeout).Err(); err != nil {
+		log.L(ctx).Warn("redis not reachable. running without cache until it recovers",
+			zap.String("addr", addr),
+			zap.Error(err))
+	} else {
+		log.L(ctx).Info("successfully connected to redis", zap.String("addr", addr))
+	}
+
+	return Cache{client: client}
 }
 
 // Run implements port.Runner interface
@@ -25,11 +51,38 @@ func (s *Cache) Run(ctx context.Context) error {
 
 // Close implements port.Runner interface
 func (s *Cache) Close(ctx context.Context) error {
-	// todo: close connection and clean up
+	return s.client.Close()
+}
+
+func (s *Cache) IsHealthy(ctx context.Context) error {
+	return s.client.Ping(ctx).Err()
+}
+
+func (s *Cache) Get(ctx context.Context, key string) ([]byte, error) {
+	value, err := s.client.Get(ctx, key).Bytes()
+	if errors.Is(err, goredis.Nil) {
+		return nil, domain.ErrNotFound
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("could not get %s from redis: %w", key, err)
+	}
+
+	return value, nil
+}
+
+func (s *Cache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if err := s.client.Set(ctx, key, value, ttl).Err(); err != nil {
+		return fmt.Errorf("could not set %s in redis: %w", key, err)
+	}
 
 	return nil
 }
 
-func (s *Cache) IsHealthy(ctx context.Context) error {
+func (s *Cache) Delete(ctx context.Context, key string) error {
+	if err := s.client.Del(ctx, key).Err(); err != nil {
+		return fmt.Errorf("could not delete %s from redis: %w", key, err)
+	}
+
 	return nil
-}
\ No newline at end of file
+}
@@ -2,36 +2,59 @@ package redpanda
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
+	"slices"
+	"strconv"
 	"time"
 
-	"github.com/joseCarlosAndrade/notification-server/internal/core/domain"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/config"
 	log "github.com/joseCarlosAndrade/notification-server/internal/core/domain/logger"
-	"github.com/joseCarlosAndrade/notification-server/internal/core/domain/models"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain/metrics"
 	"github.com/joseCarlosAndrade/notification-server/internal/core/domain/port"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/eventsource"
+	"github.com/twmb/franz-go/pkg/kadm"
 	"github.com/twmb/franz-go/pkg/kgo"
 	"go.uber.org/zap"
 )
 
-// EventsHub implements the EventsHub interface
+// topicsCheckInterval is how often the mapped topics are compared with the ones consumed
+const topicsCheckInterval = 5 * time.Second
+
+// records that could not be stored nor dead lettered are retried starting at retryBackoff, doubled up to
+// maxRetryBackoff
+const (
+	retryBackoff    = time.Second
+	maxRetryBackoff = time.Minute
+)
+
+// EventsHub implements the EventsHub interface. besides the notification topic, it consumes every topic of
+// config.TopicMappings, following the hot reloads of the mappings
 type EventsHub struct {
-	service       *port.Service
+	processor     *eventsource.Processor
 	client        *kgo.Client
-	topic         string
+	topic         string          // notification records. may be empty when only mapped topics are consumed
+	topics        map[string]bool // every topic consumed. only used by Run
 	consumerGroup string
+
+	deadLetterTopic string // empty when dead lettering is disabled
+
+	drainTimeout time.Duration         // how long the records already fetched may take to be processed on shutdown
+	run          *eventsource.RunState // lets Close wait for Run to drain, if it was started
 }
 
 // makes sure EventsHub implements the interface
 var _ port.EventsHub = (*EventsHub)(nil)
 
-func NewEventsHub(ctx context.Context, serviceRepository *port.Service, brokers []string, topic, group string) (EventsHub, error) {
-	
+func NewEventsHub(ctx context.Context, processor *eventsource.Processor, brokers []string, topic, group, deadLetterTopic string,
+	drainTimeout time.Duration) (EventsHub, error) {
+	topics := consumedTopics(topic, config.Live().TopicMappings)
+
 	client, err := kgo.NewClient(
 		kgo.SeedBrokers(brokers...),
 		kgo.ConsumerGroup(group),
-		kgo.ConsumeTopics(topic),
+		kgo.ConsumeTopics(topics...),
 		kgo.ConsumeResetOffset(kgo.NewOffset().AtStart()),
+		kgo.AutoCommitMarks(), // only offsets of processed records are committed
 	)
 
 	if err != nil {
@@ -40,117 +63,294 @@ func NewEventsHub(ctx context.Context, serviceRepository *port.Service, brokers
 
 	log.L(ctx).Info("redpanda started and connected",
 		zap.String("brokers", brokers[0]),
-		zap.String("topic", topic),
+		zap.Strings("topics", topics),
 		zap.String("group", group))
 
+	consumed := make(map[string]bool, len(topics))
+	for _, t := range topics {
+		consumed[t] = true
+	}
+
 	return EventsHub{
-		client: client,
-		topic: topic,
-		consumerGroup: group,
-		service: serviceRepository,
+		client:          client,
+		topic:           topic,
+		topics:          consumed,
+		consumerGroup:   group,
+		processor:       processor,
+		deadLetterTopic: deadLetterTopic,
+		drainTimeout:    drainTimeout,
+		run:             eventsource.NewRunState(),
 	}, nil
 }
 
-// Run implements port.Runner interface
+// Run implements port.Runner interface. records are processed with a context that is not canceled with ctx,
+// so a shutdown never aborts a write halfway: once ctx is canceled it stops fetching, drains the records
+// already fetched within the drain timeout and commits their offsets
 func (e *EventsHub) Run(ctx context.Context) error {
+	if !e.run.Start() {
+		return nil
+	}
+	defer e.run.Stop()
+
+	ctx = log.WithComponent(ctx, log.ComponentEventsHub)
+
+	processCtx, release := eventsource.DrainContext(ctx, e.drainTimeout)
+	defer release()
+
 	for {
 		// avoid fetching with canceled context
 		select {
 		case <-ctx.Done():
-			log.L(ctx).Warn("context canceled")
+			log.L(ctx).Warn("context canceled. committing processed records")
+			e.commit(context.WithoutCancel(ctx))
+
 			return nil
 		default:
 		}
 		// log.L(ctx).Info("polling")
 
-		fetches := e.client.PollFetches(ctx)
+		e.syncTopics(ctx)
+
+		// polls are bounded so topics mapped by a reload are consumed even while nothing else is produced
+		pollCtx, cancelPoll := context.WithTimeout(ctx, topicsCheckInterval)
+		fetches := e.client.PollFetches(pollCtx)
+		cancelPoll()
+		e.recordLag(fetches)
+
 		iter := fetches.RecordIter()
 		for !iter.Done() {
 			record := iter.Next()
 
-			err := e.processRecord(ctx, record)
-			if err != nil {
-				log.L(ctx).Error("error processing record", zap.Error(err))
+			// drain timeout exceeded. the records left are not committed and will be consumed again
+			if processCtx.Err() != nil {
+				log.L(ctx).Warn("drain timeout exceeded. leaving records uncommitted",
+					zap.String("topic", record.Topic),
+					zap.Int32("partition", record.Partition),
+					zap.Int64("offset", record.Offset))
 
-				// TODO: handle this error: maybe dead letter?
+				break
 			}
+
+			// marking a later record would commit past this one, so the rest of the fetch is left uncommitted too
+			if !e.process(ctx, processCtx, record) {
+				log.L(ctx).Warn("stopping with a failed record. leaving records uncommitted",
+					zap.String("topic", record.Topic),
+					zap.Int32("partition", record.Partition),
+					zap.Int64("offset", record.Offset))
+
+				break
+			}
+
+			e.client.MarkCommitRecords(record)
 		}
 	}
 }
 
-// Close implements port.Runner interface
-func (e *EventsHub) Close(ctx context.Context) error {
-	// todo: close connection and clean up
-	e.client.Close()
-	return nil
+// process hands the record to the processor until it is stored or dead lettered. an error means neither
+// happened and offsets are committed per partition, so instead of skipping the record it is retried with backoff,
+// blocking the ones after it. returns false if the hub stopped before the record was processed
+func (e *EventsHub) process(ctx, processCtx context.Context, record *kgo.Record) bool {
+	backoff := retryBackoff
+
+	for {
+		err := e.processor.Process(processCtx, toMessage(record), e.deadLetterFunc())
+		if err == nil {
+			return true
+		}
+
+		if processCtx.Err() != nil {
+			return false
+		}
+
+		log.L(ctx).Error("could not process record. retrying",
+			zap.String("topic", record.Topic),
+			zap.Int32("partition", record.Partition),
+			zap.Int64("offset", record.Offset),
+			zap.Duration("backoff", backoff),
+			zap.Error(err))
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(backoff):
+		}
+
+		backoff = min(backoff*2, maxRetryBackoff)
+	}
 }
 
-// proccesRecord starts a trace_id for this context and process the record to store this entry in
-// database and in cache
-func (e *EventsHub) processRecord(ctx context.Context, record *kgo.Record) error {
-	// handle panic
-	defer func() {
-		if r := recover(); r != nil {
-			log.L(ctx).Error("PANIC DETECTED. recovering", zap.Any("recover", r))
+// syncTopics starts consuming the topics mapped since the last check and stops consuming the ones unmapped
+func (e *EventsHub) syncTopics(ctx context.Context) {
+	wanted := consumedTopics(e.topic, config.Live().TopicMappings)
+
+	added := make([]string, 0)
+	for _, topic := range wanted {
+		if !e.topics[topic] {
+			added = append(added, topic)
 		}
-	}()
+	}
 
-	// loads a new trace_id into the context
-	ctx = log.InitResources(ctx)
+	removed := make([]string, 0)
+	for topic := range e.topics {
+		if !slices.Contains(wanted, topic) {
+			removed = append(removed, topic)
+		}
+	}
 
-	log.L(ctx).Debug("processing record", 
-		zap.String("key", string(record.Key)), 
-		zap.String("value", string(record.Value)))
-	
-		// NEXT STEPS: VALIDATE THIS AND THE restart: unless-stopped
-	notification, err := validatePayload(record.Key, record.Value)
-	if err != nil {
-		return fmt.Errorf("could not process record: %w", err)
+	if len(added) > 0 {
+		e.client.AddConsumeTopics(added...)
+		log.L(ctx).Info("consuming mapped topics", zap.Strings("topics", added))
 	}
 
-	// save in cache
-	// save in db
+	if len(removed) > 0 {
+		e.client.PurgeTopicsFromConsuming(removed...)
+		log.L(ctx).Info("stopped consuming unmapped topics", zap.Strings("topics", removed))
+	}
 
-	err = (*e.service).SaveNewNotification(ctx, notification)
-	if err != nil {
-		log.L(ctx).Error("could not save new notification", zap.Error(err))
-		return fmt.Errorf("error saving notification: %w", err)
+	for _, topic := range added {
+		e.topics[topic] = true
 	}
 
-	return nil
+	for _, topic := range removed {
+		delete(e.topics, topic)
+	}
 }
 
-func validatePayload(_ []byte, value []byte) (*models.NotificationRecord, error) {
+// consumedTopics returns the notification topic, if any, and the mapped topics
+func consumedTopics(topic string, mappings config.TopicMappings) []string {
+	topics := mappings.Topics()
+	if topic != "" {
+		topics = append(topics, topic)
+	}
 
-	var payload models.NotificationRecord
+	slices.Sort(topics)
 
-	if err := json.Unmarshal(value, &payload); err != nil {
-		return nil, fmt.Errorf("could not parse payload: %w", err)
+	return topics
+}
+
+// deadLetterFunc is nil when dead lettering is disabled
+func (e *EventsHub) deadLetterFunc() eventsource.DeadLetterFunc {
+	if e.deadLetterTopic == "" {
+		return nil
 	}
 
-	// if not passed, use now from UTC
-	if payload.SentAt == nil {
-		now := domain.NewNowTime()
-		payload.SentAt = &now
-	} else {
-		// ensuring the timestamp is utc
-		if payload.SentAt.Location() != time.UTC {
-			*payload.SentAt = payload.SentAt.UTC()
+	return e.deadLetter
+}
+
+// commit commits the offsets of every processed record
+func (e *EventsHub) commit(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, time.Second*5)
+	defer cancel()
+
+	if err := e.client.CommitMarkedOffsets(ctx); err != nil {
+		log.L(ctx).Error("could not commit offsets", zap.Error(err))
+		return
+	}
+
+	log.L(ctx).Info("offsets committed")
+}
+
+// recordLag sets the lag of every fetched partition from its high watermark and the last fetched offset
+func (e *EventsHub) recordLag(fetches kgo.Fetches) {
+	fetches.EachPartition(func(p kgo.FetchTopicPartition) {
+		if len(p.Records) == 0 {
+			return
 		}
+
+		last := p.Records[len(p.Records)-1]
+		metrics.R().SetConsumerLag(p.Topic, p.Partition, max(p.HighWatermark-last.Offset-1, 0))
+	})
+}
+
+// Close implements port.Runner interface. it waits for Run to drain the fetched records before closing the
+// client, unless ctx expires first. it does not wait if Run was never started
+func (e *EventsHub) Close(ctx context.Context) error {
+	var err error
+
+	if !e.run.Wait(ctx) {
+		err = fmt.Errorf("could not wait for the records to drain: %w", ctx.Err())
 	}
 
-	return &payload, nil
+	e.client.Close()
+
+	return err
 }
 
+// toMessage translates the record for the processor
+func toMessage(record *kgo.Record) *eventsource.Message {
+	headers := make([]eventsource.Header, 0, len(record.Headers))
+	for _, h := range record.Headers {
+		headers = append(headers, eventsource.Header{Key: h.Key, Value: h.Value})
+	}
 
-func (e *EventsHub)IsHealthy(ctx context.Context) error {
+	return &eventsource.Message{
+		System:     eventsource.SystemKafka,
+		Source:     record.Topic,
+		Partition:  record.Partition,
+		Position:   strconv.FormatInt(record.Offset, 10),
+		Replayable: true,
+		Key:        record.Key,
+		Value:      record.Value,
+		Headers:    headers,
+	}
+}
+
+// deadLetter produces the record to the dead letter topic with the failure reason in the headers
+func (e *EventsHub) deadLetter(ctx context.Context, message *eventsource.Message, reason error) error {
+	headers := eventsource.DeadLetterHeaders(message, reason)
+
+	dlqRecord := &kgo.Record{
+		Topic:   e.deadLetterTopic,
+		Key:     message.Key,
+		Value:   message.Value,
+		Headers: make([]kgo.RecordHeader, 0, len(headers)),
+	}
+
+	for _, h := range headers {
+		dlqRecord.Headers = append(dlqRecord.Headers, kgo.RecordHeader{Key: h.Key, Value: h.Value})
+	}
+
+	if err := e.client.ProduceSync(ctx, dlqRecord).FirstErr(); err != nil {
+		return fmt.Errorf("could not produce record to dead letter topic %s: %w", e.deadLetterTopic, err)
+	}
+
+	return nil
+}
+
+func (e *EventsHub) IsHealthy(ctx context.Context) error {
 	return e.client.Ping(ctx)
 }
 
+// Ping checks that the brokers are reachable and that the topics exist, without consuming. used by the tools
+func Ping(ctx context.Context, brokers []string, topics ...string) error {
+	client, err := kgo.NewClient(kgo.SeedBrokers(brokers...))
+	if err != nil {
+		return fmt.Errorf("could not create kafka client: %w", err)
+	}
+
+	admin := kadm.NewClient(client)
+	defer admin.Close()
+
+	if err := client.Ping(ctx); err != nil {
+		return fmt.Errorf("could not reach brokers: %w", err)
+	}
+
+	if len(topics) == 0 {
+		return nil
+	}
+
+	details, err := admin.ListTopics(ctx, topics...)
+	if err != nil {
+		return fmt.Errorf("could not list topics: %w", err)
+	}
+
+	return details.Error()
+}
+
 /*
 {
     "service" : "payments",
     "message" : "new order generated",
     "sentAt" : "2026-02-04T21:34:32Z"
 }
-*/
\ No newline at end of file
+*/
@@ -0,0 +1,287 @@
+package redpanda
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	log "github.com/joseCarlosAndrade/notification-server/internal/core/domain/logger"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/eventsource"
+	"github.com/twmb/franz-go/pkg/kadm"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"go.uber.org/zap"
+)
+
+// replayIdleTimeout ends a replay when no record arrives for this long. offsets taken by transaction markers
+// are never fetched, so the last offsets of a partition may never show up
+const replayIdleTimeout = 10 * time.Second
+
+// ReplayRange selects the records of a topic to replay. every bound is optional: the range starts at the first
+// record of each partition and ends at the high watermark read when the replay starts
+type ReplayRange struct {
+	Topic      string
+	Partitions []int32 // empty replays every partition
+
+	FromOffset int64     // first offset of each partition. negative if unset
+	FromTime   time.Time // first record produced at or after it
+	ToOffset   int64     // the offset the replay stops at, exclusive. negative if unset
+	ToTime     time.Time // the replay stops at the first record produced at or after it
+
+	DryRun        bool          // only decode and validate the records
+	ProgressEvery time.Duration // how often progress is reported. 0 only reports at the end
+}
+
+// ReplayStats counts the records of a replay
+type ReplayStats struct {
+	Total     int64 // records in the range
+	Read      int64
+	Processed int64 // stored, or valid on dry runs
+	Failed    int64 // could not be stored, or invalid on dry runs
+}
+
+// Replayer consumes a range of a topic outside the consumer group and runs the records through the processor,
+// so the committed offsets of the server are not touched. the notification ids are derived from the record
+// positions, so replaying stored records overwrites them instead of duplicating
+type Replayer struct {
+	processor *eventsource.Processor
+	brokers   []string
+	admin     *kadm.Client
+}
+
+func NewReplayer(ctx context.Context, processor *eventsource.Processor, brokers []string) (*Replayer, error) {
+	client, err := kgo.NewClient(kgo.SeedBrokers(brokers...))
+	if err != nil {
+		return nil, fmt.Errorf("could not create kafka client: %w", err)
+	}
+
+	return &Replayer{
+		processor: processor,
+		brokers:   brokers,
+		admin:     kadm.NewClient(client),
+	}, nil
+}
+
+func (r *Replayer) Close() {
+	r.admin.Close()
+}
+
+// Replay processes every record of the range. records that fail are logged and counted instead of dead
+// lettered, so replaying the dead letter topic doesn't feed it. progress, if not nil, gets the stats every
+// ProgressEvery
+func (r *Replayer) Replay(ctx context.Context, rng ReplayRange, progress func(ReplayStats)) (ReplayStats, error) {
+	stats := ReplayStats{}
+
+	starts, ends, err := r.bounds(ctx, rng)
+	if err != nil {
+		return stats, err
+	}
+
+	partitions := make(map[int32]kgo.Offset, len(starts))
+	for partition, start := range starts {
+		if start < ends[partition] {
+			partitions[partition] = kgo.NewOffset().At(start)
+			stats.Total += ends[partition] - start
+		}
+	}
+
+	log.L(ctx).Info("replaying topic",
+		zap.String("topic", rng.Topic),
+		zap.Any("from", starts),
+		zap.Any("to", ends),
+		zap.Int64("records", stats.Total),
+		zap.Bool("dryRun", rng.DryRun))
+
+	if len(partitions) == 0 {
+		return stats, nil
+	}
+
+	client, err := kgo.NewClient(
+		kgo.SeedBrokers(r.brokers...),
+		kgo.ConsumePartitions(map[string]map[int32]kgo.Offset{rng.Topic: partitions}),
+	)
+	if err != nil {
+		return stats, fmt.Errorf("could not create kafka client: %w", err)
+	}
+	defer client.Close()
+
+	lastProgress := time.Now()
+
+	for len(partitions) > 0 {
+		pollCtx, cancelPoll := context.WithTimeout(ctx, replayIdleTimeout)
+		fetches := client.PollFetches(pollCtx)
+		cancelPoll()
+
+		if ctx.Err() != nil {
+			return stats, ctx.Err()
+		}
+
+		for _, fetchErr := range fetches.Errors() {
+			if !errors.Is(fetchErr.Err, context.DeadlineExceeded) {
+				return stats, fmt.Errorf("could not fetch partition %d: %w", fetchErr.Partition, fetchErr.Err)
+			}
+		}
+
+		if fetches.Empty() {
+			log.L(ctx).Warn("no records for a while. ending replay",
+				zap.Int32s("partitions", slices.Sorted(maps.Keys(partitions))))
+			break
+		}
+
+		iter := fetches.RecordIter()
+		for !iter.Done() {
+			record := iter.Next()
+
+			if _, ok := partitions[record.Partition]; !ok || record.Offset >= ends[record.Partition] {
+				continue
+			}
+
+			stats.Read++
+			if err := r.replay(ctx, record, rng.DryRun); err != nil {
+				if ctx.Err() != nil {
+					return stats, ctx.Err()
+				}
+
+				stats.Failed++
+			} else {
+				stats.Processed++
+			}
+
+			if record.Offset+1 >= ends[record.Partition] {
+				delete(partitions, record.Partition)
+			}
+
+			if progress != nil && rng.ProgressEvery > 0 && time.Since(lastProgress) >= rng.ProgressEvery {
+				progress(stats)
+				lastProgress = time.Now()
+			}
+		}
+
+		// consumed partitions are not fetched anymore
+		for partition := range starts {
+			if _, ok := partitions[partition]; !ok {
+				client.PauseFetchPartitions(map[string][]int32{rng.Topic: {partition}})
+			}
+		}
+	}
+
+	if progress != nil {
+		progress(stats)
+	}
+
+	return stats, nil
+}
+
+// replay processes the record, or only checks it on dry runs. the error is the reason it failed
+func (r *Replayer) replay(ctx context.Context, record *kgo.Record, dryRun bool) error {
+	message := toMessage(record)
+
+	logger := log.L(ctx).With(zap.Int32("partition", record.Partition), zap.Int64("offset", record.Offset))
+
+	if dryRun {
+		if err := r.processor.Check(ctx, message); err != nil {
+			logger.Warn("invalid record", zap.Error(err))
+			return err
+		}
+
+		return nil
+	}
+
+	var failure error
+	deadLetter := func(ctx context.Context, message *eventsource.Message, reason error) error {
+		failure = reason
+		return nil
+	}
+
+	if err := r.processor.Process(ctx, message, deadLetter); err != nil {
+		return err
+	}
+
+	if failure != nil {
+		logger.Warn("could not replay record", zap.Error(failure))
+	}
+
+	return failure
+}
+
+// bounds returns the first offset to replay and the offset to stop at of each partition in the range
+func (r *Replayer) bounds(ctx context.Context, rng ReplayRange) (map[int32]int64, map[int32]int64, error) {
+	var (
+		listed kadm.ListedOffsets
+		err    error
+	)
+
+	if rng.FromTime.IsZero() {
+		listed, err = r.admin.ListStartOffsets(ctx, rng.Topic)
+	} else {
+		listed, err = r.admin.ListOffsetsAfterMilli(ctx, rng.FromTime.UnixMilli(), rng.Topic)
+	}
+
+	if err == nil {
+		err = listed.Error()
+	}
+
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not list start offsets of %s: %w", rng.Topic, err)
+	}
+
+	endListed, err := r.admin.ListEndOffsets(ctx, rng.Topic)
+	if err == nil {
+		err = endListed.Error()
+	}
+
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not list end offsets of %s: %w", rng.Topic, err)
+	}
+
+	var toListed kadm.ListedOffsets
+	if !rng.ToTime.IsZero() {
+		toListed, err = r.admin.ListOffsetsAfterMilli(ctx, rng.ToTime.UnixMilli(), rng.Topic)
+		if err == nil {
+			err = toListed.Error()
+		}
+
+		if err != nil {
+			return nil, nil, fmt.Errorf("could not list offsets of %s at %s: %w", rng.Topic, rng.ToTime, err)
+		}
+	}
+
+	if len(endListed[rng.Topic]) == 0 {
+		return nil, nil, fmt.Errorf("topic %s not found", rng.Topic)
+	}
+
+	starts := make(map[int32]int64)
+	ends := make(map[int32]int64)
+
+	for partition, end := range endListed[rng.Topic] {
+		if len(rng.Partitions) > 0 && !slices.Contains(rng.Partitions, partition) {
+			continue
+		}
+
+		stop := end.Offset
+		if to, ok := toListed.Lookup(rng.Topic, partition); ok && to.Offset >= 0 {
+			stop = min(stop, to.Offset)
+		}
+
+		if rng.ToOffset >= 0 {
+			stop = min(stop, rng.ToOffset)
+		}
+
+		// no record was produced after FromTime when the offset is not found
+		start := stop
+		if from, ok := listed.Lookup(rng.Topic, partition); ok && from.Offset >= 0 {
+			start = from.Offset
+		}
+
+		if rng.FromOffset >= 0 {
+			start = max(start, rng.FromOffset)
+		}
+
+		starts[partition] = start
+		ends[partition] = stop
+	}
+
+	return starts, ends, nil
+}
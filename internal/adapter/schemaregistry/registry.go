@@ -0,0 +1,174 @@
+package schemaregistry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain"
+	log "github.com/joseCarlosAndrade/notification-server/internal/core/domain/logger"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain/models"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain/port"
+	"go.uber.org/zap"
+)
+
+// Registry implements port.SchemaRegistry over the Confluent schema registry http api, which Redpanda
+// implements too. schemas are cached forever, since they never change once registered
+type Registry struct {
+	url      string
+	username string // basic auth. empty disables it
+	password string
+	client   *http.Client
+
+	mu        sync.RWMutex
+	byID      map[int]*models.Schema
+	byVersion map[string]*models.Schema // keyed by subject/version
+}
+
+// makes sure Registry implements the interface
+var _ port.SchemaRegistry = (*Registry)(nil)
+
+func NewRegistry(ctx context.Context, registryURL, username, password string, timeout time.Duration) *Registry {
+	log.L(ctx).Info("schema registry client created", zap.String("url", registryURL))
+
+	return &Registry{
+		url:       strings.TrimSuffix(registryURL, "/"),
+		username:  username,
+		password:  password,
+		client:    &http.Client{Timeout: timeout},
+		byID:      make(map[int]*models.Schema),
+		byVersion: make(map[string]*models.Schema),
+	}
+}
+
+// schemaResponse is the body of both /schemas/ids/{id} and /subjects/{subject}/versions/{version}
+type schemaResponse struct {
+	Schema     string `json:"schema"`
+	SchemaType string `json:"schemaType"`
+	ID         int    `json:"id"`
+	References []struct {
+		Name    string `json:"name"`
+		Subject string `json:"subject"`
+		Version int    `json:"version"`
+	} `json:"references"`
+}
+
+func (r *Registry) SchemaByID(ctx context.Context, id int) (*models.Schema, error) {
+	r.mu.RLock()
+	schema, ok := r.byID[id]
+	r.mu.RUnlock()
+
+	if ok {
+		return schema, nil
+	}
+
+	var resp schemaResponse
+	if err := r.get(ctx, "/schemas/ids/"+strconv.Itoa(id), &resp); err != nil {
+		return nil, fmt.Errorf("could not get schema %d: %w", id, err)
+	}
+
+	resp.ID = id
+	schema = toSchema(resp)
+
+	r.mu.Lock()
+	r.byID[id] = schema
+	r.mu.Unlock()
+
+	return schema, nil
+}
+
+func (r *Registry) SchemaByVersion(ctx context.Context, subject string, version int) (*models.Schema, error) {
+	key := subject + "/" + strconv.Itoa(version)
+
+	r.mu.RLock()
+	schema, ok := r.byVersion[key]
+	r.mu.RUnlock()
+
+	if ok {
+		return schema, nil
+	}
+
+	var resp schemaResponse
+	if err := r.get(ctx, "/subjects/"+url.PathEscape(subject)+"/versions/"+strconv.Itoa(version), &resp); err != nil {
+		return nil, fmt.Errorf("could not get version %d of subject %s: %w", version, subject, err)
+	}
+
+	schema = toSchema(resp)
+
+	r.mu.Lock()
+	r.byVersion[key] = schema
+	r.byID[schema.ID] = schema
+	r.mu.Unlock()
+
+	return schema, nil
+}
+
+func (r *Registry) IsHealthy(ctx context.Context) error {
+	var subjects []string
+
+	return r.get(ctx, "/subjects", &subjects)
+}
+
+// get decodes the json response of path into out. a 404 returns domain.ErrNotFound
+func (r *Registry) get(ctx context.Context, path string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.url+path, nil)
+	if err != nil {
+		return fmt.Errorf("could not create request: %w", err)
+	}
+
+	req.Header.Set("Accept", "application/vnd.schemaregistry.v1+json, application/json")
+
+	if r.username != "" {
+		req.SetBasicAuth(r.username, r.password)
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("could not reach schema registry: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return domain.ErrNotFound
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("schema registry returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("could not decode schema registry response: %w", err)
+	}
+
+	return nil
+}
+
+func toSchema(resp schemaResponse) *models.Schema {
+	schema := &models.Schema{
+		ID:   resp.ID,
+		Type: models.SchemaType(resp.SchemaType),
+		Text: resp.Schema,
+	}
+
+	if schema.Type == "" {
+		schema.Type = models.SchemaAvro
+	}
+
+	for _, ref := range resp.References {
+		schema.References = append(schema.References, models.SchemaReference{
+			Name:    ref.Name,
+			Subject: ref.Subject,
+			Version: ref.Version,
+		})
+	}
+
+	return schema
+}
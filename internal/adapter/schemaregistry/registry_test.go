@@ -0,0 +1,208 @@
+package schemaregistry
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/joseCarlosAndrade/notification-server/internal/adapter/schemaregistry/registrytest"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain/models"
+)
+
+const avroSchema = `{"type":"record","name":"Notification","fields":[{"name":"service","type":"string"}]}`
+
+func TestSchemaByID(t *testing.T) {
+	tests := []struct {
+		name     string
+		schema   registrytest.Schema
+		wantType models.SchemaType
+	}{
+		{"AvroByDefault", registrytest.Schema{Schema: avroSchema}, models.SchemaAvro},
+		{"Protobuf", registrytest.Schema{Schema: `syntax = "proto3";`, SchemaType: "PROTOBUF"}, models.SchemaProtobuf},
+		{"JSON", registrytest.Schema{Schema: `{"type":"object"}`, SchemaType: "JSON"}, models.SchemaJSON},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := registrytest.NewServer(t)
+			server.Register(7, "subject", 1, tt.schema)
+
+			registry := NewRegistry(context.Background(), server.URL+"/", "", "", time.Second)
+
+			schema, err := registry.SchemaByID(context.Background(), 7)
+			if err != nil {
+				t.Fatalf("SchemaByID: %v", err)
+			}
+
+			if schema.ID != 7 || schema.Type != tt.wantType || schema.Text != tt.schema.Schema {
+				t.Errorf("SchemaByID = %+v, want id 7, type %s and the registered text", schema, tt.wantType)
+			}
+		})
+	}
+}
+
+func TestSchemasAreCached(t *testing.T) {
+	ctx := context.Background()
+
+	server := registrytest.NewServer(t)
+	server.Register(1, "subject", 3, registrytest.Schema{Schema: avroSchema})
+
+	registry := NewRegistry(ctx, server.URL, "", "", time.Second)
+
+	for range 3 {
+		if _, err := registry.SchemaByID(ctx, 1); err != nil {
+			t.Fatalf("SchemaByID: %v", err)
+		}
+	}
+
+	if got := server.Requests(); got != 1 {
+		t.Errorf("registry got %d requests for the same id, want 1", got)
+	}
+
+	for range 3 {
+		if _, err := registry.SchemaByVersion(ctx, "subject", 3); err != nil {
+			t.Fatalf("SchemaByVersion: %v", err)
+		}
+	}
+
+	if got := server.Requests(); got != 2 {
+		t.Errorf("registry got %d requests, want 2: one per id and one per version", got)
+	}
+}
+
+func TestSchemaByVersion(t *testing.T) {
+	ctx := context.Background()
+
+	server := registrytest.NewServer(t)
+	server.Register(4, "with/slash", 2, registrytest.Schema{
+		Schema:     avroSchema,
+		References: []registrytest.Reference{{Name: "other.Type", Subject: "other", Version: 1}},
+	})
+
+	registry := NewRegistry(ctx, server.URL, "", "", time.Second)
+
+	schema, err := registry.SchemaByVersion(ctx, "with/slash", 2)
+	if err != nil {
+		t.Fatalf("SchemaByVersion: %v", err)
+	}
+
+	want := models.SchemaReference{Name: "other.Type", Subject: "other", Version: 1}
+	if schema.ID != 4 || len(schema.References) != 1 || schema.References[0] != want {
+		t.Fatalf("SchemaByVersion = %+v, want id 4 with reference %+v", schema, want)
+	}
+
+	// the version also fills the id cache
+	if _, err := registry.SchemaByID(ctx, 4); err != nil {
+		t.Fatalf("SchemaByID: %v", err)
+	}
+
+	if got := server.Requests(); got != 1 {
+		t.Errorf("registry got %d requests, want 1", got)
+	}
+}
+
+func TestRegistryErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		setup    func(s *registrytest.Server)
+		username string
+		password string
+		timeout  time.Duration
+		notFound bool
+	}{
+		{
+			name:     "UnknownID",
+			setup:    func(s *registrytest.Server) {},
+			notFound: true,
+		},
+		{
+			name:  "ServerError",
+			setup: func(s *registrytest.Server) { s.Status = http.StatusInternalServerError },
+		},
+		{
+			name:    "Timeout",
+			setup:   func(s *registrytest.Server) { s.Delay = time.Second },
+			timeout: 50 * time.Millisecond,
+		},
+		{
+			name:  "MissingCredentials",
+			setup: func(s *registrytest.Server) { s.Username, s.Password = "user", "secret" },
+		},
+		{
+			name:     "WrongCredentials",
+			setup:    func(s *registrytest.Server) { s.Username, s.Password = "user", "secret" },
+			username: "user",
+			password: "wrong",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			server := registrytest.NewServer(t)
+			tt.setup(server)
+
+			timeout := tt.timeout
+			if timeout == 0 {
+				timeout = time.Second
+			}
+
+			registry := NewRegistry(ctx, server.URL, tt.username, tt.password, timeout)
+
+			_, err := registry.SchemaByID(ctx, 1)
+			if err == nil {
+				t.Fatal("SchemaByID succeeded, want an error")
+			}
+
+			if got := errors.Is(err, domain.ErrNotFound); got != tt.notFound {
+				t.Errorf("SchemaByID = %v, domain.ErrNotFound %v, want %v", err, got, tt.notFound)
+			}
+
+			if err := registry.IsHealthy(ctx); err == nil && !tt.notFound {
+				t.Errorf("IsHealthy succeeded, want an error")
+			}
+		})
+	}
+}
+
+func TestBasicAuth(t *testing.T) {
+	ctx := context.Background()
+
+	server := registrytest.NewServer(t)
+	server.Username, server.Password = "user", "secret"
+	server.Register(1, "subject", 1, registrytest.Schema{Schema: avroSchema})
+
+	registry := NewRegistry(ctx, server.URL, "user", "secret", time.Second)
+
+	if _, err := registry.SchemaByID(ctx, 1); err != nil {
+		t.Fatalf("SchemaByID: %v", err)
+	}
+
+	if err := registry.IsHealthy(ctx); err != nil {
+		t.Errorf("IsHealthy: %v", err)
+	}
+}
+
+func TestErrorsAreNotCached(t *testing.T) {
+	ctx := context.Background()
+
+	server := registrytest.NewServer(t)
+	server.Status = http.StatusServiceUnavailable
+
+	registry := NewRegistry(ctx, server.URL, "", "", time.Second)
+
+	if _, err := registry.SchemaByID(ctx, 1); err == nil {
+		t.Fatal("SchemaByID succeeded while the registry is down")
+	}
+
+	server.Status = 0
+	server.Register(1, "subject", 1, registrytest.Schema{Schema: avroSchema})
+
+	if _, err := registry.SchemaByID(ctx, 1); err != nil {
+		t.Errorf("SchemaByID after the registry is back: %v", err)
+	}
+}
@@ -0,0 +1,165 @@
+// Package registrytest implements a fake schema registry over httptest, serving the endpoints the schema
+// registry client uses:
+//
+//	server := registrytest.NewServer(t)
+//	server.Register(1, "subject", 1, registrytest.Schema{Schema: avroSchema})
+//	registry := schemaregistry.NewRegistry(ctx, server.URL, "", "", time.Second)
+package registrytest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Schema is the body the registry returns for a schema
+type Schema struct {
+	Schema     string      `json:"schema"`
+	SchemaType string      `json:"schemaType,omitempty"` // empty is avro, as the real registry does
+	ID         int         `json:"id,omitempty"`
+	References []Reference `json:"references,omitempty"`
+}
+
+type Reference struct {
+	Name    string `json:"name"`
+	Subject string `json:"subject"`
+	Version int    `json:"version"`
+}
+
+// Server is a fake registry. the fields can be changed between calls
+type Server struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	byID      map[int]Schema
+	byVersion map[string]Schema // keyed by subject/version
+	subjects  map[string]bool
+
+	Username string // basic auth required when set
+	Password string
+	Delay    time.Duration // wait before answering, to test timeouts
+	Status   int           // answer every call with this status when set
+
+	requests atomic.Int64
+}
+
+// NewServer starts an empty registry, closed when the test ends
+func NewServer(t *testing.T) *Server {
+	s := &Server{
+		byID:      make(map[int]Schema),
+		byVersion: make(map[string]Schema),
+		subjects:  make(map[string]bool),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /schemas/ids/{id}", s.schemaByID)
+	mux.HandleFunc("GET /subjects/{subject}/versions/{version}", s.schemaByVersion)
+	mux.HandleFunc("GET /subjects", s.listSubjects)
+
+	s.Server = httptest.NewServer(s.middleware(mux))
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+// Register stores the schema under id and as the version of the subject
+func (s *Server) Register(id int, subject string, version int, schema Schema) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	schema.ID = id
+	s.byID[id] = schema
+	s.byVersion[subject+"/"+strconv.Itoa(version)] = schema
+	s.subjects[subject] = true
+}
+
+// Requests returns how many calls the registry answered
+func (s *Server) Requests() int64 {
+	return s.requests.Load()
+}
+
+func (s *Server) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.requests.Add(1)
+
+		if s.Delay > 0 {
+			select {
+			case <-time.After(s.Delay):
+			case <-r.Context().Done():
+				return
+			}
+		}
+
+		if s.Status != 0 {
+			writeError(w, s.Status, "forced error")
+			return
+		}
+
+		if s.Username != "" {
+			username, password, ok := r.BasicAuth()
+			if !ok || username != s.Username || password != s.Password {
+				writeError(w, http.StatusUnauthorized, "unauthorized")
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) schemaByID(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(r.PathValue("id"))
+
+	s.mu.Lock()
+	schema, ok := s.byID[id]
+	s.mu.Unlock()
+
+	if !ok {
+		writeError(w, http.StatusNotFound, "schema not found")
+		return
+	}
+
+	// the real registry does not send the id back on this endpoint
+	schema.ID = 0
+	writeJSON(w, schema)
+}
+
+func (s *Server) schemaByVersion(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	schema, ok := s.byVersion[r.PathValue("subject")+"/"+r.PathValue("version")]
+	s.mu.Unlock()
+
+	if !ok {
+		writeError(w, http.StatusNotFound, "version not found")
+		return
+	}
+
+	writeJSON(w, schema)
+}
+
+func (s *Server) listSubjects(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	subjects := make([]string, 0, len(s.subjects))
+	for subject := range s.subjects {
+		subjects = append(subjects, subject)
+	}
+	s.mu.Unlock()
+
+	writeJSON(w, subjects)
+}
+
+func writeJSON(w http.ResponseWriter, body any) {
+	w.Header().Set("Content-Type", "application/vnd.schemaregistry.v1+json")
+	json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/vnd.schemaregistry.v1+json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]any{"error_code": status, "message": message})
+}
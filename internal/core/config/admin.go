@@ -0,0 +1,20 @@
+package config
+
+import "crypto/subtle"
+
+// AdminTokens are the bearer tokens allowed to use the admin endpoints of the apis, such as deleting a
+// notification for good or changing the log level. decoded by envconfig from a comma separated list
+type AdminTokens []string
+
+// Authenticate reports if token is an admin token. every token is compared in constant time
+func (t AdminTokens) Authenticate(token string) bool {
+	found := false
+
+	for _, expected := range t {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(expected)) == 1 {
+			found = true
+		}
+	}
+
+	return found
+}
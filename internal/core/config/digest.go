@@ -0,0 +1,72 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// DigestRule collapses notifications matching service, category and priority into one summary
+// per recipient every Interval. empty matchers match anything
+type DigestRule struct {
+	Name     string
+	Service  string
+	Category string
+	Priority string
+	Interval time.Duration
+}
+
+// DigestRules is decoded by envconfig from a json array:
+// [{"name":"low-hourly","priority":"low","interval":"1h"}]
+type DigestRules []DigestRule
+
+// Decode implements envconfig.Decoder
+func (r *DigestRules) Decode(value string) error {
+	if value == "" {
+		*r = nil
+		return nil
+	}
+
+	var raw []struct {
+		Name     string `json:"name"`
+		Service  string `json:"service"`
+		Category string `json:"category"`
+		Priority string `json:"priority"`
+		Interval string `json:"interval"`
+	}
+
+	if err := json.Unmarshal([]byte(value), &raw); err != nil {
+		return fmt.Errorf("invalid digest rules: %w", err)
+	}
+
+	rules := make(DigestRules, 0, len(raw))
+	names := make(map[string]bool, len(raw))
+
+	for _, rr := range raw {
+		if rr.Name == "" || names[rr.Name] {
+			return fmt.Errorf("invalid digest rules: rule names must be unique and not empty (%q)", rr.Name)
+		}
+
+		if rr.Service == "" && rr.Category == "" && rr.Priority == "" {
+			return fmt.Errorf("invalid digest rule %s: at least one of service, category or priority is required", rr.Name)
+		}
+
+		interval, err := time.ParseDuration(rr.Interval)
+		if err != nil || interval < time.Minute {
+			return fmt.Errorf("invalid digest rule %s: interval must be a duration of at least 1m", rr.Name)
+		}
+
+		names[rr.Name] = true
+		rules = append(rules, DigestRule{
+			Name:     rr.Name,
+			Service:  rr.Service,
+			Category: rr.Category,
+			Priority: rr.Priority,
+			Interval: interval,
+		})
+	}
+
+	*r = rules
+
+	return nil
+}
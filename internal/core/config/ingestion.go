@@ -0,0 +1,20 @@
+package config
+
+import "crypto/subtle"
+
+// IngestionTokens maps each producing service allowed to send notifications over the apis to its bearer token.
+// decoded by envconfig from service:token pairs
+type IngestionTokens map[string]string
+
+// Authenticate returns the service that owns the token. every token is compared in constant time
+func (t IngestionTokens) Authenticate(token string) (string, bool) {
+	service := ""
+
+	for name, expected := range t {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(expected)) == 1 {
+			service = name
+		}
+	}
+
+	return service, service != ""
+}
@@ -0,0 +1,56 @@
+package config
+
+import (
+	"reflect"
+	"sync/atomic"
+)
+
+// live holds the settings with the hot reloadable fields updated. nil until the first Reload
+var live atomic.Pointer[AppInfo]
+
+// Live returns the current settings. only the hot reloadable fields (log level, rate limits, cache ttl,
+// topic mappings, producer settings and digest rules) can differ from App. the returned value must not be modified
+func Live() *AppInfo {
+	if current := live.Load(); current != nil {
+		return current
+	}
+
+	return &App
+}
+
+// Reload applies the hot reloadable fields of next. it returns the names of the other fields that changed,
+// which only take effect after a restart
+func Reload(next AppInfo) []string {
+	updated := *Live()
+
+	updated.LogLevel = next.LogLevel
+	updated.LogComponentLevels = next.LogComponentLevels
+	updated.RateLimitRate = next.RateLimitRate
+	updated.RateLimitBurst = next.RateLimitBurst
+	updated.RateLimitPolicy = next.RateLimitPolicy
+	updated.RateLimitSampleEvery = next.RateLimitSampleEvery
+	updated.RateLimitOverrides = next.RateLimitOverrides
+	updated.DefaultCacheTTLs = next.DefaultCacheTTLs
+	updated.TopicMappings = next.TopicMappings
+	updated.ProducerServices = next.ProducerServices
+	updated.ProducerKeys = next.ProducerKeys
+	updated.MetadataHeaders = next.MetadataHeaders
+	updated.DigestRules = next.DigestRules
+
+	live.Store(&updated)
+
+	return changedFields(updated, next)
+}
+
+func changedFields(current, next AppInfo) []string {
+	cv, nv := reflect.ValueOf(current), reflect.ValueOf(next)
+	changed := make([]string, 0)
+
+	for i := range cv.NumField() {
+		if !reflect.DeepEqual(cv.Field(i).Interface(), nv.Field(i).Interface()) {
+			changed = append(changed, cv.Type().Field(i).Name)
+		}
+	}
+
+	return changed
+}
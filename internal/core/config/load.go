@@ -0,0 +1,135 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/kelseyhightower/envconfig"
+	"gopkg.in/yaml.v3"
+)
+
+const envPrefix = "app"
+
+// Load reads the settings from env vars and the optional config file (APP_CONFIGFILE) and validates them.
+// file keys are the AppInfo field names in any case (rateLimitRate, RATE_LIMIT_RATE). the file is decoded
+// over the defaults and the env vars are laid over it, so env vars take precedence over the file. the
+// environment is only read, so loads may run concurrently (e.g. hot reloads)
+func Load() (AppInfo, error) {
+	var app AppInfo
+
+	if err := envconfig.Process(envPrefix, &app); err != nil {
+		return app, fmt.Errorf("could not load configuration: %w", err)
+	}
+
+	path := os.Getenv(envName("ConfigFile"))
+	if path != "" {
+		if err := decodeFile(path, &app); err != nil {
+			return app, err
+		}
+	}
+
+	if err := app.Validate(); err != nil {
+		return app, fmt.Errorf("invalid configuration:\n%w", err)
+	}
+
+	return app, nil
+}
+
+// decodeFile sets the fields of app found in the file, except the ones set by an env var
+func decodeFile(path string, app *AppInfo) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("could not read config file: %w", err)
+	}
+
+	// json is valid yaml, so both formats are parsed the same way
+	var raw map[string]yaml.Node
+	if err := yaml.Unmarshal(content, &raw); err != nil {
+		return fmt.Errorf("could not parse config file %s: %w", path, err)
+	}
+
+	fields := fieldsByKey()
+	unknown := make([]string, 0)
+	target := reflect.ValueOf(app).Elem()
+
+	for key, node := range raw {
+		field, ok := fields[normalizeKey(key)]
+		if !ok || field.Name == "ConfigFile" {
+			unknown = append(unknown, key)
+			continue
+		}
+
+		if _, ok := os.LookupEnv(envName(field.Name)); ok || node.Tag == "!!null" {
+			continue
+		}
+
+		if err := decodeValue(&node, target.FieldByIndex(field.Index)); err != nil {
+			return fmt.Errorf("invalid config file value for %s: %w", key, err)
+		}
+	}
+
+	if len(unknown) > 0 {
+		slices.Sort(unknown)
+		return fmt.Errorf("unknown keys in config file %s: %s", path, strings.Join(unknown, ", "))
+	}
+
+	return nil
+}
+
+// decodeValue decodes a file value into field. fields with their own envconfig decoder (like the digest rules)
+// take the same json as their env var, either as a string or as yaml. any other field takes its yaml value
+func decodeValue(node *yaml.Node, field reflect.Value) error {
+	decoder, ok := field.Addr().Interface().(envconfig.Decoder)
+	if !ok {
+		value := reflect.New(field.Type())
+		if err := node.Decode(value.Interface()); err != nil {
+			return err
+		}
+
+		field.Set(value.Elem())
+
+		return nil
+	}
+
+	if node.Kind == yaml.ScalarNode {
+		return decoder.Decode(node.Value)
+	}
+
+	var value any
+	if err := node.Decode(&value); err != nil {
+		return err
+	}
+
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	return decoder.Decode(string(encoded))
+}
+
+// fieldsByKey maps the normalized AppInfo field names to the fields
+func fieldsByKey() map[string]reflect.StructField {
+	t := reflect.TypeFor[AppInfo]()
+	fields := make(map[string]reflect.StructField, t.NumField())
+
+	for i := range t.NumField() {
+		field := t.Field(i)
+		fields[normalizeKey(field.Name)] = field
+	}
+
+	return fields
+}
+
+func normalizeKey(key string) string {
+	return strings.ToLower(strings.NewReplacer("_", "", "-", "").Replace(key))
+}
+
+// envName is the env var envconfig reads for the field
+func envName(field string) string {
+	return strings.ToUpper(envPrefix + "_" + field)
+}
@@ -0,0 +1,73 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestLoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+
+	content := `
+apiPort: "9000"
+RATE_LIMIT_RATE: 5
+schedulerInterval: 2s
+adminTokens: [first, second]
+ingestionTokens: {shop: token}
+producerServices: {checkout: [shop]}
+producerKeys: {checkout: key}
+redpandaBrokers: [file:9092]
+`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("could not write config file: %v", err)
+	}
+
+	t.Setenv("APP_CONFIGFILE", path)
+	t.Setenv("APP_RATELIMITRATE", "7")
+	t.Setenv("APP_NOTIFICATIONTOPIC", "notifications")
+	t.Setenv("APP_KAFKACONSUMERGROUP", "group")
+
+	environ := os.Environ()
+
+	app, err := Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	if app.APIPort != "9000" || app.SchedulerInterval != 2*time.Second {
+		t.Errorf("file values not loaded: port %q, scheduler interval %s", app.APIPort, app.SchedulerInterval)
+	}
+
+	if app.RateLimitRate != 7 {
+		t.Errorf("RateLimitRate = %v, want the env var value 7", app.RateLimitRate)
+	}
+
+	if !slices.Equal(app.AdminTokens, AdminTokens{"first", "second"}) || app.IngestionTokens["shop"] != "token" ||
+		!app.ProducerServices.Allows("checkout", "shop") || app.ProducerKeys["checkout"] != "key" {
+		t.Errorf("file lists and maps not loaded: %+v", app)
+	}
+
+	if app.ShutdownTimeout == 0 {
+		t.Error("defaults of the fields missing from the file were not kept")
+	}
+
+	if !slices.Equal(os.Environ(), environ) {
+		t.Error("Load changed the environment")
+	}
+}
+
+func TestLoadFileUnknownKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("notAField: 1\n"), 0o600); err != nil {
+		t.Fatalf("could not write config file: %v", err)
+	}
+
+	t.Setenv("APP_CONFIGFILE", path)
+
+	if _, err := Load(); err == nil {
+		t.Error("Load accepted an unknown key")
+	}
+}
@@ -0,0 +1,105 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// TopicMapping turns the events of a topic that are not notification records (e.g. "order.created") into
+// notification records. every field is a text/template rendered with the decoded event, so fields can be
+// constants ("shop"), paths ("{{.customer.id}}") or both ("order {{.id}} shipped"). empty fields are left unset
+type TopicMapping struct {
+	Service     string `json:"service"`
+	Recipient   string `json:"recipient,omitempty"`
+	Title       string `json:"title,omitempty"`
+	Message     string `json:"message,omitempty"`
+	Category    string `json:"category,omitempty"`
+	Priority    string `json:"priority,omitempty"`
+	TemplateID  string `json:"templateId,omitempty"`
+	Locale      string `json:"locale,omitempty"`
+	CollapseKey string `json:"collapseKey,omitempty"`
+	SentAt      string `json:"sentAt,omitempty"`    // must render an RFC 3339 timestamp. defaults to now
+	DeliverAt   string `json:"deliverAt,omitempty"` // must render an RFC 3339 timestamp
+
+	// Variables maps the template variables to event field paths (customer.name, items.0.sku). unlike the
+	// fields above, values keep their json type
+	Variables map[string]string `json:"variables,omitempty"`
+}
+
+// Templates returns the fields that are templates, keyed by their record field name
+func (m TopicMapping) Templates() map[string]string {
+	return map[string]string{
+		"service":     m.Service,
+		"recipient":   m.Recipient,
+		"title":       m.Title,
+		"message":     m.Message,
+		"category":    m.Category,
+		"priority":    m.Priority,
+		"templateId":  m.TemplateID,
+		"locale":      m.Locale,
+		"collapseKey": m.CollapseKey,
+		"sentAt":      m.SentAt,
+		"deliverAt":   m.DeliverAt,
+	}
+}
+
+// TopicMappings is decoded by envconfig from a json object keyed by topic:
+// {"orders":{"service":"shop","recipient":"{{.customer.id}}","message":"order {{.id}} created"}}.
+// every topic is consumed besides NotificationTopic. the key is matched with the source of the message, so
+// NATS subjects and AMQP queues can be mapped too
+type TopicMappings map[string]TopicMapping
+
+// Decode implements envconfig.Decoder
+func (t *TopicMappings) Decode(value string) error {
+	if value == "" {
+		*t = nil
+		return nil
+	}
+
+	var mappings map[string]TopicMapping
+	if err := json.Unmarshal([]byte(value), &mappings); err != nil {
+		return fmt.Errorf("invalid topic mappings: %w", err)
+	}
+
+	for topic, mapping := range mappings {
+		if topic == "" {
+			return fmt.Errorf("invalid topic mappings: topics cannot be empty")
+		}
+
+		if mapping.Service == "" {
+			return fmt.Errorf("invalid topic mapping for %s: service is required", topic)
+		}
+
+		if mapping.Message == "" && mapping.TemplateID == "" {
+			return fmt.Errorf("invalid topic mapping for %s: message or templateId is required", topic)
+		}
+
+		for field, text := range mapping.Templates() {
+			if _, err := template.New(field).Parse(text); err != nil {
+				return fmt.Errorf("invalid topic mapping for %s: %s: %w", topic, field, err)
+			}
+		}
+
+		for variable, path := range mapping.Variables {
+			if variable == "" || path == "" || strings.Contains(path, "..") {
+				return fmt.Errorf("invalid topic mapping for %s: invalid variable %q: %q", topic, variable, path)
+			}
+		}
+	}
+
+	*t = mappings
+
+	return nil
+}
+
+// Topics returns the mapped topics
+func (t TopicMappings) Topics() []string {
+	topics := make([]string, 0, len(t))
+	for topic := range t {
+		topics = append(topics, topic)
+	}
+
+	return topics
+}
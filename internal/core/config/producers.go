@@ -0,0 +1,72 @@
+package config
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"slices"
+)
+
+// headers producers identify themselves with. the signature is the hex encoded hmac-sha256 of the record value,
+// keyed with the producer key, so the id can't be written by anyone who doesn't hold the key
+const (
+	ProducerIDHeader        = "producer-id"
+	ProducerSignatureHeader = "producer-signature"
+)
+
+// ProducerServices maps each producer id to the services it may send notifications of, so a producer can't
+// spoof the service of another. decoded by envconfig from a json object: {"checkout":["shop","billing"]}.
+// when set, every record must carry a producer id signed with its ProducerKeys key
+type ProducerServices map[string][]string
+
+// Decode implements envconfig.Decoder
+func (p *ProducerServices) Decode(value string) error {
+	if value == "" {
+		*p = nil
+		return nil
+	}
+
+	var producers map[string][]string
+	if err := json.Unmarshal([]byte(value), &producers); err != nil {
+		return fmt.Errorf("invalid producer services: %w", err)
+	}
+
+	for producer, services := range producers {
+		if producer == "" || len(services) == 0 || slices.Contains(services, "") {
+			return fmt.Errorf("invalid producer services for %q: producers need at least one non empty service", producer)
+		}
+	}
+
+	*p = producers
+
+	return nil
+}
+
+// Allows reports if the producer may send notifications of the service
+func (p ProducerServices) Allows(producer, service string) bool {
+	return slices.Contains(p[producer], service)
+}
+
+// ProducerKeys maps each producer id to the key its records are signed with. decoded by envconfig from
+// producer:key pairs
+type ProducerKeys map[string]string
+
+// Verify reports if signature is the signature of value with the key of the producer. compared in constant time
+func (k ProducerKeys) Verify(producer string, value []byte, signature string) bool {
+	key, ok := k[producer]
+	if !ok {
+		return false
+	}
+
+	expected, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write(value)
+
+	return hmac.Equal(mac.Sum(nil), expected)
+}
@@ -0,0 +1,60 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// rate limit policies applied to notifications exceeding the service limit
+const (
+	RateLimitDrop       = "drop"       // discard the notification
+	RateLimitDeadLetter = "deadletter" // send the record to the dead letter topic
+	RateLimitSample     = "sample"     // keep one of every RateLimitSampleEvery excess notifications
+)
+
+// RateLimit is the token bucket of a producing service: Rate notifications per second with bursts of Burst
+type RateLimit struct {
+	Rate   float64 `json:"rate"`
+	Burst  int     `json:"burst"`
+	Policy string  `json:"policy,omitempty"` // Policy defaults to RateLimitPolicy
+}
+
+// RateLimitOverrides is decoded by envconfig from a json object keyed by service:
+// {"payments":{"rate":100,"burst":200,"policy":"deadletter"}}
+type RateLimitOverrides map[string]RateLimit
+
+// Decode implements envconfig.Decoder
+func (o *RateLimitOverrides) Decode(value string) error {
+	if value == "" {
+		*o = nil
+		return nil
+	}
+
+	var overrides map[string]RateLimit
+	if err := json.Unmarshal([]byte(value), &overrides); err != nil {
+		return fmt.Errorf("invalid rate limit overrides: %w", err)
+	}
+
+	for service, limit := range overrides {
+		if limit.Rate < 0 || limit.Burst < 0 {
+			return fmt.Errorf("invalid rate limit for %s: rate and burst cannot be negative", service)
+		}
+
+		if limit.Policy != "" && !IsValidRateLimitPolicy(limit.Policy) {
+			return fmt.Errorf("invalid rate limit for %s: unknown policy %q", service, limit.Policy)
+		}
+	}
+
+	*o = overrides
+
+	return nil
+}
+
+func IsValidRateLimitPolicy(policy string) bool {
+	switch policy {
+	case RateLimitDrop, RateLimitDeadLetter, RateLimitSample:
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,154 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Validate checks every setting and returns all the problems found at once
+func (a AppInfo) Validate() error {
+	v := validator{}
+
+	switch a.EventSource {
+	case SourceKafka:
+		v.check(len(a.RedpandaBrokers) > 0, "RedpandaBrokers", "at least one broker is required")
+		for _, broker := range a.RedpandaBrokers {
+			v.check(broker != "", "RedpandaBrokers", "brokers cannot be empty")
+		}
+
+		v.check(a.NotificationTopic != "" || len(a.TopicMappings) > 0, "NotificationTopic", "is required without topic mappings")
+		v.check(a.KafkaConsumerGroup != "", "KafkaConsumerGroup", "is required")
+		v.check(a.DeadLetterTopic == "" || a.DeadLetterTopic != a.NotificationTopic,
+			"DeadLetterTopic", "cannot be the notification topic")
+		_, mapped := a.TopicMappings[a.DeadLetterTopic]
+		v.check(a.DeadLetterTopic == "" || !mapped, "DeadLetterTopic", "cannot be a mapped topic")
+	case SourceNATS:
+		v.check(a.NATSURL != "", "NATSURL", "is required")
+		v.check(a.NATSStream != "", "NATSStream", "is required")
+		v.check(a.NATSSubject != "", "NATSSubject", "is required")
+		v.check(a.NATSConsumer != "", "NATSConsumer", "is required")
+		v.check(a.NATSDeadLetterSubject == "" || a.NATSDeadLetterSubject != a.NATSSubject,
+			"NATSDeadLetterSubject", "cannot be the notification subject")
+	case SourceAMQP:
+		v.check(a.AMQPURL != "", "AMQPURL", "is required")
+		v.check(a.AMQPQueue != "", "AMQPQueue", "is required")
+		v.check(a.AMQPPrefetch >= 1, "AMQPPrefetch", "must be at least 1")
+		v.check(a.AMQPDeadLetterQueue == "" || a.AMQPDeadLetterQueue != a.AMQPQueue,
+			"AMQPDeadLetterQueue", "cannot be the notification queue")
+	case SourceFile:
+		v.check(a.EventFile != "", "EventFile", "is required")
+		v.check(a.EventDeadLetterFile == "" || a.EventDeadLetterFile != a.EventFile,
+			"EventDeadLetterFile", "cannot be the events file")
+	default:
+		v.check(false, "EventSource", "must be kafka, nats, amqp or file")
+	}
+
+	_, mapped := a.TopicMappings[a.NotificationTopic]
+	v.check(a.NotificationTopic == "" || !mapped, "TopicMappings", "the notification topic cannot be mapped")
+
+	if a.SchemaRegistryURL != "" {
+		u, err := url.Parse(a.SchemaRegistryURL)
+		v.check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "",
+			"SchemaRegistryURL", "must be an http or https url")
+		v.positive(a.SchemaRegistryTimeout, "SchemaRegistryTimeout")
+	}
+
+	for _, producer := range slices.Sorted(maps.Keys(a.ProducerServices)) {
+		v.check(a.ProducerKeys[producer] != "", "ProducerKeys", fmt.Sprintf("producer %q has no key", producer))
+	}
+
+	for _, header := range a.MetadataHeaders {
+		v.check(header != "", "MetadataHeaders", "headers cannot be empty")
+	}
+
+	v.check(a.EventRetryAttempts >= 1, "EventRetryAttempts", "must be at least 1")
+	v.check(a.EventRetryBackoff >= 0, "EventRetryBackoff", "cannot be negative")
+
+	port, err := strconv.Atoi(a.APIPort)
+	v.check(err == nil && port > 0 && port <= 65535, "APIPort", "must be a port number")
+
+	grpcPort, err := strconv.Atoi(a.GRPCPort)
+	v.check(err == nil && grpcPort > 0 && grpcPort <= 65535, "GRPCPort", "must be a port number")
+	v.check(a.GRPCPort != a.APIPort, "GRPCPort", "cannot be the api port")
+
+	v.check(a.StorageBackend == BackendMongo || a.StorageBackend == BackendMemory, "StorageBackend", "must be mongo or memory")
+	if a.StorageBackend == BackendMongo {
+		v.check(a.MongoURI != "", "MongoURI", "is required")
+		v.check(a.MongoNotificationsDB != "", "MongoNotificationsDB", "is required")
+		v.check(a.MongoNotificationsCollection != "", "MongoNotificationsCollection", "is required")
+		v.check(a.MongoTemplatesCollection != "", "MongoTemplatesCollection", "is required")
+		v.check(a.MongoPreferencesCollection != "", "MongoPreferencesCollection", "is required")
+	}
+
+	v.check(a.CacheBackend == BackendRedis || a.CacheBackend == BackendMemory, "CacheBackend", "must be redis or memory")
+	if a.UseCache && a.CacheBackend == BackendRedis {
+		v.check(a.RedisAddr != "", "RedisAddr", "is required when the redis cache is enabled")
+	}
+
+	for service, token := range a.IngestionTokens {
+		v.check(service != "" && token != "", "IngestionTokens", "services and tokens cannot be empty")
+	}
+	v.check(a.IngestionMaxBatch >= 1, "IngestionMaxBatch", "must be at least 1")
+	v.check(!slices.Contains(a.AdminTokens, ""), "AdminTokens", "tokens cannot be empty")
+
+	v.check(a.DefaultCacheTTLs > 0, "DefaultCacheTTLs", "must be positive")
+	v.check(a.DefaultLocale != "", "DefaultLocale", "is required")
+
+	if a.LogLevel != "" {
+		_, err := zapcore.ParseLevel(a.LogLevel)
+		v.check(err == nil, "LogLevel", "must be debug, info, warn or error")
+	}
+
+	for component, level := range a.LogComponentLevels {
+		_, err := zapcore.ParseLevel(level)
+		v.check(err == nil, "LogComponentLevels", component+" must be debug, info, warn or error")
+	}
+
+	v.check(a.LogSamplingInitial >= 0, "LogSamplingInitial", "cannot be negative")
+	v.check(a.LogSamplingThereafter >= 0, "LogSamplingThereafter", "cannot be negative")
+
+	v.positive(a.SchedulerInterval, "SchedulerInterval")
+	v.positive(a.SchedulerLease, "SchedulerLease")
+	v.positive(a.DigestCheckInterval, "DigestCheckInterval")
+	v.positive(a.HealthCheckInterval, "HealthCheckInterval")
+	v.positive(a.HealthCheckTimeout, "HealthCheckTimeout")
+	v.positive(a.ConfigPollInterval, "ConfigPollInterval")
+	v.positive(a.StartupTimeout, "StartupTimeout")
+	v.positive(a.ShutdownTimeout, "ShutdownTimeout")
+	v.positive(a.DrainTimeout, "DrainTimeout")
+
+	v.check(a.HealthFailureThreshold >= 1, "HealthFailureThreshold", "must be at least 1")
+
+	v.check(a.RateLimitRate >= 0, "RateLimitRate", "cannot be negative")
+	v.check(a.RateLimitBurst >= 0, "RateLimitBurst", "cannot be negative")
+	v.check(IsValidRateLimitPolicy(a.RateLimitPolicy), "RateLimitPolicy", "must be drop, deadletter or sample")
+	v.check(a.RateLimitSampleEvery >= 1, "RateLimitSampleEvery", "must be at least 1")
+
+	return v.err()
+}
+
+// validator collects every failed check
+type validator struct {
+	errs []error
+}
+
+func (v *validator) check(ok bool, field, message string) {
+	if !ok {
+		v.errs = append(v.errs, fmt.Errorf("%s: %s", field, message))
+	}
+}
+
+func (v *validator) positive(d time.Duration, field string) {
+	v.check(d > 0, field, "must be a positive duration")
+}
+
+func (v *validator) err() error {
+	return errors.Join(v.errs...)
+}
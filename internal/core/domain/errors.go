@@ -1 +1,29 @@
-package domain
\ No newline at end of file
+package domain
+
+import "errors"
+
+var (
+	// ErrNotFound is returned when the requested entity does not exist
+	ErrNotFound = errors.New("not found")
+
+	// ErrConflict is returned when an entity with the same key already exists
+	ErrConflict = errors.New("conflict")
+
+	// ErrForbidden is returned when the caller is not allowed to act on the entity
+	ErrForbidden = errors.New("forbidden")
+
+	// ErrInvalidArgument is returned when the input of an operation is not valid
+	ErrInvalidArgument = errors.New("invalid argument")
+
+	// ErrInvalidTemplate is returned when a template cannot be parsed or executed with its example variables
+	ErrInvalidTemplate = errors.New("invalid template")
+
+	// ErrThrottled is returned when a notification exceeded the rate limit of its service
+	ErrThrottled = errors.New("notification throttled")
+
+	// ErrDeadLetter wraps errors of notifications that must be sent to the dead letter topic instead of being retried
+	ErrDeadLetter = errors.New("dead letter")
+
+	// ErrTemplateRender is returned when a stored template could not be rendered with the record variables
+	ErrTemplateRender = errors.New("could not render template")
+)
\ No newline at end of file
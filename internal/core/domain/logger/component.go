@@ -0,0 +1,121 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// components have their own named logger and level. L(ctx) uses the component set with WithComponent
+const (
+	ComponentEventsHub = "eventsHub"
+	ComponentStorage   = "storage"
+	ComponentAPI       = "api"
+)
+
+const componentKey ctxKey = "component"
+
+// componentLevel follows the root level until a level is set for the component
+type componentLevel struct {
+	override atomic.Pointer[zapcore.Level]
+}
+
+// Enabled implements zapcore.LevelEnabler
+func (c *componentLevel) Enabled(l zapcore.Level) bool {
+	if override := c.override.Load(); override != nil {
+		return override.Enabled(l)
+	}
+
+	return level.Enabled(l)
+}
+
+type component struct {
+	level  *componentLevel
+	logger *zap.Logger
+}
+
+// components is built by InitLogger and only read afterwards
+var components = map[string]*component{}
+
+// Levels is the root level and the level of every component. an empty component level follows the root
+type Levels struct {
+	Level      string            `json:"level"`
+	Components map[string]string `json:"components"`
+}
+
+// WithComponent makes L(ctx) log with the named logger and the level of the component
+func WithComponent(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, componentKey, name)
+}
+
+// SetComponentLevel changes the level of a component. an empty level makes it follow the root level again
+func SetComponentLevel(name, levelName string) error {
+	c, ok := components[name]
+	if !ok {
+		return fmt.Errorf("%w: unknown log component %s", domain.ErrNotFound, name)
+	}
+
+	if levelName == "" {
+		c.level.override.Store(nil)
+		return nil
+	}
+
+	parsed, err := zapcore.ParseLevel(levelName)
+	if err != nil {
+		return fmt.Errorf("%w: invalid log level %q", domain.ErrInvalidArgument, levelName)
+	}
+
+	c.level.override.Store(&parsed)
+
+	return nil
+}
+
+// SetComponentLevels sets the level of every component. the ones missing from levels follow the root level
+func SetComponentLevels(levels map[string]string) error {
+	for name := range levels {
+		if _, ok := components[name]; !ok {
+			return fmt.Errorf("%w: unknown log component %s", domain.ErrNotFound, name)
+		}
+	}
+
+	for name := range components {
+		if err := SetComponentLevel(name, levels[name]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetLevels returns the current levels
+func GetLevels() Levels {
+	levels := Levels{
+		Level:      level.Level().String(),
+		Components: make(map[string]string, len(components)),
+	}
+
+	for name, c := range components {
+		levels.Components[name] = ""
+
+		if override := c.level.override.Load(); override != nil {
+			levels.Components[name] = override.String()
+		}
+	}
+
+	return levels
+}
+
+// baseLogger returns the logger of the context component, or the root logger
+func baseLogger(ctx context.Context) *zap.Logger {
+	name, _ := ctx.Value(componentKey).(string)
+
+	if c, ok := components[name]; ok {
+		return c.logger
+	}
+
+	return logger
+}
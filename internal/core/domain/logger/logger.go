@@ -2,23 +2,43 @@ package logger
 
 import (
 	"context"
+	"fmt"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/joseCarlosAndrade/notification-server/internal/core/config"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
 
-var logger *zap.Logger
+// logger discards everything until InitLogger is called, so packages can log in tests and tools
+var logger = zap.NewNop()
 
-// type ctxKey string
-const traceIDKey string = "trace_id"  
+// level can be changed at runtime with SetLevel
+var level = zap.NewAtomicLevel()
+
+// redactBodies hides the payloads logged with Body
+var redactBodies bool
+
+// ctxKey is private so no other package can read or overwrite the logger values in the context
+type ctxKey string
+
+const (
+	traceIDKey   ctxKey = "trace_id"
+	requestIDKey ctxKey = "request_id"
+	fieldsKey    ctxKey = "fields"
+)
+
+// maxRequestIDLength bounds the request ids accepted from clients and producers
+const maxRequestIDLength = 128
 
 func InitLogger() *zap.Logger {
 	var encoderConfig zapcore.EncoderConfig
 	var encoder zapcore.Encoder
-	var level zapcore.LevelEnabler
 	
 	if config.App.Development  {
 		encoderConfig = zap.NewDevelopmentEncoderConfig() // plain text encoder
@@ -27,7 +47,6 @@ func InitLogger() *zap.Logger {
 		// encoderConfig.EncodeCaller = zapcore.FullCallerEncoder // display full file path (/home/username/.../file.go)
 	
 		encoder = zapcore.NewConsoleEncoder(encoderConfig)
-		level = zapcore.DebugLevel
 
 	} else {
 		encoderConfig = zap.NewProductionEncoderConfig() // JSON encoder 
@@ -35,42 +54,182 @@ func InitLogger() *zap.Logger {
 		encoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder // time format // similar to rfc 3339
 
 		encoder = zapcore.NewJSONEncoder(encoderConfig)
-		level = zapcore.InfoLevel
 	}
 	
+	// the level was validated with the config
+	_ = SetLevel(config.App.LogLevel)
 
-	core := zapcore.NewCore(
-		encoder, 
-		zapcore.AddSync(os.Stdout), 
-		level,
-	)
+	redactBodies = config.App.LogRedactBodies && !config.App.Development
 
-	logger = zap.New(
-		core, 
-		zap.AddCaller(), 
+	newLogger := func(enabler zapcore.LevelEnabler) *zap.Logger {
+		var core zapcore.Core = zapcore.NewCore(
+			encoder,
+			zapcore.AddSync(os.Stdout),
+			enabler,
+		)
+
+		// the first messages of each second are logged and then only one of every LogSamplingThereafter
+		// with the same message and level, so high volume messages don't flood the output
+		if config.App.LogSamplingInitial > 0 {
+			core = zapcore.NewSamplerWithOptions(core, time.Second,
+				config.App.LogSamplingInitial, max(config.App.LogSamplingThereafter, 1))
+		}
+
+		return zap.New(
+			core,
+			zap.AddCaller(),
 		// zap.AddCallerSkip(1), // when using wrappers around zap, this prevents the shown path to be the wrapper
 		) // this addCaller also display the path to the file, but relative
+	}
 
+	logger = newLogger(level)
+
+	for _, name := range []string{ComponentEventsHub, ComponentStorage, ComponentAPI} {
+		componentLevel := &componentLevel{}
+		components[name] = &component{
+			level:  componentLevel,
+			logger: newLogger(componentLevel).Named(name),
+		}
+	}
+
+	if err := SetComponentLevels(config.App.LogComponentLevels); err != nil {
+		logger.Warn("could not set component log levels", zap.Error(err))
+	}
 
 	return logger
 }
 
-func InitResources(ctx context.Context) context.Context {
-	traceId := uuid.NewString()
-	ctx = context.WithValue(ctx, "trace_id", traceId)
+// SetLevel changes the level of every logger. an empty level is debug in development and info otherwise
+func SetLevel(name string) error {
+	parsed := zapcore.InfoLevel
+	if config.App.Development {
+		parsed = zapcore.DebugLevel
+	}
 
-	return ctx
-}
+	if name != "" {
+		var err error
 
-// L gets a context and tries to put a field of trace_id from this context into the returned logger
-func L(ctx context.Context) *zap.Logger {
-	if ctx != nil {
-		if id, ok := ctx.Value(traceIDKey).(string); ok {
-			return logger.With(zap.String("trace_id", id))
+		parsed, err = zapcore.ParseLevel(name)
+		if err != nil {
+			return fmt.Errorf("%w: invalid log level %q", domain.ErrInvalidArgument, name)
 		}
 	} 
 
-	return logger
+	level.SetLevel(parsed)
+
+	return nil
+}
+
+// Body logs a message body, unless bodies are redacted (LogRedactBodies in production). then only its size is logged
+func Body(key string, value []byte) zap.Field {
+	if redactBodies {
+		return zap.String(key, fmt.Sprintf("[redacted %d bytes]", len(value)))
+	}
+
+	return zap.ByteString(key, value)
+}
+
+// InitResources loads the request id, a trace_id and the given fields into the context. the request id is the
+// correlation id sent by the producer or client, logged as request_id. it is dropped when longer than
+// maxRequestIDLength or with characters other than letters, digits and -_.:/+=@, so clients can't forge log
+// fields. the trace_id is the one of the current span (a trace was propagated or started) or a new uuid
+func InitResources(ctx context.Context, incomingID string, fields ...zap.Field) context.Context {
+	if validRequestID(incomingID) {
+		ctx = context.WithValue(ctx, requestIDKey, incomingID)
+	}
+
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		ctx = context.WithValue(ctx, traceIDKey, uuid.NewString())
+	}
+
+	return WithFields(ctx, fields...)
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+
+	for _, c := range []byte(id) {
+		valid := c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' ||
+			strings.IndexByte("-_.:/+=@", c) >= 0
+		if !valid {
+			return false
+		}
+	}
+
+	return true
+}
+
+// WithFields attaches fields to every log line written with L(ctx) from the returned context
+func WithFields(ctx context.Context, fields ...zap.Field) context.Context {
+	if len(fields) == 0 {
+		return ctx
+	}
+
+	current, _ := ctx.Value(fieldsKey).([]zap.Field)
+
+	// copy so contexts derived from the same parent don't share the backing array
+	merged := make([]zap.Field, 0, len(current)+len(fields))
+	merged = append(merged, current...)
+	merged = append(merged, fields...)
+
+	return context.WithValue(ctx, fieldsKey, merged)
+}
+
+// TraceID returns the trace_id of the current span, or the one loaded by InitResources when there is no span.
+// empty if there is neither
+func TraceID(ctx context.Context) string {
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		return sc.TraceID().String()
+	}
+
+	id, _ := ctx.Value(traceIDKey).(string)
+
+	return id
+}
+
+// RequestID returns the request id sent by the client or, when it sent none, the trace_id, so the apis can always
+// answer with an id to look the logs up by
+func RequestID(ctx context.Context) string {
+	if id, ok := ctx.Value(requestIDKey).(string); ok {
+		return id
+	}
+
+	return TraceID(ctx)
+}
+
+// L gets a context and puts its trace_id, request_id, the span_id of the current span and the fields attached with
+// WithFields into the returned logger. it is the logger of the component set with WithComponent, if any
+func L(ctx context.Context) *zap.Logger {
+	if ctx == nil {
+		return logger
+	}
+
+	base := baseLogger(ctx)
+
+	stored, _ := ctx.Value(fieldsKey).([]zap.Field)
+
+	fields := make([]zap.Field, 0, len(stored)+3)
+	fields = append(fields, stored...)
+
+	if traceID := TraceID(ctx); traceID != "" {
+		fields = append(fields, zap.String("trace_id", traceID))
+	}
+
+	if requestID, ok := ctx.Value(requestIDKey).(string); ok {
+		fields = append(fields, zap.String("request_id", requestID))
+	}
+
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		fields = append(fields, zap.String("span_id", sc.SpanID().String()))
+	}
+
+	if len(fields) == 0 {
+		return base
+	}
+
+	return base.With(fields...)
 }
 
 // /*
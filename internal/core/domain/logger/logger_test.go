@@ -0,0 +1,55 @@
+package logger
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestInitResourcesRequestID(t *testing.T) {
+	tests := []struct {
+		name     string
+		incoming string
+		valid    bool
+	}{
+		{"Sent", "abc-123_x.y:z/w+v=u@t", true},
+		{"Missing", "", false},
+		{"TooLong", strings.Repeat("a", maxRequestIDLength+1), false},
+		{"Spaces", "abc 123", false},
+		{"Quotes", `abc"}`, false},
+		{"NewLine", "abc\n123", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := InitResources(context.Background(), tt.incoming)
+
+			if got := RequestID(ctx); (got == tt.incoming) != tt.valid {
+				t.Errorf("RequestID = %q for incoming %q, want it used %v", got, tt.incoming, tt.valid)
+			}
+
+			if TraceID(ctx) == "" || RequestID(ctx) == "" {
+				t.Errorf("TraceID = %q, RequestID = %q, want both set", TraceID(ctx), RequestID(ctx))
+			}
+		})
+	}
+}
+
+func TestTraceIDIsTheSpanTraceID(t *testing.T) {
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: trace.TraceID{1},
+		SpanID:  trace.SpanID{1},
+	})
+
+	ctx := InitResources(trace.ContextWithSpanContext(context.Background(), sc), "request")
+
+	if got := TraceID(ctx); got != sc.TraceID().String() {
+		t.Errorf("TraceID = %q, want the span trace id %q", got, sc.TraceID())
+	}
+
+	if got := RequestID(ctx); got != "request" {
+		t.Errorf("RequestID = %q, want %q", got, "request")
+	}
+}
@@ -0,0 +1,62 @@
+package metrics
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Recorder receives the application metrics. adapters emit through R() so they don't depend on the exporter
+type Recorder interface {
+	// RecordConsumed counts a record consumed from a topic partition. a non nil err counts it as failed
+	RecordConsumed(topic string, partition int32, err error)
+	// ObserveProcessing observes how long processing a record took
+	ObserveProcessing(topic string, duration time.Duration)
+	// SetConsumerLag sets how many records the consumer is behind the high watermark of a partition
+	SetConsumerLag(topic string, partition int32, lag int64)
+
+	// ObserveStorage observes the latency of a storage operation. a non nil err counts it as failed
+	ObserveStorage(operation string, duration time.Duration, err error)
+
+	// ObserveHTTPRequest observes an api request by route pattern and status code
+	ObserveHTTPRequest(route string, status int, duration time.Duration)
+	// ObserveGRPCRequest observes a grpc call by full method name and status code
+	ObserveGRPCRequest(method, code string, duration time.Duration)
+
+	// SetHealth sets the last health probe result of a dependency
+	SetHealth(dependency string, healthy bool)
+
+	// RecordThrottled counts a notification exceeding the rate limit of its service and the policy applied
+	RecordThrottled(service, policy string)
+}
+
+type holder struct {
+	recorder Recorder
+}
+
+var current atomic.Pointer[holder]
+
+func init() {
+	current.Store(&holder{recorder: noop{}})
+}
+
+// SetRecorder replaces the global recorder. until it is called, metrics are discarded
+func SetRecorder(recorder Recorder) {
+	current.Store(&holder{recorder: recorder})
+}
+
+// R returns the global recorder
+func R() Recorder {
+	return current.Load().recorder
+}
+
+// noop discards every metric
+type noop struct{}
+
+func (noop) RecordConsumed(string, int32, error)              {}
+func (noop) ObserveProcessing(string, time.Duration)          {}
+func (noop) SetConsumerLag(string, int32, int64)              {}
+func (noop) ObserveStorage(string, time.Duration, error)      {}
+func (noop) ObserveHTTPRequest(string, int, time.Duration)    {}
+func (noop) ObserveGRPCRequest(string, string, time.Duration) {}
+func (noop) SetHealth(string, bool)                           {}
+func (noop) RecordThrottled(string, string)                   {}
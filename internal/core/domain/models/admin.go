@@ -0,0 +1,50 @@
+package models
+
+import "time"
+
+// DeleteFilter selects the notifications deleted by an operator. it deletes them whatever their status
+type DeleteFilter struct {
+	Service   string
+	Recipient string    // optional
+	Since     time.Time // notifications sent at or after it. zero has no lower bound
+	Until     time.Time // notifications sent before it. zero has no upper bound
+}
+
+// ServiceStats summarizes the stored notifications of a service
+type ServiceStats struct {
+	Service  string                       `json:"service"`
+	Total    int64                        `json:"total"`
+	Unread   int64                        `json:"unread"` // Unread counts the visible notifications not read yet
+	ByStatus map[NotificationStatus]int64 `json:"byStatus"`
+	Oldest   time.Time                    `json:"oldest"` // sentAt of the oldest notification
+	Newest   time.Time                    `json:"newest"`
+}
+
+// Add counts n notifications with the given status and read state, sent between oldest and newest
+func (s *ServiceStats) Add(status NotificationStatus, isRead bool, n int64, oldest, newest time.Time) {
+	if s.ByStatus == nil {
+		s.ByStatus = make(map[NotificationStatus]int64)
+	}
+
+	s.Total += n
+	s.ByStatus[status] += n
+
+	if status.IsVisible() && !isRead {
+		s.Unread += n
+	}
+
+	if s.Oldest.IsZero() || oldest.Before(s.Oldest) {
+		s.Oldest = oldest
+	}
+
+	if newest.After(s.Newest) {
+		s.Newest = newest
+	}
+}
+
+// IndexMigration reports the indexes changed by a migration, by collection and name
+type IndexMigration struct {
+	Created []string `json:"created"`
+	Dropped []string `json:"dropped"` // Dropped are the indexes the storage no longer uses
+	Kept    []string `json:"kept"`
+}
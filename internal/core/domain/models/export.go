@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// ExportFormat is the encoding of an export
+type ExportFormat string
+
+const (
+	// ExportNDJSON writes one json notification per line. it is the only format that can be imported back
+	ExportNDJSON ExportFormat = "ndjson"
+	// ExportCSV writes a header and one row per notification, for spreadsheets
+	ExportCSV ExportFormat = "csv"
+)
+
+// IsValid reports if f is one of the known formats
+func (f ExportFormat) IsValid() bool {
+	return f == ExportNDJSON || f == ExportCSV
+}
+
+// ExportFilter selects the notifications of a service to export, whatever their status, oldest first
+type ExportFilter struct {
+	Service   string
+	Recipient string    // optional
+	Since     time.Time // notifications sent at or after it. zero has no lower bound
+	Until     time.Time // notifications sent before it. zero has no upper bound
+}
+
+// ImportResult counts the notifications restored by an import
+type ImportResult struct {
+	Imported int64 `json:"imported"`
+}
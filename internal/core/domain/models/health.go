@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// HealthStatus of a dependency or of the whole application
+type HealthStatus string
+
+const (
+	HealthUp       HealthStatus = "up"
+	HealthDown     HealthStatus = "down"
+	HealthDegraded HealthStatus = "degraded" // HealthDegraded means a non critical dependency is down
+)
+
+// HealthReport is the status of the application and of each dependency
+type HealthReport struct {
+	Status       HealthStatus                `json:"status"`
+	Dependencies map[string]DependencyHealth `json:"dependencies,omitempty"`
+	CheckedAt    time.Time                   `json:"checkedAt"`
+}
+
+type DependencyHealth struct {
+	Status   HealthStatus `json:"status"`
+	Critical bool         `json:"critical"` // Critical dependencies being down make the application not ready
+	// ConsecutiveFailures counts failed probes since the last success. the dependency is down once it reaches the threshold
+	ConsecutiveFailures int        `json:"consecutiveFailures"`
+	LastError           string     `json:"lastError,omitempty"`
+	LastCheck           *time.Time `json:"lastCheck,omitempty"`
+}
@@ -2,20 +2,152 @@ package models
 
 import "time"
 
+// NotificationStatus is the delivery state of a stored notification
+type NotificationStatus string
+
+const (
+	// StatusPending notifications have a deliverAt in the future and are not visible yet
+	StatusPending NotificationStatus = "pending"
+	// StatusDelivered notifications are visible to the recipient. documents without status are delivered
+	StatusDelivered NotificationStatus = "delivered"
+	// StatusDigestPending notifications matched a digest rule and wait to be collapsed into a summary
+	StatusDigestPending NotificationStatus = "digest_pending"
+	// StatusDigested notifications were collapsed into the summary notification referenced by DigestID
+	StatusDigested NotificationStatus = "digested"
+	// StatusSuppressed notifications were muted by the recipient preferences
+	StatusSuppressed NotificationStatus = "suppressed"
+)
+
+// Priority of a notification. empty priorities are treated as PriorityNormal
+type Priority string
+
+const (
+	PriorityLow      Priority = "low"
+	PriorityNormal   Priority = "normal"
+	PriorityHigh     Priority = "high"
+	PriorityCritical Priority = "critical"
+)
+
+// IsValid reports if p is one of the known priorities
+func (p Priority) IsValid() bool {
+	switch p {
+	case PriorityLow, PriorityNormal, PriorityHigh, PriorityCritical:
+		return true
+	}
+
+	return false
+}
+
 // NotificationRecord represents the record received by the eventshub layer
 type NotificationRecord struct {
-	Service string    `json:"service"` // Service represents the service that produced this notification
-	Message string    `json:"message"` // Message is the data
-	SentAt  *time.Time `json:"sentAt"`
+	Service   string     `json:"service"`             // Service represents the service that produced this notification
+	Recipient string     `json:"recipient,omitempty"` // Recipient is the user this notification is addressed to
+	Title     string     `json:"title,omitempty"`
+	Message   string     `json:"message"` // Message is the data
+	SentAt    *time.Time `json:"sentAt"`
+	Category  string     `json:"category,omitempty"`
+	Priority  Priority   `json:"priority,omitempty"`
+
+	// if TemplateID is set, Title and Message are rendered by the server from the stored template
+	TemplateID string         `json:"templateId,omitempty"`
+	Variables  map[string]any `json:"variables,omitempty"` // Variables are passed to the template when rendering
+	Locale     string         `json:"locale,omitempty"`    // Locale selects the template translation. falls back to the default locale
+
+	// DeliverAt delays the notification: it is stored as pending and delivered by the scheduler at this time
+	DeliverAt *time.Time `json:"deliverAt,omitempty"`
+
+	// CollapseKey makes a new notification with the same service, recipient and key replace the unread one
+	// instead of adding another (e.g. "disk-full")
+	CollapseKey string `json:"collapseKey,omitempty"`
+
+	// ID is the id the notification is stored with. when empty a new one is generated. event sources derive it
+	// from the message position, so redeliveries and replays overwrite the notification instead of duplicating it
+	ID string `json:"-"`
+
+	// Metadata holds the whitelisted headers of the message the record came in. only set by the event sources
+	Metadata map[string]string `json:"-"`
+
+	// DigestRule is set by the service when the notification must be accumulated into a digest
+	DigestRule string `json:"-"`
+	// Delivery is set by the service from the recipient preferences
+	Delivery *Delivery `json:"-"`
+}
+
+// InitialStatus is the status the record is stored with
+func (r *NotificationRecord) InitialStatus() NotificationStatus {
+	switch {
+	case r.Delivery != nil && r.Delivery.Decision == DecisionSuppress:
+		return StatusSuppressed
+	case r.DeliverAt != nil:
+		return StatusPending
+	case r.DigestRule != "":
+		return StatusDigestPending
+	}
+
+	return StatusDelivered
+}
+
+// AsNotification is the notification stored for the record under id
+func (r *NotificationRecord) AsNotification(id string) Notification {
+	return Notification{
+		ID:          id,
+		Service:     r.Service,
+		Recipient:   r.Recipient,
+		Title:       r.Title,
+		Message:     r.Message,
+		SentAt:      *r.SentAt,
+		Category:    r.Category,
+		Priority:    r.Priority,
+		Status:      r.InitialStatus(),
+		DeliverAt:   r.DeliverAt,
+		DigestRule:  r.DigestRule,
+		Delivery:    r.Delivery,
+		CollapseKey: r.CollapseKey,
+		Count:       1,
+		Metadata:    r.Metadata,
+	}
+}
+
+// IsVisible reports if the recipient can see the notification: scheduled and digest ones wait until they are
+// delivered or summarized, and suppressed ones never show up
+func (s NotificationStatus) IsVisible() bool {
+	switch s {
+	case StatusPending, StatusDigestPending, StatusDigested, StatusSuppressed:
+		return false
+	}
+
+	return true
 }
 
 type Notification struct {
-	ID      string     `json:"_id"`
-	Service string     `json:"service"`
-	Message string     `json:"message"`
-	IsRead  bool       `json:"isRead"`
-	SentAt  time.Time  `json:"sentAt"`
-	ReadAt  *time.Time `json:"readAt"` // might not exist yet
+	ID        string     `json:"_id"`
+	Service   string     `json:"service"`
+	Recipient string     `json:"recipient,omitempty"`
+	Title     string     `json:"title,omitempty"`
+	Message   string     `json:"message"`
+	IsRead    bool       `json:"isRead"`
+	SentAt    time.Time  `json:"sentAt"`
+	ReadAt    *time.Time `json:"readAt"` // might not exist yet
+	Category  string     `json:"category,omitempty"`
+	Priority  Priority   `json:"priority,omitempty"`
+
+	Status    NotificationStatus `json:"status"`
+	DeliverAt *time.Time         `json:"deliverAt,omitempty"`
+
+	DigestRule string   `json:"digestRule,omitempty"`
+	DigestID   string   `json:"digestId,omitempty"` // DigestID is the summary this notification was collapsed into
+	DigestOf   []string `json:"digestOf,omitempty"` // DigestOf links a summary notification to its originals
+
+	Delivery *Delivery `json:"delivery,omitempty"`
+
+	CollapseKey string `json:"collapseKey,omitempty"`
+	Count       int    `json:"count"` // Count is how many notifications were collapsed into this one
+
+	Metadata map[string]string `json:"metadata,omitempty"` // Metadata holds the whitelisted headers of the source message
+
+	// ArchivedAt is set when the recipient dismissed the notification. archived notifications are kept for the
+	// audit trail but are not listed unless asked for
+	ArchivedAt *time.Time `json:"archivedAt,omitempty"`
 }
 
 // LastTime represnets the filter for getting notifications from the last day-hour-minute
@@ -23,4 +155,34 @@ type LastTime struct {
 	Days int
 	Hours int
 	Minutes int
-}
\ No newline at end of file
+}
+
+// NotificationFilter selects notifications, most recent first. only the visible ones unless IncludeHidden is set,
+// and only the ones not archived unless IncludeArchived is set
+type NotificationFilter struct {
+	Service    string
+	Recipient  string    // optional
+	Since      time.Time // notifications sent at or after it. zero has no lower bound
+	Until      time.Time // notifications sent before it. zero has no upper bound
+	UnreadOnly bool
+	Search     string // optional. case insensitive text the title or message must contain
+
+	// IncludeHidden also returns the notifications the recipient can't see: scheduled, digest and suppressed ones
+	IncludeHidden bool
+	// IncludeArchived also returns the notifications the recipient archived
+	IncludeArchived bool
+
+	Limit  int // limit <= 0 returns all
+	Offset int
+}
+
+// SubscriptionFilter selects the notifications streamed to a subscriber. empty fields match everything
+type SubscriptionFilter struct {
+	Service   string
+	Recipient string
+}
+
+// Matches reports if the notification must be streamed to the subscriber
+func (f SubscriptionFilter) Matches(n *Notification) bool {
+	return (f.Service == "" || f.Service == n.Service) && (f.Recipient == "" || f.Recipient == n.Recipient)
+}
@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// Preferences are set by a recipient to control which notifications they get and how
+type Preferences struct {
+	Recipient       string                `json:"recipient"`
+	Locale          string                `json:"locale,omitempty"`   // Locale is preferred over the record locale when rendering templates
+	TimeZone        string                `json:"timeZone,omitempty"` // TimeZone is an IANA name (America/Sao_Paulo) used by the quiet hours. defaults to UTC
+	MutedServices   []string              `json:"mutedServices,omitempty"`
+	MutedCategories []string              `json:"mutedCategories,omitempty"`
+	QuietHours      *QuietHours           `json:"quietHours,omitempty"`
+	Channels        map[Priority][]string `json:"channels,omitempty"` // Channels selects where notifications are routed per priority
+	UpdatedAt       time.Time             `json:"updatedAt"`
+}
+
+// QuietHours is a daily window, in HH:MM of the recipient time zone, where notifications are stored silently.
+// the window may cross midnight (22:00 - 07:00)
+type QuietHours struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+// DeliveryDecision is what the service decided to do with a notification based on the recipient preferences
+type DeliveryDecision string
+
+const (
+	// DecisionDeliver notifications are delivered through the routed channels
+	DecisionDeliver DeliveryDecision = "deliver"
+	// DecisionSilent notifications are stored in the inbox but not pushed to any channel
+	DecisionSilent DeliveryDecision = "silent"
+	// DecisionSuppress notifications are kept only for audit and are not visible to the recipient
+	DecisionSuppress DeliveryDecision = "suppress"
+)
+
+// Delivery records the decision taken for a notification
+type Delivery struct {
+	Decision DeliveryDecision `json:"decision"`
+	Reason   string           `json:"reason,omitempty"`
+	Channels []string         `json:"channels,omitempty"`
+}
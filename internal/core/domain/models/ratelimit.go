@@ -0,0 +1,11 @@
+package models
+
+// ThrottleStats counts what the rate limiter did with the notifications of a service
+type ThrottleStats struct {
+	Service      string `json:"service"`
+	Allowed      uint64 `json:"allowed"`
+	Throttled    uint64 `json:"throttled"` // Throttled is the total of excess notifications, whatever the policy
+	Dropped      uint64 `json:"dropped"`
+	DeadLettered uint64 `json:"deadLettered"`
+	Sampled      uint64 `json:"sampled"` // Sampled excess notifications were kept by the sample policy
+}
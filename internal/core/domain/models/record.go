@@ -0,0 +1,73 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain"
+)
+
+// DecodeNotificationRecord parses and validates a json record. the event sources and the http api use it, so
+// records are accepted or rejected the same way wherever they come from. producer is the authenticated service
+// the record must belong to (see SetProducer), empty for sources that authorize producers on their own
+func DecodeNotificationRecord(value []byte, producer string) (*NotificationRecord, error) {
+	var record NotificationRecord
+
+	if err := json.Unmarshal(value, &record); err != nil {
+		return nil, fmt.Errorf("%w: could not parse payload: %w", domain.ErrInvalidArgument, err)
+	}
+
+	if producer != "" {
+		if err := record.SetProducer(producer); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := record.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &record, nil
+}
+
+// SetProducer makes the record belong to the authenticated service: an empty service is set to it and records of
+// other services are rejected with domain.ErrForbidden
+func (r *NotificationRecord) SetProducer(service string) error {
+	if r.Service == "" {
+		r.Service = service
+	}
+
+	if r.Service != service {
+		return fmt.Errorf("%w: cannot send notifications of service %q", domain.ErrForbidden, r.Service)
+	}
+
+	return nil
+}
+
+// Validate checks the required fields and normalizes the record: if not passed, sentAt is now, and it is
+// always UTC. errors wrap domain.ErrInvalidArgument
+func (r *NotificationRecord) Validate() error {
+	if r.Service == "" {
+		return fmt.Errorf("%w: service is required", domain.ErrInvalidArgument)
+	}
+
+	if r.Message == "" && r.TemplateID == "" {
+		return fmt.Errorf("%w: message or templateId is required", domain.ErrInvalidArgument)
+	}
+
+	if r.Priority != "" && !r.Priority.IsValid() {
+		return fmt.Errorf("%w: unknown priority %q", domain.ErrInvalidArgument, r.Priority)
+	}
+
+	// if not passed, use now from UTC
+	if r.SentAt == nil {
+		now := domain.NewNowTime()
+		r.SentAt = &now
+	} else if r.SentAt.Location() != time.UTC {
+		// ensuring the timestamp is utc
+		*r.SentAt = r.SentAt.UTC()
+	}
+
+	return nil
+}
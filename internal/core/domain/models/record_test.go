@@ -0,0 +1,38 @@
+package models
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain"
+)
+
+func TestDecodeNotificationRecord(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		producer string
+		service  string
+		err      error
+	}{
+		{"Valid", `{"service":"shop","message":"hi"}`, "", "shop", nil},
+		{"UnknownFieldsIgnored", `{"service":"shop","message":"hi","extra":1}`, "", "shop", nil},
+		{"ServiceFromProducer", `{"message":"hi"}`, "shop", "shop", nil},
+		{"OtherProducer", `{"service":"bank","message":"hi"}`, "shop", "", domain.ErrForbidden},
+		{"MissingService", `{"message":"hi"}`, "", "", domain.ErrInvalidArgument},
+		{"InvalidJSON", `{"message":`, "shop", "", domain.ErrInvalidArgument},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			record, err := DecodeNotificationRecord([]byte(tt.value), tt.producer)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("err = %v, want %v", err, tt.err)
+			}
+
+			if err == nil && record.Service != tt.service {
+				t.Errorf("service = %q, want %q", record.Service, tt.service)
+			}
+		})
+	}
+}
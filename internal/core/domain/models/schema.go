@@ -0,0 +1,26 @@
+package models
+
+// SchemaType is the format of a schema registry schema
+type SchemaType string
+
+const (
+	SchemaAvro     SchemaType = "AVRO" // the registry omits the type of avro schemas
+	SchemaProtobuf SchemaType = "PROTOBUF"
+	SchemaJSON     SchemaType = "JSON"
+)
+
+// Schema is a schema registered in the schema registry
+type Schema struct {
+	ID         int
+	Type       SchemaType
+	Text       string
+	References []SchemaReference // other schemas imported by this one
+}
+
+// SchemaReference points to the subject version holding an imported schema. Name is how the schema imports
+// it: the import path for protobuf and the full name of the type for avro
+type SchemaReference struct {
+	Name    string
+	Subject string
+	Version int
+}
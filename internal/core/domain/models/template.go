@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// Template is a versioned, per-locale notification template using text/template syntax.
+// Versions are immutable: uploading a template again creates a new version
+type Template struct {
+	ID        string         `json:"id"`
+	Locale    string         `json:"locale"`
+	Version   int            `json:"version"`
+	Title     string         `json:"title"`
+	Body      string         `json:"body"`
+	Example   map[string]any `json:"example,omitempty"` // Example has every variable used, to validate the template at upload time
+	CreatedAt time.Time      `json:"createdAt"`
+}
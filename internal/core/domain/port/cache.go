@@ -1,8 +1,16 @@
 package port
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 type Cache interface {
 	Runner
 	IsHealthy(ctx context.Context) error 
+
+	// Get returns the cached value or domain.ErrNotFound
+	Get(ctx context.Context, key string) ([]byte, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, key string) error
 }
\ No newline at end of file
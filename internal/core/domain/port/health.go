@@ -0,0 +1,16 @@
+package port
+
+import (
+	"context"
+
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain/models"
+)
+
+type Health interface {
+	// Liveness reports if the application is running. it does not depend on the dependencies
+	Liveness(ctx context.Context) models.HealthReport
+	// Readiness reports the status of every dependency. the application is ready unless a critical one is down
+	Readiness(ctx context.Context) models.HealthReport
+	// IsUp reports if the dependency is up. unknown dependencies are down
+	IsUp(name string) bool
+}
@@ -0,0 +1,881 @@
+// Package porttest implements conformance suites for the storage ports, like testing/fstest does for file
+// systems. every adapter runs the same suite from its tests, so they all follow one behavior contract:
+//
+//	func TestStorage(t *testing.T) {
+//		porttest.TestStorage(t, func(t *testing.T) port.Storage {
+//			return memory.NewStorage(context.Background())
+//		})
+//	}
+package porttest
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain/models"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain/port"
+)
+
+const service = "porttest"
+
+// TestStorage checks the behavior of a port.Storage. newStorage must return an empty storage on every call
+func TestStorage(t *testing.T, newStorage func(t *testing.T) port.Storage) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, s port.Storage)
+	}{
+		{"GetAllNotificationsByTime", testGetByTime},
+		{"StoreIsIdempotent", testStoreIsIdempotent},
+		{"StoreKeepsState", testStoreKeepsState},
+		{"GetLatestNotifications", testGetLatest},
+		{"GetNotification", testGetNotification},
+		{"MarkNotificationAsRead", testMarkAsRead},
+		{"ArchiveNotification", testArchive},
+		{"DeleteNotification", testDeleteNotification},
+		{"ListNotifications", testList},
+		{"HiddenStatuses", testHiddenStatuses},
+		{"CollapseKey", testCollapse},
+		{"CollapseIsIdempotent", testCollapseIsIdempotent},
+		{"Metadata", testMetadata},
+		{"ClaimAndPromote", testClaimAndPromote},
+		{"ExpiredLease", testExpiredLease},
+		{"Digest", testDigest},
+		{"DeleteNotifications", testDelete},
+		{"NotificationStats", testStats},
+		{"ExportNotifications", testExport},
+		{"RestoreNotifications", testRestore},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.run(t, newStorage(t))
+		})
+	}
+}
+
+// now is truncated to milliseconds, the precision of most databases
+func now() time.Time {
+	return domain.NewNowTime().Truncate(time.Millisecond)
+}
+
+func record(sentAt time.Time, message string) *models.NotificationRecord {
+	return &models.NotificationRecord{
+		Service:   service,
+		Recipient: "recipient",
+		Message:   message,
+		SentAt:    &sentAt,
+		Priority:  models.PriorityNormal,
+	}
+}
+
+func store(t *testing.T, s port.Storage, rec *models.NotificationRecord) string {
+	t.Helper()
+
+	id, err := s.StoreNewNotification(context.Background(), rec, uuid.NewString())
+	if err != nil {
+		t.Fatalf("StoreNewNotification: %v", err)
+	}
+
+	return id
+}
+
+func latest(t *testing.T, s port.Storage) []*models.Notification {
+	t.Helper()
+
+	found, err := s.GetLatestNotifications(context.Background(), service, 0)
+	if err != nil {
+		t.Fatalf("GetLatestNotifications: %v", err)
+	}
+
+	return found
+}
+
+func ids(notifications []*models.Notification) []string {
+	final := make([]string, 0, len(notifications))
+	for _, n := range notifications {
+		final = append(final, n.ID)
+	}
+
+	return final
+}
+
+func testGetByTime(t *testing.T, s port.Storage) {
+	ctx := context.Background()
+
+	recent := store(t, s, record(now().Add(-5*time.Minute), "recent"))
+	store(t, s, record(now().Add(-2*time.Hour), "old"))
+
+	other := record(now(), "other service")
+	other.Service = "other"
+	store(t, s, other)
+
+	found, err := s.GetAllNotificationsByTime(ctx, service, models.LastTime{Hours: 1})
+	if err != nil {
+		t.Fatalf("GetAllNotificationsByTime: %v", err)
+	}
+
+	if got := ids(found); !slices.Equal(got, []string{recent}) {
+		t.Errorf("GetAllNotificationsByTime = %v, want [%s]", got, recent)
+	}
+}
+
+func testStoreIsIdempotent(t *testing.T, s port.Storage) {
+	ctx := context.Background()
+	rec := record(now(), "same")
+
+	for range 2 {
+		id, err := s.StoreNewNotification(ctx, rec, "fixed-id")
+		if err != nil {
+			t.Fatalf("StoreNewNotification: %v", err)
+		}
+
+		if id != "fixed-id" {
+			t.Errorf("StoreNewNotification id = %s, want fixed-id", id)
+		}
+	}
+
+	if got := ids(latest(t, s)); !slices.Equal(got, []string{"fixed-id"}) {
+		t.Errorf("notifications = %v, want [fixed-id]", got)
+	}
+}
+
+// testStoreKeepsState checks that storing an id again, as replays do, does not reset what happened to it
+func testStoreKeepsState(t *testing.T, s port.Storage) {
+	ctx := context.Background()
+
+	if _, err := s.StoreNewNotification(ctx, record(now(), "first"), "fixed-id"); err != nil {
+		t.Fatalf("StoreNewNotification: %v", err)
+	}
+
+	if err := s.ArchiveNotification(ctx, "fixed-id", now()); err != nil {
+		t.Fatalf("ArchiveNotification: %v", err)
+	}
+
+	if _, err := s.StoreNewNotification(ctx, record(now(), "replayed"), "fixed-id"); err != nil {
+		t.Fatalf("StoreNewNotification again: %v", err)
+	}
+
+	found, err := s.ListNotifications(ctx, models.NotificationFilter{Service: service, IncludeArchived: true})
+	if err != nil {
+		t.Fatalf("ListNotifications: %v", err)
+	}
+
+	if len(found) != 1 {
+		t.Fatalf("ListNotifications = %v, want [fixed-id]", ids(found))
+	}
+
+	if n := found[0]; !n.IsRead || n.ReadAt == nil || n.ArchivedAt == nil || n.Message != "first" {
+		t.Errorf("stored again notification has isRead %v, readAt %v, archivedAt %v and message %q, "+
+			"want it read, archived and unchanged", n.IsRead, n.ReadAt, n.ArchivedAt, n.Message)
+	}
+}
+
+func testGetLatest(t *testing.T, s port.Storage) {
+	ctx := context.Background()
+
+	oldest := store(t, s, record(now().Add(-3*time.Minute), "oldest"))
+	middle := store(t, s, record(now().Add(-2*time.Minute), "middle"))
+	newest := store(t, s, record(now().Add(-time.Minute), "newest"))
+
+	found, err := s.GetLatestNotifications(ctx, service, 2)
+	if err != nil {
+		t.Fatalf("GetLatestNotifications: %v", err)
+	}
+
+	if got := ids(found); !slices.Equal(got, []string{newest, middle}) {
+		t.Errorf("GetLatestNotifications(2) = %v, want [%s %s]", got, newest, middle)
+	}
+
+	if got := ids(latest(t, s)); !slices.Equal(got, []string{newest, middle, oldest}) {
+		t.Errorf("GetLatestNotifications(0) = %v, want every notification", got)
+	}
+}
+
+func testGetNotification(t *testing.T, s port.Storage) {
+	ctx := context.Background()
+
+	id := store(t, s, record(now(), "stored"))
+
+	n, err := s.GetNotification(ctx, id)
+	if err != nil {
+		t.Fatalf("GetNotification: %v", err)
+	}
+
+	if n.ID != id || n.Service != service || n.Message != "stored" {
+		t.Errorf("GetNotification = %+v, want the stored notification %s", n, id)
+	}
+
+	if _, err := s.GetNotification(ctx, "missing"); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("GetNotification(missing) = %v, want domain.ErrNotFound", err)
+	}
+}
+
+func testMarkAsRead(t *testing.T, s port.Storage) {
+	ctx := context.Background()
+
+	read := store(t, s, record(now().Add(-time.Minute), "read"))
+	unread := store(t, s, record(now(), "unread"))
+
+	if err := s.MarkNotificationAsRead(ctx, read); err != nil {
+		t.Fatalf("MarkNotificationAsRead: %v", err)
+	}
+
+	found, err := s.GetNonReadNotifications(ctx, service)
+	if err != nil {
+		t.Fatalf("GetNonReadNotifications: %v", err)
+	}
+
+	if got := ids(found); !slices.Equal(got, []string{unread}) {
+		t.Errorf("GetNonReadNotifications = %v, want [%s]", got, unread)
+	}
+
+	for _, n := range latest(t, s) {
+		if n.ID == read && (!n.IsRead || n.ReadAt == nil) {
+			t.Errorf("read notification has isRead %v and readAt %v", n.IsRead, n.ReadAt)
+		}
+	}
+
+	if err := s.MarkNotificationAsRead(ctx, "missing"); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("MarkNotificationAsRead(missing) = %v, want domain.ErrNotFound", err)
+	}
+}
+
+func testArchive(t *testing.T, s port.Storage) {
+	ctx := context.Background()
+
+	archivedRecord := record(now().Add(-time.Minute), "archived")
+	archivedRecord.CollapseKey = "key"
+	archived := store(t, s, archivedRecord)
+	kept := store(t, s, record(now(), "kept"))
+
+	archivedAt := now()
+	if err := s.ArchiveNotification(ctx, archived, archivedAt); err != nil {
+		t.Fatalf("ArchiveNotification: %v", err)
+	}
+
+	// archiving again keeps the first archivedAt
+	if err := s.ArchiveNotification(ctx, archived, archivedAt.Add(time.Hour)); err != nil {
+		t.Fatalf("ArchiveNotification again: %v", err)
+	}
+
+	if got := ids(latest(t, s)); !slices.Equal(got, []string{kept}) {
+		t.Errorf("GetLatestNotifications = %v, want [%s]", got, kept)
+	}
+
+	unread, err := s.GetNonReadNotifications(ctx, service)
+	if err != nil {
+		t.Fatalf("GetNonReadNotifications: %v", err)
+	}
+
+	if got := ids(unread); !slices.Equal(got, []string{kept}) {
+		t.Errorf("GetNonReadNotifications = %v, want [%s]", got, kept)
+	}
+
+	byTime, err := s.GetAllNotificationsByTime(ctx, service, models.LastTime{Hours: 1})
+	if err != nil {
+		t.Fatalf("GetAllNotificationsByTime: %v", err)
+	}
+
+	if got := ids(byTime); !slices.Equal(got, []string{kept}) {
+		t.Errorf("GetAllNotificationsByTime = %v, want [%s]", got, kept)
+	}
+
+	all, err := s.ListNotifications(ctx, models.NotificationFilter{Service: service, IncludeArchived: true})
+	if err != nil {
+		t.Fatalf("ListNotifications: %v", err)
+	}
+
+	if got := ids(all); !slices.Equal(got, []string{kept, archived}) {
+		t.Fatalf("ListNotifications with archived ones = %v, want [%s %s]", got, kept, archived)
+	}
+
+	if n := all[1]; n.ArchivedAt == nil || !n.ArchivedAt.Equal(archivedAt) || !n.IsRead || n.ReadAt == nil {
+		t.Errorf("archived notification has archivedAt %v, isRead %v and readAt %v, want archivedAt %v and read",
+			n.ArchivedAt, n.IsRead, n.ReadAt, archivedAt)
+	}
+
+	// an archived notification is never collapsed into
+	next := record(now(), "next")
+	next.CollapseKey = "key"
+
+	if nextID := store(t, s, next); nextID == archived {
+		t.Errorf("notification was collapsed into the archived one")
+	}
+
+	if err := s.UnarchiveNotification(ctx, archived); err != nil {
+		t.Fatalf("UnarchiveNotification: %v", err)
+	}
+
+	listed, err := s.ListNotifications(ctx, models.NotificationFilter{Service: service})
+	if err != nil {
+		t.Fatalf("ListNotifications: %v", err)
+	}
+
+	if !slices.Contains(ids(listed), archived) {
+		t.Errorf("ListNotifications = %v, want the unarchived %s", ids(listed), archived)
+	}
+
+	for _, n := range listed {
+		if n.ID == archived && (n.ArchivedAt != nil || !n.IsRead) {
+			t.Errorf("unarchived notification has archivedAt %v and isRead %v, want no archivedAt and read",
+				n.ArchivedAt, n.IsRead)
+		}
+	}
+
+	if err := s.ArchiveNotification(ctx, "missing", now()); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("ArchiveNotification(missing) = %v, want domain.ErrNotFound", err)
+	}
+
+	if err := s.UnarchiveNotification(ctx, "missing"); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("UnarchiveNotification(missing) = %v, want domain.ErrNotFound", err)
+	}
+}
+
+func testDeleteNotification(t *testing.T, s port.Storage) {
+	ctx := context.Background()
+
+	deleted := store(t, s, record(now().Add(-time.Minute), "deleted"))
+	kept := store(t, s, record(now(), "kept"))
+
+	// archived notifications can be deleted too
+	if err := s.ArchiveNotification(ctx, deleted, now()); err != nil {
+		t.Fatalf("ArchiveNotification: %v", err)
+	}
+
+	if err := s.DeleteNotification(ctx, deleted); err != nil {
+		t.Fatalf("DeleteNotification: %v", err)
+	}
+
+	all, err := s.ListNotifications(ctx, models.NotificationFilter{Service: service, IncludeArchived: true})
+	if err != nil {
+		t.Fatalf("ListNotifications: %v", err)
+	}
+
+	if got := ids(all); !slices.Equal(got, []string{kept}) {
+		t.Errorf("ListNotifications = %v, want [%s]", got, kept)
+	}
+
+	if err := s.DeleteNotification(ctx, deleted); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("DeleteNotification(deleted) = %v, want domain.ErrNotFound", err)
+	}
+}
+
+func testList(t *testing.T, s port.Storage) {
+	ctx := context.Background()
+
+	oldest := store(t, s, record(now().Add(-3*time.Hour), "oldest"))
+	middle := store(t, s, record(now().Add(-2*time.Hour), "middle"))
+	newestRecord := record(now().Add(-time.Hour), "newest")
+	newestRecord.Title = "titled"
+	newest := store(t, s, newestRecord)
+
+	other := record(now(), "other recipient")
+	other.Recipient = "other"
+	otherID := store(t, s, other)
+
+	if err := s.MarkNotificationAsRead(ctx, middle); err != nil {
+		t.Fatalf("MarkNotificationAsRead: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		filter models.NotificationFilter
+		want   []string
+	}{
+		{"All", models.NotificationFilter{}, []string{otherID, newest, middle, oldest}},
+		{"Recipient", models.NotificationFilter{Recipient: "recipient"}, []string{newest, middle, oldest}},
+		{"UnreadOnly", models.NotificationFilter{UnreadOnly: true}, []string{otherID, newest, oldest}},
+		{"Since", models.NotificationFilter{Since: now().Add(-90 * time.Minute)}, []string{otherID, newest}},
+		{"Until", models.NotificationFilter{Until: now().Add(-90 * time.Minute)}, []string{middle, oldest}},
+		{"FirstPage", models.NotificationFilter{Limit: 3}, []string{otherID, newest, middle}},
+		{"LastPage", models.NotificationFilter{Limit: 3, Offset: 3}, []string{oldest}},
+		{"PastTheEnd", models.NotificationFilter{Offset: 10}, []string{}},
+		{"Search", models.NotificationFilter{Search: "MIDDLE"}, []string{middle}},
+		{"SearchTitle", models.NotificationFilter{Search: "titled"}, []string{newest}},
+	}
+
+	for _, tt := range tests {
+		tt.filter.Service = service
+
+		found, err := s.ListNotifications(ctx, tt.filter)
+		if err != nil {
+			t.Fatalf("ListNotifications(%s): %v", tt.name, err)
+		}
+
+		if got := ids(found); !slices.Equal(got, tt.want) {
+			t.Errorf("ListNotifications(%s) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func testHiddenStatuses(t *testing.T, s port.Storage) {
+	visible := store(t, s, record(now(), "visible"))
+
+	scheduled := record(now(), "scheduled")
+	deliverAt := now().Add(time.Hour)
+	scheduled.DeliverAt = &deliverAt
+	store(t, s, scheduled)
+
+	digest := record(now(), "digest")
+	digest.DigestRule = "rule"
+	store(t, s, digest)
+
+	suppressed := record(now(), "suppressed")
+	suppressed.Delivery = &models.Delivery{Decision: models.DecisionSuppress}
+	store(t, s, suppressed)
+
+	if got := ids(latest(t, s)); !slices.Equal(got, []string{visible}) {
+		t.Errorf("visible notifications = %v, want [%s]", got, visible)
+	}
+
+	all, err := s.ListNotifications(context.Background(), models.NotificationFilter{Service: service, IncludeHidden: true})
+	if err != nil {
+		t.Fatalf("ListNotifications: %v", err)
+	}
+
+	if len(all) != 4 {
+		t.Errorf("ListNotifications with hidden ones = %v, want 4 notifications", ids(all))
+	}
+}
+
+func testCollapse(t *testing.T, s port.Storage) {
+	ctx := context.Background()
+
+	first := record(now().Add(-time.Minute), "first")
+	first.CollapseKey = "key"
+	firstID := store(t, s, first)
+
+	second := record(now(), "second")
+	second.CollapseKey = "key"
+	secondID := store(t, s, second)
+
+	if secondID != firstID {
+		t.Fatalf("collapsed notification id = %s, want %s", secondID, firstID)
+	}
+
+	found := latest(t, s)
+	if len(found) != 1 || found[0].Count != 2 || found[0].Message != "second" {
+		t.Fatalf("collapsed notifications = %+v, want one with count 2 and the last message", found)
+	}
+
+	// a read notification is not replaced anymore
+	if err := s.MarkNotificationAsRead(ctx, firstID); err != nil {
+		t.Fatalf("MarkNotificationAsRead: %v", err)
+	}
+
+	third := record(now(), "third")
+	third.CollapseKey = "key"
+
+	if thirdID := store(t, s, third); thirdID == firstID {
+		t.Errorf("notification collapsed into a read one")
+	}
+}
+
+func testCollapseIsIdempotent(t *testing.T, s port.Storage) {
+	ctx := context.Background()
+
+	collapsible := func(message string) *models.NotificationRecord {
+		rec := record(now(), message)
+		rec.CollapseKey = "key"
+
+		return rec
+	}
+
+	firstID, err := s.StoreNewNotification(ctx, collapsible("first"), "first-id")
+	if err != nil {
+		t.Fatalf("StoreNewNotification: %v", err)
+	}
+
+	// the second id is stored twice, as a replay would
+	for range 2 {
+		id, err := s.StoreNewNotification(ctx, collapsible("second"), "second-id")
+		if err != nil {
+			t.Fatalf("StoreNewNotification: %v", err)
+		}
+
+		if id != firstID {
+			t.Fatalf("collapsed notification id = %s, want %s", id, firstID)
+		}
+	}
+
+	found := latest(t, s)
+	if len(found) != 1 || found[0].Count != 2 {
+		t.Fatalf("collapsed notifications = %+v, want one with count 2", found)
+	}
+
+	// once read, a replayed id is still not stored as a new notification
+	if err := s.MarkNotificationAsRead(ctx, firstID); err != nil {
+		t.Fatalf("MarkNotificationAsRead: %v", err)
+	}
+
+	id, err := s.StoreNewNotification(ctx, collapsible("second"), "second-id")
+	if err != nil {
+		t.Fatalf("StoreNewNotification: %v", err)
+	}
+
+	if id != firstID {
+		t.Errorf("replayed collapsed notification id = %s, want %s", id, firstID)
+	}
+
+	if found := latest(t, s); len(found) != 1 || found[0].Count != 2 || !found[0].IsRead {
+		t.Errorf("collapsed notifications = %+v, want one read with count 2", found)
+	}
+}
+
+func testMetadata(t *testing.T, s port.Storage) {
+	rec := record(now(), "with metadata")
+	rec.Metadata = map[string]string{"tenant": "acme"}
+	id := store(t, s, rec)
+
+	found := latest(t, s)
+	if len(found) != 1 || found[0].ID != id {
+		t.Fatalf("GetLatestNotifications = %v, want [%s]", ids(found), id)
+	}
+
+	if got := found[0].Metadata["tenant"]; got != "acme" {
+		t.Errorf("metadata tenant = %q, want acme", got)
+	}
+}
+
+func testClaimAndPromote(t *testing.T, s port.Storage) {
+	ctx := context.Background()
+	at := now()
+
+	pending := record(at.Add(-time.Hour), "pending")
+	deliverAt := at.Add(-time.Minute)
+	pending.DeliverAt = &deliverAt
+	pending.Delivery = &models.Delivery{Decision: models.DecisionSilent, Reason: "quiet hours"}
+	id := store(t, s, pending)
+
+	future := record(at, "future")
+	futureAt := at.Add(time.Hour)
+	future.DeliverAt = &futureAt
+	store(t, s, future)
+
+	claimed, err := s.ClaimDueNotifications(ctx, "a", at, time.Minute, 10)
+	if err != nil {
+		t.Fatalf("ClaimDueNotifications: %v", err)
+	}
+
+	if got := ids(claimed); !slices.Equal(got, []string{id}) {
+		t.Fatalf("ClaimDueNotifications = %v, want [%s]", got, id)
+	}
+
+	claimed, err = s.ClaimDueNotifications(ctx, "b", at, time.Minute, 10)
+	if err != nil || len(claimed) != 0 {
+		t.Fatalf("ClaimDueNotifications of a leased notification = %v, %v, want none", ids(claimed), err)
+	}
+
+	if err := s.PromoteNotification(ctx, id, "b", at); !errors.Is(err, domain.ErrConflict) {
+		t.Errorf("PromoteNotification by another owner = %v, want domain.ErrConflict", err)
+	}
+
+	if err := s.PromoteNotification(ctx, id, "a", at); err != nil {
+		t.Fatalf("PromoteNotification: %v", err)
+	}
+
+	found := latest(t, s)
+	if len(found) != 1 || found[0].ID != id || !found[0].SentAt.Equal(at) || found[0].Status != models.StatusDelivered {
+		t.Fatalf("promoted notifications = %+v, want %s delivered at %s", found, id, at)
+	}
+
+	if found[0].Delivery == nil || found[0].Delivery.Decision != models.DecisionSilent {
+		t.Errorf("promoted delivery = %+v, want the silent decision kept", found[0].Delivery)
+	}
+
+	if err := s.PromoteNotification(ctx, id, "a", at); !errors.Is(err, domain.ErrConflict) {
+		t.Errorf("second PromoteNotification = %v, want domain.ErrConflict", err)
+	}
+}
+
+func testExpiredLease(t *testing.T, s port.Storage) {
+	ctx := context.Background()
+	at := now()
+
+	pending := record(at, "pending")
+	deliverAt := at.Add(-time.Minute)
+	pending.DeliverAt = &deliverAt
+	id := store(t, s, pending)
+
+	if _, err := s.ClaimDueNotifications(ctx, "a", at, time.Minute, 10); err != nil {
+		t.Fatalf("ClaimDueNotifications: %v", err)
+	}
+
+	// the owner died and the lease expired
+	claimed, err := s.ClaimDueNotifications(ctx, "b", at.Add(2*time.Minute), time.Minute, 10)
+	if err != nil {
+		t.Fatalf("ClaimDueNotifications: %v", err)
+	}
+
+	if got := ids(claimed); !slices.Equal(got, []string{id}) {
+		t.Fatalf("ClaimDueNotifications after the lease expired = %v, want [%s]", got, id)
+	}
+
+	if err := s.PromoteNotification(ctx, id, "a", at); !errors.Is(err, domain.ErrConflict) {
+		t.Errorf("PromoteNotification by the expired owner = %v, want domain.ErrConflict", err)
+	}
+}
+
+func testDigest(t *testing.T, s port.Storage) {
+	ctx := context.Background()
+	at := now()
+
+	originals := make([]string, 0, 2)
+	for _, message := range []string{"a", "b"} {
+		rec := record(at.Add(-time.Minute), message)
+		rec.DigestRule = "rule"
+		originals = append(originals, store(t, s, rec))
+	}
+
+	pending, err := s.GetPendingDigestNotifications(ctx, "rule", at)
+	if err != nil {
+		t.Fatalf("GetPendingDigestNotifications: %v", err)
+	}
+
+	if len(pending) != 2 {
+		t.Fatalf("GetPendingDigestNotifications = %v, want %v", ids(pending), originals)
+	}
+
+	summary := record(at, "2 notifications")
+
+	// storing the same digest twice must not duplicate it
+	for range 2 {
+		if err := s.StoreDigest(ctx, summary, "digest-id", originals); err != nil {
+			t.Fatalf("StoreDigest: %v", err)
+		}
+	}
+
+	found := latest(t, s)
+	if len(found) != 1 || found[0].ID != "digest-id" {
+		t.Fatalf("visible notifications = %v, want [digest-id]", ids(found))
+	}
+
+	digestOf := slices.Sorted(slices.Values(found[0].DigestOf))
+	if !slices.Equal(digestOf, slices.Sorted(slices.Values(originals))) {
+		t.Errorf("digestOf = %v, want %v", found[0].DigestOf, originals)
+	}
+
+	pending, err = s.GetPendingDigestNotifications(ctx, "rule", at)
+	if err != nil || len(pending) != 0 {
+		t.Errorf("GetPendingDigestNotifications after the digest = %v, %v, want none", ids(pending), err)
+	}
+}
+
+func testDelete(t *testing.T, s port.Storage) {
+	ctx := context.Background()
+	at := now()
+
+	old := store(t, s, record(at.Add(-2*time.Hour), "old"))
+	store(t, s, record(at.Add(-time.Hour), "in range"))
+
+	// deleted even if still waiting for delivery
+	pending := record(at.Add(-time.Hour), "pending")
+	deliverAt := at.Add(time.Hour)
+	pending.DeliverAt = &deliverAt
+	store(t, s, pending)
+
+	other := record(at.Add(-time.Hour), "other recipient")
+	other.Recipient = "other"
+	otherID := store(t, s, other)
+
+	deleted, err := s.DeleteNotifications(ctx, models.DeleteFilter{
+		Service:   service,
+		Recipient: "recipient",
+		Since:     at.Add(-90 * time.Minute),
+		Until:     at,
+	})
+	if err != nil {
+		t.Fatalf("DeleteNotifications: %v", err)
+	}
+
+	if deleted != 2 {
+		t.Errorf("DeleteNotifications deleted %d, want 2", deleted)
+	}
+
+	if got := ids(latest(t, s)); !slices.Equal(got, []string{otherID, old}) {
+		t.Errorf("notifications after delete = %v, want [%s %s]", got, otherID, old)
+	}
+}
+
+func testStats(t *testing.T, s port.Storage) {
+	ctx := context.Background()
+	at := now()
+
+	store(t, s, record(at.Add(-time.Hour), "unread"))
+	read := store(t, s, record(at, "read"))
+
+	scheduled := record(at.Add(-2*time.Hour), "scheduled")
+	deliverAt := at.Add(time.Hour)
+	scheduled.DeliverAt = &deliverAt
+	store(t, s, scheduled)
+
+	other := record(at, "other service")
+	other.Service = "other"
+	store(t, s, other)
+
+	if err := s.MarkNotificationAsRead(ctx, read); err != nil {
+		t.Fatalf("MarkNotificationAsRead: %v", err)
+	}
+
+	stats, err := s.NotificationStats(ctx)
+	if err != nil {
+		t.Fatalf("NotificationStats: %v", err)
+	}
+
+	if len(stats) != 2 || stats[0].Service != "other" || stats[1].Service != service {
+		t.Fatalf("NotificationStats = %+v, want other and %s", stats, service)
+	}
+
+	got := stats[1]
+	if got.Total != 3 || got.Unread != 1 {
+		t.Errorf("stats total %d and unread %d, want 3 and 1", got.Total, got.Unread)
+	}
+
+	if got.ByStatus[models.StatusDelivered] != 2 || got.ByStatus[models.StatusPending] != 1 {
+		t.Errorf("stats by status = %v, want 2 delivered and 1 pending", got.ByStatus)
+	}
+
+	if !got.Oldest.Equal(at.Add(-2*time.Hour)) || !got.Newest.Equal(at) {
+		t.Errorf("stats range = %s to %s, want %s to %s", got.Oldest, got.Newest, at.Add(-2*time.Hour), at)
+	}
+}
+
+func testExport(t *testing.T, s port.Storage) {
+	ctx := context.Background()
+	at := now()
+
+	store(t, s, record(at.Add(-3*time.Hour), "too old"))
+	first := store(t, s, record(at.Add(-2*time.Hour), "first"))
+
+	// exported whatever the status
+	scheduled := record(at.Add(-time.Hour), "scheduled")
+	deliverAt := at.Add(time.Hour)
+	scheduled.DeliverAt = &deliverAt
+	second := store(t, s, scheduled)
+
+	other := record(at.Add(-time.Hour), "other service")
+	other.Service = "other"
+	store(t, s, other)
+
+	var exported []string
+	err := s.ExportNotifications(ctx, models.ExportFilter{Service: service, Since: at.Add(-150 * time.Minute)},
+		func(n *models.Notification) error {
+			exported = append(exported, n.ID)
+			return nil
+		})
+	if err != nil {
+		t.Fatalf("ExportNotifications: %v", err)
+	}
+
+	if !slices.Equal(exported, []string{first, second}) {
+		t.Errorf("ExportNotifications = %v, want [%s %s] oldest first", exported, first, second)
+	}
+
+	stop := errors.New("stop")
+	calls := 0
+
+	err = s.ExportNotifications(ctx, models.ExportFilter{Service: service}, func(n *models.Notification) error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) || calls != 1 {
+		t.Errorf("ExportNotifications with a failing fn = %v after %d calls, want the error after 1", err, calls)
+	}
+}
+
+func testRestore(t *testing.T, s port.Storage) {
+	ctx := context.Background()
+	at := now()
+
+	id := store(t, s, record(at, "original"))
+
+	readAt := at.Add(time.Minute)
+	backup := []*models.Notification{
+		{
+			ID: id, Service: service, Recipient: "recipient", Message: "restored", SentAt: at, IsRead: true,
+			ReadAt: &readAt, Status: models.StatusDelivered, Priority: models.PriorityNormal, Count: 1,
+			Metadata: map[string]string{"tenant": "acme"},
+		},
+		{
+			ID: uuid.NewString(), Service: service, Recipient: "recipient", Message: "missing", SentAt: at.Add(-time.Hour),
+			Status: models.StatusDelivered, Priority: models.PriorityNormal, Count: 1,
+		},
+	}
+
+	// restoring twice changes nothing
+	for range 2 {
+		if err := s.RestoreNotifications(ctx, backup); err != nil {
+			t.Fatalf("RestoreNotifications: %v", err)
+		}
+	}
+
+	found := latest(t, s)
+	if got := ids(found); !slices.Equal(got, []string{id, backup[1].ID}) {
+		t.Fatalf("notifications after restore = %v, want [%s %s]", got, id, backup[1].ID)
+	}
+
+	restored := found[0]
+	if restored.Message != "restored" || !restored.IsRead || restored.ReadAt == nil ||
+		!restored.ReadAt.Equal(readAt) || restored.Metadata["tenant"] != "acme" {
+		t.Errorf("restored notification = %+v, want the backup", restored)
+	}
+}
+
+// TestCache checks the behavior of a port.Cache. newCache must return an empty cache on every call
+func TestCache(t *testing.T, newCache func(t *testing.T) port.Cache) {
+	ctx := context.Background()
+
+	t.Run("GetMissing", func(t *testing.T) {
+		c := newCache(t)
+
+		if _, err := c.Get(ctx, "missing"); !errors.Is(err, domain.ErrNotFound) {
+			t.Errorf("Get(missing) = %v, want domain.ErrNotFound", err)
+		}
+	})
+
+	t.Run("SetGetDelete", func(t *testing.T) {
+		c := newCache(t)
+
+		for _, value := range []string{"first", "second"} {
+			if err := c.Set(ctx, "key", []byte(value), time.Minute); err != nil {
+				t.Fatalf("Set: %v", err)
+			}
+
+			got, err := c.Get(ctx, "key")
+			if err != nil || string(got) != value {
+				t.Errorf("Get = %q, %v, want %q", got, err, value)
+			}
+		}
+
+		if err := c.Delete(ctx, "key"); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+
+		if _, err := c.Get(ctx, "key"); !errors.Is(err, domain.ErrNotFound) {
+			t.Errorf("Get after Delete = %v, want domain.ErrNotFound", err)
+		}
+
+		if err := c.Delete(ctx, "key"); err != nil {
+			t.Errorf("Delete(missing) = %v, want nil", err)
+		}
+	})
+
+	t.Run("Expiration", func(t *testing.T) {
+		c := newCache(t)
+
+		if err := c.Set(ctx, "key", []byte("value"), 100*time.Millisecond); err != nil {
+			t.Fatalf("Set: %v", err)
+		}
+
+		time.Sleep(200 * time.Millisecond)
+
+		if _, err := c.Get(ctx, "key"); !errors.Is(err, domain.ErrNotFound) {
+			t.Errorf("Get after the ttl = %v, want domain.ErrNotFound", err)
+		}
+	})
+}
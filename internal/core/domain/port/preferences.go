@@ -0,0 +1,16 @@
+package port
+
+import (
+	"context"
+
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain/models"
+)
+
+type PreferencesStorage interface {
+	// GetPreferences returns the recipient preferences or domain.ErrNotFound
+	GetPreferences(ctx context.Context, recipient string) (*models.Preferences, error)
+	// SavePreferences replaces the recipient preferences
+	SavePreferences(ctx context.Context, preferences *models.Preferences) error
+	// DeletePreferences resets the recipient to the defaults. returns domain.ErrNotFound if there was nothing to delete
+	DeletePreferences(ctx context.Context, recipient string) error
+}
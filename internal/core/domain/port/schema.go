@@ -0,0 +1,18 @@
+package port
+
+import (
+	"context"
+
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain/models"
+)
+
+// SchemaRegistry resolves the schemas of payloads in the schema registry wire format. schemas never change
+// once registered, so implementations may cache them forever
+type SchemaRegistry interface {
+	IsHealthy(ctx context.Context) error
+
+	// SchemaByID returns the schema or domain.ErrNotFound
+	SchemaByID(ctx context.Context, id int) (*models.Schema, error)
+	// SchemaByVersion returns a version of a subject or domain.ErrNotFound. used to resolve references
+	SchemaByVersion(ctx context.Context, subject string, version int) (*models.Schema, error)
+}
@@ -2,6 +2,7 @@ package port
 
 import (
 	"context"
+	"io"
 
 	"github.com/joseCarlosAndrade/notification-server/internal/core/domain/models"
 )
@@ -9,7 +10,53 @@ import (
 type Service interface {
 	// todo: define service operations that saves noticiation: tries cache first, then store it on mongo
 	
-	// SaveNewNotification generates an id, stores notification in db and in cache (if available)
-	SaveNewNotification(ctx context.Context, notification *models.NotificationRecord) error
+	// SaveNewNotification generates an id, renders the template (if any), stores notification in db and in cache (if available).
+	// returns the id of the stored notification, which is the existing one if it was collapsed. returns domain.ErrThrottled
+	// if the service exceeded its rate limit, wrapped in domain.ErrDeadLetter if it must be dead lettered
+	SaveNewNotification(ctx context.Context, notification *models.NotificationRecord) (string, error)
 
+	// ListNotifications returns a page of the visible notifications matching the filter, most recent first.
+	// filter.Service is required and the page is capped
+	ListNotifications(ctx context.Context, filter models.NotificationFilter) ([]*models.Notification, error)
+	// MarkNotificationAsRead marks the notification of service as read. returns domain.ErrNotFound if the
+	// notification does not exist or belongs to another service
+	MarkNotificationAsRead(ctx context.Context, service, notificationID string) error
+	// ArchiveNotification dismisses the notification of service from the recipient lists without deleting it.
+	// returns domain.ErrNotFound if the notification does not exist or belongs to another service
+	ArchiveNotification(ctx context.Context, service, notificationID string) error
+	// UnarchiveNotification restores an archived notification of service to the recipient lists. returns
+	// domain.ErrNotFound if the notification does not exist or belongs to another service
+	UnarchiveNotification(ctx context.Context, service, notificationID string) error
+	// DeleteNotification deletes the notification for good, archived or not. meant for admins only. returns
+	// domain.ErrNotFound if the notification does not exist
+	DeleteNotification(ctx context.Context, notificationID string) error
+	// Subscribe streams the notifications that become visible on this replica and match the filter, scheduled
+	// ones when this replica delivers them. digest notifications are not streamed. the returned func unsubscribes
+	// and closes the channel
+	Subscribe(ctx context.Context, filter models.SubscriptionFilter) (<-chan *models.Notification, func())
+	// PublishNotification streams a notification that became visible outside SaveNewNotification, like a
+	// scheduled one once it is delivered, to the subscribers
+	PublishNotification(ctx context.Context, notification *models.Notification)
+
+	// SaveTemplate validates the template, rendering it with its example variables, and stores it as a new version
+	SaveTemplate(ctx context.Context, template *models.Template) (*models.Template, error)
+	// GetTemplate returns a template version. if version is 0, returns the latest one
+	GetTemplate(ctx context.Context, templateID, locale string, version int) (*models.Template, error)
+	ListTemplateVersions(ctx context.Context, templateID string) ([]*models.Template, error)
+
+	// GetPreferences returns the recipient preferences or domain.ErrNotFound if they use the defaults
+	GetPreferences(ctx context.Context, recipient string) (*models.Preferences, error)
+	// SavePreferences validates and replaces the recipient preferences
+	SavePreferences(ctx context.Context, preferences *models.Preferences) (*models.Preferences, error)
+	DeletePreferences(ctx context.Context, recipient string) error
+
+	// ExportNotifications writes the notifications matching the filter to w in the given format, as they are
+	// read from the storage. filter.Service is required
+	ExportNotifications(ctx context.Context, filter models.ExportFilter, format models.ExportFormat, w io.Writer) error
+	// ImportNotifications restores an ndjson export read from r. notifications are replaced by id, so an
+	// interrupted import can be run again. invalid lines fail the import with domain.ErrInvalidArgument
+	ImportNotifications(ctx context.Context, r io.Reader) (models.ImportResult, error)
+
+	// GetThrottleStats returns the rate limiter counters per producing service
+	GetThrottleStats(ctx context.Context) []models.ThrottleStats
 }
\ No newline at end of file
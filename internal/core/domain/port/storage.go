@@ -2,6 +2,7 @@ package port
 
 import (
 	"context"
+	"time"
 
 	"github.com/joseCarlosAndrade/notification-server/internal/core/domain/models"
 )
@@ -10,9 +11,56 @@ type Storage interface {
 	Runner
 	IsHealthy(ctx context.Context) error 
 
-	StoreNewNotification(ctx context.Context, notification *models.NotificationRecord, id string) error 
+	// StoreNewNotification stores the notification under id and returns the id of the stored document, which is
+	// the existing one when the notification was collapsed into an unread one with the same collapse key. storing
+	// an id that was already stored or collapsed changes nothing, so replays keep the read and archived state
+	StoreNewNotification(ctx context.Context, notification *models.NotificationRecord, id string) (string, error)
+	// GetNotification returns the notification stored under id, whatever its status. returns domain.ErrNotFound if
+	// it does not exist
+	GetNotification(ctx context.Context, notificationID string) (*models.Notification, error)
 	MarkNotificationAsRead(ctx context.Context, notificationID string) error
 	GetAllNotificationsByTime(ctx context.Context, serviceName string, filter models.LastTime) ([]*models.Notification, error)
 	GetLatestNotifications(ctx context.Context, serviceName string, n int) ([]*models.Notification, error)
 	GetNonReadNotifications(ctx context.Context, serviceName string) ([]*models.Notification, error)
-}
\ No newline at end of file
+	// ListNotifications returns the visible notifications matching the filter, most recent first. notifications
+	// sent at the same time are ordered by id, so pages are stable
+	ListNotifications(ctx context.Context, filter models.NotificationFilter) ([]*models.Notification, error)
+
+	// ArchiveNotification hides the notification from the lists and marks it as read. archiving an archived
+	// notification keeps its archivedAt. returns domain.ErrNotFound if the notification does not exist
+	ArchiveNotification(ctx context.Context, notificationID string, at time.Time) error
+	// UnarchiveNotification lists the notification again. it stays read. returns domain.ErrNotFound if the
+	// notification does not exist
+	UnarchiveNotification(ctx context.Context, notificationID string) error
+	// DeleteNotification deletes the notification whatever its status. returns domain.ErrNotFound if it does not exist
+	DeleteNotification(ctx context.Context, notificationID string) error
+
+	// ClaimDueNotifications leases pending notifications whose deliverAt is before now to owner
+	ClaimDueNotifications(ctx context.Context, owner string, now time.Time, lease time.Duration, limit int) ([]*models.Notification, error)
+	// PromoteNotification marks a pending notification leased by owner as delivered. returns domain.ErrConflict if the lease was lost
+	PromoteNotification(ctx context.Context, notificationID, owner string, deliveredAt time.Time) error
+
+	// GetPendingDigestNotifications returns notifications accumulated by a digest rule sent before the given time
+	GetPendingDigestNotifications(ctx context.Context, rule string, before time.Time) ([]*models.Notification, error)
+	// StoreDigest stores the summary notification (idempotent on id) and marks the originals as digested into it
+	StoreDigest(ctx context.Context, digest *models.NotificationRecord, id string, originalIDs []string) error
+
+	// DeleteNotifications deletes every notification matching the filter, whatever its status. returns how many
+	// were deleted
+	DeleteNotifications(ctx context.Context, filter models.DeleteFilter) (int64, error)
+	// NotificationStats summarizes the notifications of every service, sorted by service
+	NotificationStats(ctx context.Context) ([]*models.ServiceStats, error)
+
+	// ExportNotifications calls fn with every notification matching the filter, oldest first, as they are read,
+	// so exports of any size don't have to fit in memory. it stops at the first error returned by fn
+	ExportNotifications(ctx context.Context, filter models.ExportFilter, fn func(*models.Notification) error) error
+	// RestoreNotifications stores the notifications as they are, replacing the ones with the same id, so
+	// restoring the same backup twice is a no-op
+	RestoreNotifications(ctx context.Context, notifications []*models.Notification) error
+}
+
+// IndexMigrator is implemented by the storages that rely on indexes
+type IndexMigrator interface {
+	// MigrateIndexes creates the missing indexes and, if dropStale is set, drops the ones no longer used
+	MigrateIndexes(ctx context.Context, dropStale bool) (models.IndexMigration, error)
+}
@@ -0,0 +1,17 @@
+package port
+
+import (
+	"context"
+
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain/models"
+)
+
+type TemplateStorage interface {
+	// StoreTemplate inserts a new template version. returns domain.ErrConflict if this version already exists
+	StoreTemplate(ctx context.Context, template *models.Template) error
+	// GetLatestTemplate returns the highest version of a template for the locale or domain.ErrNotFound
+	GetLatestTemplate(ctx context.Context, templateID, locale string) (*models.Template, error)
+	GetTemplateVersion(ctx context.Context, templateID, locale string, version int) (*models.Template, error)
+	// ListTemplateVersions returns every version of every locale of a template
+	ListTemplateVersions(ctx context.Context, templateID string) ([]*models.Template, error)
+}
@@ -0,0 +1,187 @@
+package eventsource
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/joseCarlosAndrade/notification-server/internal/core/config"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain/models"
+)
+
+// mapper converts the events of the mapped topics (config.TopicMappings) into notification records. the
+// mappings are compiled once per configuration, so a hot reload takes effect on the next message
+type mapper struct {
+	mu       sync.Mutex
+	settings *config.AppInfo // configuration the mappings were compiled from
+	mappings map[string]*mapping
+}
+
+// mapping is a compiled config.TopicMapping
+type mapping struct {
+	templates map[string]*template.Template // keyed by record field. empty fields have none
+	variables map[string]string
+}
+
+// lookup returns the mapping of the source, if it is mapped
+func (m *mapper) lookup(source string) (*mapping, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if settings := config.Live(); settings != m.settings {
+		m.compile(settings)
+	}
+
+	mapping, ok := m.mappings[source]
+
+	return mapping, ok
+}
+
+// compile parses every mapping. they were already parsed when the configuration was loaded, so they can't fail
+func (m *mapper) compile(settings *config.AppInfo) {
+	m.settings = settings
+	m.mappings = make(map[string]*mapping, len(settings.TopicMappings))
+
+	for topic, tm := range settings.TopicMappings {
+		compiled := &mapping{
+			templates: make(map[string]*template.Template),
+			variables: tm.Variables,
+		}
+
+		for field, text := range tm.Templates() {
+			if text == "" {
+				continue
+			}
+
+			compiled.templates[field] = template.Must(template.New(field).Option("missingkey=error").Parse(text))
+		}
+
+		m.mappings[topic] = compiled
+	}
+}
+
+// decode maps the event to a notification record and validates it like DecodeNotificationRecord does. errors
+// wrap domain.ErrInvalidArgument, since they are caused by the event
+func (m *mapping) decode(value []byte) (*models.NotificationRecord, error) {
+	decoder := json.NewDecoder(bytes.NewReader(value))
+	decoder.UseNumber() // keeps large ids as they were sent
+
+	var event map[string]any
+	if err := decoder.Decode(&event); err != nil {
+		return nil, fmt.Errorf("%w: could not parse event: %w", domain.ErrInvalidArgument, err)
+	}
+
+	fields := make(map[string]string, len(m.templates))
+	for field, tmpl := range m.templates {
+		var rendered strings.Builder
+		if err := tmpl.Execute(&rendered, event); err != nil {
+			return nil, fmt.Errorf("%w: could not map %s: %w", domain.ErrInvalidArgument, field, err)
+		}
+
+		fields[field] = rendered.String()
+	}
+
+	record := &models.NotificationRecord{
+		Service:     fields["service"],
+		Recipient:   fields["recipient"],
+		Title:       fields["title"],
+		Message:     fields["message"],
+		Category:    fields["category"],
+		Priority:    models.Priority(fields["priority"]),
+		TemplateID:  fields["templateId"],
+		Locale:      fields["locale"],
+		CollapseKey: fields["collapseKey"],
+	}
+
+	var err error
+	if record.SentAt, err = parseTime(fields["sentAt"], "sentAt"); err != nil {
+		return nil, err
+	}
+
+	if record.DeliverAt, err = parseTime(fields["deliverAt"], "deliverAt"); err != nil {
+		return nil, err
+	}
+
+	// missing variables are left unset, so templates can handle optional event fields
+	if len(m.variables) > 0 {
+		record.Variables = make(map[string]any, len(m.variables))
+
+		for variable, path := range m.variables {
+			if value, ok := lookupPath(event, path); ok {
+				record.Variables[variable] = normalizeNumber(value)
+			}
+		}
+	}
+
+	if err := record.Validate(); err != nil {
+		return nil, err
+	}
+
+	return record, nil
+}
+
+// parseTime returns nil for an empty value
+func parseTime(value, field string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s must be an RFC 3339 timestamp: %w", domain.ErrInvalidArgument, field, err)
+	}
+
+	return &t, nil
+}
+
+// lookupPath walks the dot separated path through objects and arrays (items.0.sku)
+func lookupPath(event map[string]any, path string) (any, bool) {
+	var current any = event
+
+	for _, key := range strings.Split(path, ".") {
+		switch node := current.(type) {
+		case map[string]any:
+			value, ok := node[key]
+			if !ok {
+				return nil, false
+			}
+
+			current = value
+		case []any:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(node) {
+				return nil, false
+			}
+
+			current = node[i]
+		default:
+			return nil, false
+		}
+	}
+
+	return current, true
+}
+
+// normalizeNumber turns json numbers into int64 when they are integers and float64 otherwise
+func normalizeNumber(value any) any {
+	number, ok := value.(json.Number)
+	if !ok {
+		return value
+	}
+
+	if i, err := number.Int64(); err == nil {
+		return i
+	}
+
+	if f, err := number.Float64(); err == nil {
+		return f
+	}
+
+	return number.String()
+}
@@ -0,0 +1,149 @@
+// Package eventsource processes the notifications received from any event source. adapters only translate
+// their messages into Message and ack them as Processor says, so every source validates, retries and dead
+// letters the same way
+package eventsource
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// event source systems, used on spans and logs
+const (
+	SystemKafka = "kafka"
+	SystemNATS  = "nats"
+	SystemAMQP  = "rabbitmq"
+	SystemFile  = "file"
+)
+
+type Header struct {
+	Key   string
+	Value []byte
+}
+
+// dead letter headers telling where the message was consumed from. named after kafka for compatibility
+const (
+	headerDeadLetterReason    = "dlq-reason"
+	headerDeadLetterTopic     = "dlq-source-topic"
+	headerDeadLetterPartition = "dlq-source-partition"
+	headerDeadLetterOffset    = "dlq-source-offset"
+)
+
+// Message is a record received from an event source
+type Message struct {
+	System    string // one of the System constants
+	Source    string // topic, subject, queue or file the message was read from
+	Partition int32  // 0 for sources without partitions
+	Position  string // offset, sequence, delivery tag or line. identifies the message on logs and dead letters
+
+	// Replayable is set when Source, Partition and Position identify the message for good, so redeliveries and
+	// replays store the notification with the same id. delivery tags and stdin lines don't
+	Replayable bool
+
+	Key     []byte
+	Value   []byte
+	Headers []Header
+}
+
+// correlationHeaders are the headers producers may use to send their correlation id, in order of precedence
+var correlationHeaders = []string{"x-request-id", "x-correlation-id", "correlation-id"}
+
+// Header returns the first non empty value of the header. keys are case insensitive
+func (m *Message) Header(key string) string {
+	for _, h := range m.Headers {
+		if strings.EqualFold(h.Key, key) && len(h.Value) > 0 {
+			return string(h.Value)
+		}
+	}
+
+	return ""
+}
+
+// CorrelationID returns the correlation id sent by the producer, if any
+func (m *Message) CorrelationID() string {
+	for _, key := range correlationHeaders {
+		if value := m.Header(key); value != "" {
+			return value
+		}
+	}
+
+	return ""
+}
+
+// headerCarrier adapts the message headers to propagation.TextMapCarrier, so producers can send the
+// trace context (traceparent, tracestate, baggage) in the headers
+type headerCarrier struct {
+	message *Message
+}
+
+var _ propagation.TextMapCarrier = headerCarrier{}
+
+// Get matches keys like Message.Header does, case insensitive
+func (c headerCarrier) Get(key string) string {
+	return c.message.Header(key)
+}
+
+func (c headerCarrier) Set(key, value string) {
+	for i, h := range c.message.Headers {
+		if strings.EqualFold(h.Key, key) {
+			c.message.Headers[i].Value = []byte(value)
+			return
+		}
+	}
+
+	c.message.Headers = append(c.message.Headers, Header{Key: key, Value: []byte(value)})
+}
+
+func (c headerCarrier) Keys() []string {
+	keys := make([]string, 0, len(c.message.Headers))
+	for _, h := range c.message.Headers {
+		keys = append(keys, h.Key)
+	}
+
+	return keys
+}
+
+// DeadLetterHeaders returns the message headers plus the failure reason and where the message came from
+func DeadLetterHeaders(m *Message, reason error) []Header {
+	headers := append([]Header{}, m.Headers...)
+
+	return append(headers,
+		Header{Key: headerDeadLetterReason, Value: []byte(reason.Error())},
+		Header{Key: headerDeadLetterTopic, Value: []byte(m.Source)},
+		Header{Key: headerDeadLetterPartition, Value: []byte(strconv.Itoa(int(m.Partition)))},
+		Header{Key: headerDeadLetterOffset, Value: []byte(m.Position)},
+	)
+}
+
+// Origin returns where the message was first consumed from. messages re-driven from a dead letter destination
+// return the source in their dead letter headers. when dead lettered more than once, the first headers win
+func (m *Message) Origin() (source string, partition int32, position string) {
+	source = m.Header(headerDeadLetterTopic)
+	if source == "" {
+		return m.Source, m.Partition, m.Position
+	}
+
+	p, _ := strconv.ParseInt(m.Header(headerDeadLetterPartition), 10, 32)
+
+	return source, int32(p), m.Header(headerDeadLetterOffset)
+}
+
+// DrainContext returns the context messages must be processed with. it is not canceled with ctx, so a
+// shutdown never aborts a write halfway, but it is canceled drainTimeout after ctx, which bounds the drain
+// of the messages already received. the returned func releases it
+func DrainContext(ctx context.Context, drainTimeout time.Duration) (context.Context, func()) {
+	processCtx, cancelProcess := context.WithCancel(context.WithoutCancel(ctx))
+
+	stopDrain := context.AfterFunc(ctx, func() {
+		time.AfterFunc(drainTimeout, cancelProcess)
+	})
+
+	return processCtx, func() {
+		stopDrain()
+		cancelProcess()
+	}
+}
@@ -0,0 +1,312 @@
+package eventsource
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/bufbuild/protocompile"
+	"github.com/hamba/avro/v2"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain/models"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain/port"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// wireHeaderSize is the magic byte plus the schema id of the schema registry wire format
+const wireHeaderSize = 5
+
+// payloadDecoder turns the payloads encoded with a registered schema into json, so they are validated and
+// mapped like any other. the decoder is picked by the content-type header, when sent, or by the magic byte
+// of the wire format. anything else is json
+type payloadDecoder struct {
+	registry port.SchemaRegistry // nil when no registry is configured
+
+	mu       sync.Mutex
+	avro     map[int]avro.Schema                 // parsed avro schemas by id
+	protobuf map[int]protoreflect.FileDescriptor // compiled protobuf schemas by id
+}
+
+func newPayloadDecoder(registry port.SchemaRegistry) *payloadDecoder {
+	return &payloadDecoder{
+		registry: registry,
+		avro:     make(map[int]avro.Schema),
+		protobuf: make(map[int]protoreflect.FileDescriptor),
+	}
+}
+
+// contentTypeFormat returns the schema type of a content-type header. empty if it is unknown or not sent
+func contentTypeFormat(contentType string) models.SchemaType {
+	contentType = strings.ToLower(contentType)
+
+	switch {
+	case strings.Contains(contentType, "avro"):
+		return models.SchemaAvro
+	case strings.Contains(contentType, "protobuf"):
+		return models.SchemaProtobuf
+	case strings.Contains(contentType, "json"):
+		return models.SchemaJSON
+	}
+
+	return ""
+}
+
+// toJSON returns the payload as json. errors caused by the payload wrap domain.ErrInvalidArgument, and the ones
+// of the registry may be transient
+func (d *payloadDecoder) toJSON(ctx context.Context, message *Message) ([]byte, error) {
+	value := message.Value
+	format := contentTypeFormat(message.Header("content-type"))
+	wire := len(value) >= wireHeaderSize && value[0] == 0
+
+	if format == models.SchemaJSON || (format == "" && !wire) {
+		return value, nil
+	}
+
+	if !wire {
+		return nil, fmt.Errorf("%w: %s payload is not in the schema registry wire format", domain.ErrInvalidArgument, format)
+	}
+
+	if d.registry == nil {
+		return nil, fmt.Errorf("%w: payload encoded with a registered schema but no schema registry is configured",
+			domain.ErrInvalidArgument)
+	}
+
+	id := int(binary.BigEndian.Uint32(value[1:wireHeaderSize]))
+
+	schema, err := d.registry.SchemaByID(ctx, id)
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil, fmt.Errorf("%w: unknown schema %d", domain.ErrInvalidArgument, id)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if format != "" && format != schema.Type {
+		return nil, fmt.Errorf("%w: content-type is %s but schema %d is %s", domain.ErrInvalidArgument, format, id, schema.Type)
+	}
+
+	data := value[wireHeaderSize:]
+
+	switch schema.Type {
+	case models.SchemaAvro:
+		return d.avroToJSON(ctx, schema, data)
+	case models.SchemaProtobuf:
+		return d.protobufToJSON(ctx, schema, data)
+	case models.SchemaJSON:
+		return data, nil // json schema payloads are plain json after the header
+	}
+
+	return nil, fmt.Errorf("%w: schema %d has unknown type %s", domain.ErrInvalidArgument, id, schema.Type)
+}
+
+func (d *payloadDecoder) avroToJSON(ctx context.Context, schema *models.Schema, data []byte) ([]byte, error) {
+	d.mu.Lock()
+	parsed, ok := d.avro[schema.ID]
+	d.mu.Unlock()
+
+	if !ok {
+		cache := &avro.SchemaCache{}
+		if err := d.parseAvroReferences(ctx, schema.References, cache, map[string]bool{}); err != nil {
+			return nil, err
+		}
+
+		var err error
+		if parsed, err = avro.ParseWithCache(schema.Text, "", cache); err != nil {
+			return nil, fmt.Errorf("%w: invalid avro schema %d: %w", domain.ErrInvalidArgument, schema.ID, err)
+		}
+
+		d.mu.Lock()
+		d.avro[schema.ID] = parsed
+		d.mu.Unlock()
+	}
+
+	// avro.Unmarshal takes truncated payloads for null values, the stream decoder reports them
+	var decoded any
+	if err := avro.NewDecoderForSchema(parsed, bytes.NewReader(data)).Decode(&decoded); err != nil {
+		return nil, fmt.Errorf("%w: could not decode avro payload: %w", domain.ErrInvalidArgument, err)
+	}
+
+	encoded, err := json.Marshal(decoded)
+	if err != nil {
+		return nil, fmt.Errorf("%w: could not encode avro payload as json: %w", domain.ErrInvalidArgument, err)
+	}
+
+	return encoded, nil
+}
+
+// parseAvroReferences adds the named types of the referenced schemas to cache, the deepest ones first
+func (d *payloadDecoder) parseAvroReferences(ctx context.Context, references []models.SchemaReference,
+	cache *avro.SchemaCache, seen map[string]bool) error {
+	for _, ref := range references {
+		if seen[ref.Name] {
+			continue
+		}
+
+		seen[ref.Name] = true
+
+		referenced, err := d.reference(ctx, ref)
+		if err != nil {
+			return err
+		}
+
+		if err := d.parseAvroReferences(ctx, referenced.References, cache, seen); err != nil {
+			return err
+		}
+
+		if _, err := avro.ParseWithCache(referenced.Text, "", cache); err != nil {
+			return fmt.Errorf("%w: invalid avro schema %s: %w", domain.ErrInvalidArgument, ref.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// protobufToJSON decodes the message selected by the message indexes that precede the data
+func (d *payloadDecoder) protobufToJSON(ctx context.Context, schema *models.Schema, data []byte) ([]byte, error) {
+	d.mu.Lock()
+	file, ok := d.protobuf[schema.ID]
+	d.mu.Unlock()
+
+	if !ok {
+		var err error
+		if file, err = d.compileProtobuf(ctx, schema); err != nil {
+			return nil, err
+		}
+
+		d.mu.Lock()
+		d.protobuf[schema.ID] = file
+		d.mu.Unlock()
+	}
+
+	indexes, data, err := readMessageIndexes(data)
+	if err != nil {
+		return nil, err
+	}
+
+	descriptor, err := messageDescriptor(file, indexes)
+	if err != nil {
+		return nil, err
+	}
+
+	message := dynamicpb.NewMessage(descriptor)
+	if err := proto.Unmarshal(data, message); err != nil {
+		return nil, fmt.Errorf("%w: could not decode protobuf payload: %w", domain.ErrInvalidArgument, err)
+	}
+
+	encoded, err := protojson.Marshal(message)
+	if err != nil {
+		return nil, fmt.Errorf("%w: could not encode protobuf payload as json: %w", domain.ErrInvalidArgument, err)
+	}
+
+	return encoded, nil
+}
+
+// compileProtobuf compiles the schema with every referenced file. the google/protobuf imports are built in
+func (d *payloadDecoder) compileProtobuf(ctx context.Context, schema *models.Schema) (protoreflect.FileDescriptor, error) {
+	name := "schema-" + strconv.Itoa(schema.ID) + ".proto"
+	sources := map[string]string{name: schema.Text}
+
+	if err := d.collectProtobufReferences(ctx, schema.References, sources); err != nil {
+		return nil, err
+	}
+
+	compiler := protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{
+			Accessor: protocompile.SourceAccessorFromMap(sources),
+		}),
+	}
+
+	files, err := compiler.Compile(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid protobuf schema %d: %w", domain.ErrInvalidArgument, schema.ID, err)
+	}
+
+	return files[0], nil
+}
+
+// collectProtobufReferences adds the source of every referenced file, keyed by its import path
+func (d *payloadDecoder) collectProtobufReferences(ctx context.Context, references []models.SchemaReference,
+	sources map[string]string) error {
+	for _, ref := range references {
+		if _, ok := sources[ref.Name]; ok {
+			continue
+		}
+
+		referenced, err := d.reference(ctx, ref)
+		if err != nil {
+			return err
+		}
+
+		sources[ref.Name] = referenced.Text
+
+		if err := d.collectProtobufReferences(ctx, referenced.References, sources); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (d *payloadDecoder) reference(ctx context.Context, ref models.SchemaReference) (*models.Schema, error) {
+	referenced, err := d.registry.SchemaByVersion(ctx, ref.Subject, ref.Version)
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil, fmt.Errorf("%w: unknown schema reference %s (%s version %d)", domain.ErrInvalidArgument,
+			ref.Name, ref.Subject, ref.Version)
+	}
+
+	return referenced, err
+}
+
+// readMessageIndexes reads the path of the message type in the schema file: a count followed by the index of
+// the message at each nesting level, as zigzag varints. a zero count is the first message of the file
+func readMessageIndexes(data []byte) ([]int, []byte, error) {
+	count, n := binary.Varint(data)
+	if n <= 0 || count < 0 || int(count) > len(data) {
+		return nil, nil, fmt.Errorf("%w: invalid protobuf message indexes", domain.ErrInvalidArgument)
+	}
+
+	data = data[n:]
+
+	if count == 0 {
+		return []int{0}, data, nil
+	}
+
+	indexes := make([]int, count)
+	for i := range indexes {
+		index, n := binary.Varint(data)
+		if n <= 0 || index < 0 {
+			return nil, nil, fmt.Errorf("%w: invalid protobuf message indexes", domain.ErrInvalidArgument)
+		}
+
+		indexes[i] = int(index)
+		data = data[n:]
+	}
+
+	return indexes, data, nil
+}
+
+func messageDescriptor(file protoreflect.FileDescriptor, indexes []int) (protoreflect.MessageDescriptor, error) {
+	messages := file.Messages()
+
+	var descriptor protoreflect.MessageDescriptor
+	for _, index := range indexes {
+		if index >= messages.Len() {
+			return nil, fmt.Errorf("%w: message index %v not in schema %s", domain.ErrInvalidArgument, indexes, file.Path())
+		}
+
+		descriptor = messages.Get(index)
+		messages = descriptor.Messages()
+	}
+
+	return descriptor, nil
+}
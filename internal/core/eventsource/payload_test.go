@@ -0,0 +1,316 @@
+package eventsource
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"maps"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/hamba/avro/v2"
+	"github.com/joseCarlosAndrade/notification-server/internal/adapter/schemaregistry"
+	"github.com/joseCarlosAndrade/notification-server/internal/adapter/schemaregistry/registrytest"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+const (
+	avroID = iota + 1
+	avroWithReferenceID
+	protobufID
+	jsonSchemaID
+)
+
+const (
+	avroSchema = `{"type":"record","name":"Notification","namespace":"test",
+		"fields":[{"name":"service","type":"string"},{"name":"message","type":"string"}]}`
+
+	avroAuthorSchema = `{"type":"record","name":"Author","namespace":"test","fields":[{"name":"name","type":"string"}]}`
+
+	avroWithReferenceSchema = `{"type":"record","name":"Signed","namespace":"test",
+		"fields":[{"name":"service","type":"string"},{"name":"author","type":"test.Author"}]}`
+
+	protobufSchema = `syntax = "proto3";
+package test;
+
+message Other {
+  string unused = 1;
+}
+
+message Notification {
+  string service = 1;
+  string message = 2;
+}`
+)
+
+// newRegistry serves the schemas used by the tests from a fake registry
+func newRegistry(t *testing.T) (*registrytest.Server, *schemaregistry.Registry) {
+	server := registrytest.NewServer(t)
+	server.Register(avroID, "avro-value", 1, registrytest.Schema{Schema: avroSchema})
+	server.Register(100, "author", 1, registrytest.Schema{Schema: avroAuthorSchema})
+	server.Register(avroWithReferenceID, "signed-value", 1, registrytest.Schema{
+		Schema:     avroWithReferenceSchema,
+		References: []registrytest.Reference{{Name: "test.Author", Subject: "author", Version: 1}},
+	})
+	server.Register(protobufID, "protobuf-value", 1, registrytest.Schema{Schema: protobufSchema, SchemaType: "PROTOBUF"})
+	server.Register(jsonSchemaID, "json-value", 1, registrytest.Schema{Schema: `{"type":"object"}`, SchemaType: "JSON"})
+
+	return server, schemaregistry.NewRegistry(context.Background(), server.URL, "", "", 200*time.Millisecond)
+}
+
+// wire prefixes data with the magic byte and the schema id
+func wire(id uint32, data []byte) []byte {
+	return append(binary.BigEndian.AppendUint32([]byte{0}, id), data...)
+}
+
+func avroPayload(t *testing.T, schema string, value map[string]any) []byte {
+	t.Helper()
+
+	cache := &avro.SchemaCache{}
+	if _, err := avro.ParseWithCache(avroAuthorSchema, "", cache); err != nil {
+		t.Fatalf("could not parse avro schema: %v", err)
+	}
+
+	parsed, err := avro.ParseWithCache(schema, "", cache)
+	if err != nil {
+		t.Fatalf("could not parse avro schema: %v", err)
+	}
+
+	data, err := avro.Marshal(parsed, value)
+	if err != nil {
+		t.Fatalf("could not encode avro payload: %v", err)
+	}
+
+	return data
+}
+
+// protobufPayload encodes the Notification message, the second of the schema, after its message indexes
+func protobufPayload(service, message string) []byte {
+	data := binary.AppendVarint(nil, 1) // one index
+	data = binary.AppendVarint(data, 1) // the second message
+
+	data = protowire.AppendTag(data, 1, protowire.BytesType)
+	data = protowire.AppendString(data, service)
+	data = protowire.AppendTag(data, 2, protowire.BytesType)
+
+	return protowire.AppendString(data, message)
+}
+
+func TestToJSON(t *testing.T) {
+	tests := []struct {
+		name        string
+		value       func(t *testing.T) []byte
+		contentType string
+		noRegistry  bool
+		want        map[string]any
+		wantInvalid bool // the payload is at fault and must be dead lettered
+	}{
+		{
+			name:  "PlainJSON",
+			value: func(t *testing.T) []byte { return []byte(`{"service":"svc","message":"hi"}`) },
+			want:  map[string]any{"service": "svc", "message": "hi"},
+		},
+		{
+			name:        "JSONContentTypeSkipsTheMagicByte",
+			value:       func(t *testing.T) []byte { return wire(avroID, []byte(`{}`)) },
+			contentType: "application/json",
+			wantInvalid: true, // decoded as json, which the header bytes are not. the registry is not called
+		},
+		{
+			name: "Avro",
+			value: func(t *testing.T) []byte {
+				return wire(avroID, avroPayload(t, avroSchema, map[string]any{"service": "svc", "message": "hi"}))
+			},
+			want: map[string]any{"service": "svc", "message": "hi"},
+		},
+		{
+			name: "AvroContentType",
+			value: func(t *testing.T) []byte {
+				return wire(avroID, avroPayload(t, avroSchema, map[string]any{"service": "svc", "message": "hi"}))
+			},
+			contentType: "application/vnd.apache.avro+binary",
+			want:        map[string]any{"service": "svc", "message": "hi"},
+		},
+		{
+			name: "AvroWithReference",
+			value: func(t *testing.T) []byte {
+				return wire(avroWithReferenceID, avroPayload(t, avroWithReferenceSchema, map[string]any{
+					"service": "svc",
+					"author":  map[string]any{"name": "ana"},
+				}))
+			},
+			want: map[string]any{"service": "svc", "author": map[string]any{"name": "ana"}},
+		},
+		{
+			name:  "Protobuf",
+			value: func(t *testing.T) []byte { return wire(protobufID, protobufPayload("svc", "hi")) },
+			want:  map[string]any{"service": "svc", "message": "hi"},
+		},
+		{
+			name:  "JSONSchema",
+			value: func(t *testing.T) []byte { return wire(jsonSchemaID, []byte(`{"service":"svc"}`)) },
+			want:  map[string]any{"service": "svc"},
+		},
+		{
+			name:        "AvroContentTypeWithoutWireFormat",
+			value:       func(t *testing.T) []byte { return []byte(`{"service":"svc"}`) },
+			contentType: "avro/binary",
+			wantInvalid: true,
+		},
+		{
+			name:        "ContentTypeDoesNotMatchTheSchema",
+			value:       func(t *testing.T) []byte { return wire(protobufID, protobufPayload("svc", "hi")) },
+			contentType: "application/x-avro",
+			wantInvalid: true,
+		},
+		{
+			name:        "UnknownSchema",
+			value:       func(t *testing.T) []byte { return wire(999, []byte{1, 2}) },
+			wantInvalid: true,
+		},
+		{
+			name:        "NoRegistry",
+			value:       func(t *testing.T) []byte { return wire(avroID, []byte{1, 2}) },
+			noRegistry:  true,
+			wantInvalid: true,
+		},
+		{
+			name:        "CorruptAvro",
+			value:       func(t *testing.T) []byte { return wire(avroID, []byte{0xff}) },
+			wantInvalid: true,
+		},
+		{
+			name:        "InvalidMessageIndexes",
+			value:       func(t *testing.T) []byte { return wire(protobufID, binary.AppendVarint(nil, 5)) },
+			wantInvalid: true,
+		},
+		{
+			name: "MessageIndexOutOfTheSchema",
+			value: func(t *testing.T) []byte {
+				return wire(protobufID, binary.AppendVarint(binary.AppendVarint(nil, 1), 7))
+			},
+			wantInvalid: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, registry := newRegistry(t)
+
+			decoder := newPayloadDecoder(registry)
+			if tt.noRegistry {
+				decoder = newPayloadDecoder(nil)
+			}
+
+			message := &Message{Value: tt.value(t)}
+			if tt.contentType != "" {
+				message.Headers = []Header{{Key: "Content-Type", Value: []byte(tt.contentType)}}
+			}
+
+			value, err := decoder.toJSON(context.Background(), message)
+
+			if tt.wantInvalid {
+				if err == nil {
+					// json payloads are only rejected when they are parsed
+					err = json.Unmarshal(value, &map[string]any{})
+				} else if !errors.Is(err, domain.ErrInvalidArgument) {
+					t.Errorf("toJSON = %v, want domain.ErrInvalidArgument", err)
+				}
+
+				if err == nil {
+					t.Errorf("toJSON = %s, want an error", value)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("toJSON: %v", err)
+			}
+
+			var got map[string]any
+			if err := json.Unmarshal(value, &got); err != nil {
+				t.Fatalf("toJSON returned invalid json %s: %v", value, err)
+			}
+
+			if !equalJSON(got, tt.want) {
+				t.Errorf("toJSON = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func equalJSON(a, b map[string]any) bool {
+	return maps.EqualFunc(a, b, func(x, y any) bool {
+		xm, xok := x.(map[string]any)
+		ym, yok := y.(map[string]any)
+
+		if xok || yok {
+			return xok && yok && equalJSON(xm, ym)
+		}
+
+		return x == y
+	})
+}
+
+func TestSchemasAreResolvedOnce(t *testing.T) {
+	server, registry := newRegistry(t)
+	decoder := newPayloadDecoder(registry)
+
+	payloads := [][]byte{
+		wire(protobufID, protobufPayload("svc", "first")),
+		wire(protobufID, protobufPayload("svc", "second")),
+		wire(avroWithReferenceID, avroPayload(t, avroWithReferenceSchema, map[string]any{
+			"service": "svc",
+			"author":  map[string]any{"name": "ana"},
+		})),
+		wire(avroWithReferenceID, avroPayload(t, avroWithReferenceSchema, map[string]any{
+			"service": "svc",
+			"author":  map[string]any{"name": "bia"},
+		})),
+	}
+
+	for _, payload := range payloads {
+		if _, err := decoder.toJSON(context.Background(), &Message{Value: payload}); err != nil {
+			t.Fatalf("toJSON: %v", err)
+		}
+	}
+
+	// one call per schema id plus one for the reference
+	if got := server.Requests(); got != 3 {
+		t.Errorf("registry got %d requests, want 3", got)
+	}
+}
+
+// registry failures may be transient, so they must not be taken for invalid payloads
+func TestRegistryFailuresAreRetryable(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(s *registrytest.Server)
+	}{
+		{"ServerError", func(s *registrytest.Server) { s.Status = http.StatusInternalServerError }},
+		{"Timeout", func(s *registrytest.Server) { s.Delay = time.Second }},
+		{"Unauthorized", func(s *registrytest.Server) { s.Username, s.Password = "user", "secret" }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, registry := newRegistry(t)
+			tt.setup(server)
+
+			_, err := newPayloadDecoder(registry).toJSON(context.Background(), &Message{
+				Value: wire(avroID, []byte{1, 2}),
+			})
+			if err == nil {
+				t.Fatal("toJSON succeeded, want an error")
+			}
+
+			if !isRetryable(err) {
+				t.Errorf("toJSON = %v, want a retryable error", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,341 @@
+package eventsource
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/config"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain"
+	log "github.com/joseCarlosAndrade/notification-server/internal/core/domain/logger"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain/metrics"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain/models"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain/port"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+var tracer = otel.Tracer(config.AppTraceName)
+
+// idNamespace derives the notification ids from the message positions
+var idNamespace = uuid.MustParse("2b5f0c1e-8d4a-4f7b-9e36-0a8c4d1f7e52")
+
+// DeadLetterFunc sends a message that could not be processed to the dead letter destination of the source
+type DeadLetterFunc func(ctx context.Context, message *Message, reason error) error
+
+// Processor stores the notifications of the messages received by the event sources
+type Processor struct {
+	service  *port.Service
+	attempts int           // how many times a notification is saved before giving up
+	backoff  time.Duration // wait before the first retry. doubled on every retry
+
+	payloads *payloadDecoder // avro and protobuf payloads
+	mapper   mapper          // events of the mapped topics
+}
+
+// NewProcessor creates the processor. registry may be nil, in which case only json payloads are accepted
+func NewProcessor(ctx context.Context, serviceRepository *port.Service, registry port.SchemaRegistry, attempts int,
+	backoff time.Duration) *Processor {
+	return &Processor{
+		service:  serviceRepository,
+		attempts: max(attempts, 1),
+		backoff:  backoff,
+		payloads: newPayloadDecoder(registry),
+	}
+}
+
+// Process decodes and saves the notification of the message. failures that retrying won't fix (invalid
+// payloads, templates that can't be rendered, notifications dead lettered by the rate limiter) are sent to
+// deadLetter right away, and the others after every attempt failed. deadLetter may be nil when the source has
+// no dead letter destination, in which case those messages are discarded.
+//
+// returns nil when the source is done with the message: it was stored, dropped by the rate limiter or dead
+// lettered. returns an error when the message must be delivered again: ctx was canceled before it was
+// stored or it could not be dead lettered
+func (p *Processor) Process(ctx context.Context, message *Message, deadLetter DeadLetterFunc) error {
+	start := time.Now()
+
+	ctx = otel.GetTextMapPropagator().Extract(ctx, headerCarrier{message: message})
+
+	attributes := []attribute.KeyValue{
+		semconv.MessagingSystemKey.String(message.System),
+		semconv.MessagingDestinationName(message.Source),
+		attribute.String("messaging.message.position", message.Position),
+	}
+
+	switch message.System {
+	case SystemKafka:
+		attributes = append(attributes, semconv.MessagingDestinationPartitionID(strconv.Itoa(int(message.Partition))))
+		if len(message.Key) > 0 {
+			attributes = append(attributes, semconv.MessagingKafkaMessageKey(string(message.Key)))
+		}
+	case SystemAMQP:
+		attributes = append(attributes, semconv.MessagingRabbitMQDestinationRoutingKey(string(message.Key)))
+	}
+
+	if producer := message.Header(config.ProducerIDHeader); producer != "" {
+		attributes = append(attributes, attribute.String("messaging.producer.id", producer))
+	}
+
+	ctx, span := tracer.Start(ctx, "process "+message.Source,
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(attributes...))
+
+	// loads the producer correlation id, the message position and key into the logger, next to the span trace id
+	fields := []zap.Field{
+		zap.String("source", message.Source),
+		zap.Int32("partition", message.Partition),
+		zap.String("position", message.Position),
+	}
+
+	if len(message.Key) > 0 {
+		fields = append(fields, zap.ByteString("key", message.Key))
+	}
+
+	ctx = log.InitResources(ctx, message.CorrelationID(), fields...)
+
+	var failure error
+	defer func() {
+		if failure != nil {
+			span.RecordError(failure)
+			span.SetStatus(codes.Error, failure.Error())
+		}
+
+		span.End()
+
+		metrics.R().ObserveProcessing(message.Source, time.Since(start))
+		metrics.R().RecordConsumed(message.Source, message.Partition, failure)
+	}()
+
+	log.L(ctx).Debug("processing message",
+		log.Body("key", message.Key),
+		log.Body("value", message.Value))
+
+	failure = p.save(ctx, message)
+	if failure == nil {
+		return nil
+	}
+
+	log.L(ctx).Error("error processing message", zap.Error(failure))
+
+	// the rate limiter drop policy discards the notification on purpose
+	if errors.Is(failure, domain.ErrThrottled) && !errors.Is(failure, domain.ErrDeadLetter) {
+		return nil
+	}
+
+	// the drain timeout was exceeded. the message will be delivered again
+	if ctx.Err() != nil {
+		return fmt.Errorf("message left unprocessed: %w", failure)
+	}
+
+	if deadLetter == nil {
+		log.L(ctx).Warn("dead letter not configured. discarding message")
+		return nil
+	}
+
+	if err := deadLetter(ctx, message, failure); err != nil {
+		log.L(ctx).Error("could not dead letter message", zap.Error(err))
+		return fmt.Errorf("could not dead letter message: %w", err)
+	}
+
+	log.L(ctx).Info("message sent to dead letter")
+
+	return nil
+}
+
+// save decodes and saves the notification, retrying the failures that may be transient
+func (p *Processor) save(ctx context.Context, message *Message) (err error) {
+	// handle panic
+	defer func() {
+		if r := recover(); r != nil {
+			log.L(ctx).Error("PANIC DETECTED. recovering", zap.Any("recover", r))
+			err = fmt.Errorf("%w: panic processing message: %v", domain.ErrDeadLetter, r)
+		}
+	}()
+
+	var notification *models.NotificationRecord
+
+	// the schema registry may be briefly unavailable, so decoding is retried too
+	err = p.retry(ctx, "could not decode message", func() error {
+		validateCtx, validateSpan := tracer.Start(ctx, "validatePayload")
+		defer validateSpan.End()
+
+		var err error
+		if notification, err = p.decode(validateCtx, message); err != nil {
+			validateSpan.RecordError(err)
+			validateSpan.SetStatus(codes.Error, err.Error())
+		}
+
+		return err
+	})
+
+	if err != nil {
+		if !isRetryable(err) {
+			// retrying an invalid payload will never work
+			return fmt.Errorf("%w: could not decode message: %w", domain.ErrDeadLetter, err)
+		}
+
+		return fmt.Errorf("could not decode message: %w", err)
+	}
+
+	ctx = log.WithFields(ctx, zap.String("service", notification.Service))
+
+	err = p.retry(ctx, "could not save notification", func() error {
+		_, err := (*p.service).SaveNewNotification(ctx, notification)
+		return err
+	})
+
+	if err != nil {
+		return fmt.Errorf("error saving notification: %w", err)
+	}
+
+	return nil
+}
+
+// retry calls fn until it succeeds, fails with an error that is not retryable or every attempt is used. the
+// wait between attempts starts at the backoff and doubles every time
+func (p *Processor) retry(ctx context.Context, message string, fn func() error) error {
+	backoff := p.backoff
+
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || !isRetryable(err) || attempt >= p.attempts {
+			return err
+		}
+
+		log.L(ctx).Warn(message+". retrying",
+			zap.Int("attempt", attempt),
+			zap.Duration("backoff", backoff),
+			zap.Error(err))
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+	}
+}
+
+// Check decodes and validates the message without storing it, as Process would before saving. used by dry runs
+func (p *Processor) Check(ctx context.Context, message *Message) error {
+	_, err := p.decode(ctx, message)
+	return err
+}
+
+// decode turns the payload into json and maps the events of the mapped sources. every other message must be a
+// notification record. re-driven dead letters are mapped as the source they were first consumed from
+func (p *Processor) decode(ctx context.Context, message *Message) (*models.NotificationRecord, error) {
+	value, err := p.payloads.toJSON(ctx, message)
+	if err != nil {
+		return nil, err
+	}
+
+	var record *models.NotificationRecord
+
+	source, partition, position := message.Origin()
+
+	if mapping, ok := p.mapper.lookup(source); ok {
+		record, err = mapping.decode(value)
+	} else {
+		record, err = models.DecodeNotificationRecord(value, "")
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := authorizeProducer(message, record); err != nil {
+		return nil, err
+	}
+
+	record.Metadata = metadata(message)
+
+	if message.Replayable {
+		record.ID = uuid.NewSHA1(idNamespace, fmt.Appendf(nil, "%s|%s|%d|%s", message.System, source, partition,
+			position)).String()
+	}
+
+	return record, nil
+}
+
+// authorizeProducer checks the service of the record against the ones allowed for its producer. once
+// ProducerServices is set, every record needs the producer-id header signed with the producer key, so the id
+// is bound to something the producer holds. file records are read from local files and are not checked. errors
+// wrap domain.ErrForbidden
+func authorizeProducer(message *Message, record *models.NotificationRecord) error {
+	settings := config.Live()
+
+	// nothing to check the producer against
+	if len(settings.ProducerServices) == 0 || message.System == SystemFile {
+		return nil
+	}
+
+	producer := message.Header(config.ProducerIDHeader)
+	if producer == "" {
+		return fmt.Errorf("%w: %s header is required", domain.ErrForbidden, config.ProducerIDHeader)
+	}
+
+	if _, ok := settings.ProducerServices[producer]; !ok {
+		return fmt.Errorf("%w: unknown producer %q", domain.ErrForbidden, producer)
+	}
+
+	if !settings.ProducerKeys.Verify(producer, message.Value, message.Header(config.ProducerSignatureHeader)) {
+		return fmt.Errorf("%w: invalid %s header for producer %q", domain.ErrForbidden,
+			config.ProducerSignatureHeader, producer)
+	}
+
+	if !settings.ProducerServices.Allows(producer, record.Service) {
+		return fmt.Errorf("%w: producer %q cannot send notifications of service %q", domain.ErrForbidden, producer,
+			record.Service)
+	}
+
+	return nil
+}
+
+// metadata returns the whitelisted headers of the message, keyed as configured. nil if there are none
+func metadata(message *Message) map[string]string {
+	var found map[string]string
+
+	for _, header := range config.Live().MetadataHeaders {
+		value := message.Header(header)
+		if value == "" {
+			continue
+		}
+
+		if found == nil {
+			found = make(map[string]string)
+		}
+
+		found[header] = value
+	}
+
+	return found
+}
+
+// isRetryable reports if saving again may work: errors caused by the notification itself never go away
+func isRetryable(err error) bool {
+	for _, permanent := range []error{
+		domain.ErrDeadLetter,
+		domain.ErrThrottled,
+		domain.ErrInvalidArgument,
+		domain.ErrInvalidTemplate,
+		domain.ErrTemplateRender,
+		domain.ErrNotFound,
+		domain.ErrForbidden,
+	} {
+		if errors.Is(err, permanent) {
+			return false
+		}
+	}
+
+	return true
+}
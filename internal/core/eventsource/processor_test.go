@@ -0,0 +1,120 @@
+package eventsource
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/joseCarlosAndrade/notification-server/internal/core/config"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain/models"
+)
+
+func sign(key string, value []byte) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write(value)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestAuthorizeProducer(t *testing.T) {
+	config.Reload(config.AppInfo{
+		ProducerServices: config.ProducerServices{"checkout": {"shop"}},
+		ProducerKeys:     config.ProducerKeys{"checkout": "secret", "other": "other-secret"},
+	})
+	t.Cleanup(func() { config.Reload(config.App) })
+
+	value := []byte(`{"service":"shop"}`)
+
+	tests := []struct {
+		name      string
+		system    string
+		headers   []Header
+		service   string
+		forbidden bool
+	}{
+		{
+			name:    "Signed",
+			headers: []Header{{"producer-id", []byte("checkout")}, {"producer-signature", []byte(sign("secret", value))}},
+			service: "shop",
+		},
+		{
+			name:    "HeadersAreCaseInsensitive",
+			headers: []Header{{"Producer-ID", []byte("checkout")}, {"PRODUCER-SIGNATURE", []byte(sign("secret", value))}},
+			service: "shop",
+		},
+		{
+			name:      "MissingProducer",
+			service:   "shop",
+			forbidden: true,
+		},
+		{
+			name:      "MissingSignature",
+			headers:   []Header{{"producer-id", []byte("checkout")}},
+			service:   "shop",
+			forbidden: true,
+		},
+		{
+			name:      "SignedWithAnotherKey",
+			headers:   []Header{{"producer-id", []byte("checkout")}, {"producer-signature", []byte(sign("other-secret", value))}},
+			service:   "shop",
+			forbidden: true,
+		},
+		{
+			name:      "SignatureOfAnotherValue",
+			headers:   []Header{{"producer-id", []byte("checkout")}, {"producer-signature", []byte(sign("secret", []byte("{}")))}},
+			service:   "shop",
+			forbidden: true,
+		},
+		{
+			name:      "UnknownProducer",
+			headers:   []Header{{"producer-id", []byte("other")}, {"producer-signature", []byte(sign("other-secret", value))}},
+			service:   "shop",
+			forbidden: true,
+		},
+		{
+			name:      "ServiceOfAnotherProducer",
+			headers:   []Header{{"producer-id", []byte("checkout")}, {"producer-signature", []byte(sign("secret", value))}},
+			service:   "billing",
+			forbidden: true,
+		},
+		{
+			name:    "FilesAreNotChecked",
+			system:  SystemFile,
+			service: "billing",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			system := tt.system
+			if system == "" {
+				system = SystemKafka
+			}
+
+			message := &Message{System: system, Value: value, Headers: tt.headers}
+
+			err := authorizeProducer(message, &models.NotificationRecord{Service: tt.service})
+			if tt.forbidden != errors.Is(err, domain.ErrForbidden) || (!tt.forbidden && err != nil) {
+				t.Errorf("authorizeProducer = %v, want forbidden %v", err, tt.forbidden)
+			}
+		})
+	}
+}
+
+func TestHeaderCarrierIsCaseInsensitive(t *testing.T) {
+	message := &Message{Headers: []Header{{Key: "Traceparent", Value: []byte("a")}}}
+	carrier := headerCarrier{message: message}
+
+	if got := carrier.Get("traceparent"); got != "a" {
+		t.Errorf("Get = %q, want %q", got, "a")
+	}
+
+	carrier.Set("TRACEPARENT", "b")
+
+	if len(message.Headers) != 1 || carrier.Get("traceparent") != "b" {
+		t.Errorf("Set added or missed the header: %+v", message.Headers)
+	}
+}
@@ -0,0 +1,59 @@
+package eventsource
+
+import (
+	"context"
+	"sync"
+)
+
+// RunState lets the Close of a source wait for its Run to drain, but only if Run was started: when the startup
+// fails before that, nothing will drain and Close must not wait for it
+type RunState struct {
+	mu      sync.Mutex
+	started bool
+	closed  bool
+	stopped chan struct{} // closed when Run returns
+}
+
+func NewRunState() *RunState {
+	return &RunState{stopped: make(chan struct{})}
+}
+
+// Start must be called by Run before anything else. it reports false when the source was already closed, in
+// which case Run must return right away without calling Stop
+func (s *RunState) Start() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return false
+	}
+
+	s.started = true
+
+	return true
+}
+
+// Stop must be deferred by Run once Start returned true
+func (s *RunState) Stop() {
+	close(s.stopped)
+}
+
+// Wait waits for Run to return if it was started. it reports false if ctx expired first. Run can't start once
+// Wait was called
+func (s *RunState) Wait(ctx context.Context) bool {
+	s.mu.Lock()
+	s.closed = true
+	started := s.started
+	s.mu.Unlock()
+
+	if !started {
+		return true
+	}
+
+	select {
+	case <-s.stopped:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
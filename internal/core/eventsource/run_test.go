@@ -0,0 +1,44 @@
+package eventsource
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRunStateWait(t *testing.T) {
+	t.Run("NeverStarted", func(t *testing.T) {
+		s := NewRunState()
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		if !s.Wait(ctx) {
+			t.Error("Wait waited for a Run that never started")
+		}
+
+		if s.Start() {
+			t.Error("Run started after Close")
+		}
+	})
+
+	t.Run("Started", func(t *testing.T) {
+		s := NewRunState()
+		if !s.Start() {
+			t.Fatal("Start = false before Close")
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		if s.Wait(ctx) {
+			t.Error("Wait returned before Run stopped")
+		}
+
+		s.Stop()
+
+		if !s.Wait(context.Background()) {
+			t.Error("Wait = false after Run stopped")
+		}
+	})
+}
@@ -0,0 +1,244 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain"
+	log "github.com/joseCarlosAndrade/notification-server/internal/core/domain/logger"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain/metrics"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain/models"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain/port"
+	"go.uber.org/zap"
+)
+
+// Check probes a dependency
+type Check func(ctx context.Context) error
+
+type dependency struct {
+	name     string
+	check    Check
+	critical bool
+
+	status              models.HealthStatus
+	consecutiveFailures int
+	lastError           string
+	lastCheck           *time.Time
+}
+
+// Monitor probes every registered dependency periodically. a dependency is only considered down after
+// threshold consecutive failures, and a failing dependency never stops the application: critical ones make
+// it not ready, the others make it degraded. it implements port.Runner and port.Health
+type Monitor struct {
+	mu           sync.RWMutex
+	dependencies map[string]*dependency
+	lastRound    time.Time
+
+	interval  time.Duration
+	timeout   time.Duration
+	threshold int
+
+	done chan struct{}
+}
+
+var _ port.Runner = (*Monitor)(nil)
+var _ port.Health = (*Monitor)(nil)
+
+func NewMonitor(ctx context.Context, interval, timeout time.Duration, threshold int) *Monitor {
+	return &Monitor{
+		dependencies: make(map[string]*dependency),
+		interval:     interval,
+		timeout:      timeout,
+		threshold:    max(threshold, 1),
+		done:         make(chan struct{}),
+	}
+}
+
+// Register adds a dependency. dependencies start up until the first probes say otherwise
+func (m *Monitor) Register(name string, check Check, critical bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.dependencies[name] = &dependency{
+		name:     name,
+		check:    check,
+		critical: critical,
+		status:   models.HealthUp,
+	}
+}
+
+// Run implements port.Runner interface
+func (m *Monitor) Run(ctx context.Context) error {
+	log.L(ctx).Info("starting healthcheck",
+		zap.Duration("interval", m.interval),
+		zap.Int("threshold", m.threshold))
+
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		m.probe(ctx)
+
+		select {
+		case <-ctx.Done():
+			log.L(ctx).Warn("context canceled. exiting healthcheck")
+			return nil
+		case <-m.done:
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Close implements port.Runner interface
+func (m *Monitor) Close(ctx context.Context) error {
+	select {
+	case <-m.done:
+	default:
+		close(m.done)
+	}
+
+	return nil
+}
+
+// Probe checks every dependency once and returns the readiness. meant for the tools, which don't Run the monitor
+func (m *Monitor) Probe(ctx context.Context) models.HealthReport {
+	m.probe(ctx)
+
+	return m.Readiness(ctx)
+}
+
+// probe checks every dependency concurrently, so one hanging probe doesn't delay the others
+func (m *Monitor) probe(ctx context.Context) {
+	m.mu.RLock()
+	dependencies := make([]*dependency, 0, len(m.dependencies))
+	for _, d := range m.dependencies {
+		dependencies = append(dependencies, d)
+	}
+	m.mu.RUnlock()
+
+	var wg sync.WaitGroup
+
+	for _, d := range dependencies {
+		wg.Go(func() {
+			ctxTimeout, cancel := context.WithTimeout(ctx, m.timeout)
+			defer cancel()
+
+			err := d.check(ctxTimeout)
+			m.record(ctx, d, err)
+		})
+	}
+
+	wg.Wait()
+
+	m.mu.Lock()
+	m.lastRound = domain.NewNowTime()
+	m.mu.Unlock()
+}
+
+func (m *Monitor) record(ctx context.Context, d *dependency, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := domain.NewNowTime()
+	d.lastCheck = &now
+	previous := d.status
+
+	if err == nil {
+		d.consecutiveFailures = 0
+		d.lastError = ""
+		d.status = models.HealthUp
+	} else {
+		d.consecutiveFailures++
+		d.lastError = err.Error()
+
+		if d.consecutiveFailures >= m.threshold {
+			d.status = models.HealthDown
+		}
+	}
+
+	metrics.R().SetHealth(d.name, d.status == models.HealthUp)
+
+	if previous == d.status {
+		if err != nil {
+			log.L(ctx).Warn("health probe failed",
+				zap.String("name", d.name),
+				zap.Int("consecutiveFailures", d.consecutiveFailures),
+				zap.Error(err))
+		}
+
+		return
+	}
+
+	if d.status == models.HealthDown {
+		log.L(ctx).Error("dependency is down",
+			zap.String("name", d.name),
+			zap.Bool("critical", d.critical),
+			zap.Error(err))
+
+		return
+	}
+
+	log.L(ctx).Info("dependency recovered", zap.String("name", d.name))
+}
+
+// IsUp implements port.Health interface
+func (m *Monitor) IsUp(name string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	d, ok := m.dependencies[name]
+
+	return ok && d.status == models.HealthUp
+}
+
+// Liveness implements port.Health interface. the application is alive while the probes keep running
+func (m *Monitor) Liveness(ctx context.Context) models.HealthReport {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	now := domain.NewNowTime()
+	report := models.HealthReport{Status: models.HealthUp, CheckedAt: now}
+
+	// probes stuck for several rounds mean the process is wedged
+	if !m.lastRound.IsZero() && now.Sub(m.lastRound) > 3*(m.interval+m.timeout) {
+		report.Status = models.HealthDown
+	}
+
+	return report
+}
+
+// Readiness implements port.Health interface
+func (m *Monitor) Readiness(ctx context.Context) models.HealthReport {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	report := models.HealthReport{
+		Status:       models.HealthUp,
+		Dependencies: make(map[string]models.DependencyHealth, len(m.dependencies)),
+		CheckedAt:    m.lastRound,
+	}
+
+	for name, d := range m.dependencies {
+		report.Dependencies[name] = models.DependencyHealth{
+			Status:              d.status,
+			Critical:            d.critical,
+			ConsecutiveFailures: d.consecutiveFailures,
+			LastError:           d.lastError,
+			LastCheck:           d.lastCheck,
+		}
+
+		if d.status != models.HealthDown {
+			continue
+		}
+
+		if d.critical {
+			report.Status = models.HealthDown
+		} else if report.Status == models.HealthUp {
+			report.Status = models.HealthDegraded
+		}
+	}
+
+	return report
+}
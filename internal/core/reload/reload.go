@@ -0,0 +1,138 @@
+package reload
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/joseCarlosAndrade/notification-server/internal/core/config"
+	log "github.com/joseCarlosAndrade/notification-server/internal/core/domain/logger"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain/port"
+	"go.uber.org/zap"
+)
+
+// Watcher reloads the configuration on SIGHUP or when the config file changes. it implements port.Runner.
+// only the hot reloadable settings are applied (see config.Reload): nothing is restarted, and an invalid
+// configuration is logged and ignored
+type Watcher struct {
+	path         string // config file. empty when only env vars are used
+	pollInterval time.Duration
+	modTime      time.Time
+
+	done chan struct{}
+}
+
+var _ port.Runner = (*Watcher)(nil)
+
+func NewWatcher(ctx context.Context, path string, pollInterval time.Duration) Watcher {
+	w := Watcher{
+		path:         path,
+		pollInterval: pollInterval,
+		done:         make(chan struct{}),
+	}
+
+	w.modTime, _ = w.fileModTime()
+
+	return w
+}
+
+// Run implements port.Runner interface
+func (w *Watcher) Run(ctx context.Context) error {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	defer signal.Stop(hangup)
+
+	log.L(ctx).Info("watching configuration", zap.String("file", w.path))
+
+	ticker := time.NewTicker(w.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.L(ctx).Warn("context canceled. exiting configuration watcher")
+			return nil
+		case <-w.done:
+			return nil
+		case <-hangup:
+			log.L(ctx).Info("sighup received. reloading configuration")
+			w.reload(ctx)
+		case <-ticker.C:
+			if w.fileChanged(ctx) {
+				log.L(ctx).Info("config file changed. reloading configuration", zap.String("file", w.path))
+				w.reload(ctx)
+			}
+		}
+	}
+}
+
+// Close implements port.Runner interface
+func (w *Watcher) Close(ctx context.Context) error {
+	select {
+	case <-w.done:
+	default:
+		close(w.done)
+	}
+
+	return nil
+}
+
+func (w *Watcher) reload(ctx context.Context) {
+	next, err := config.Load()
+	if err != nil {
+		log.L(ctx).Error("could not reload configuration. keeping the current one", zap.Error(err))
+		return
+	}
+
+	restart := config.Reload(next)
+
+	if err := log.SetLevel(config.Live().LogLevel); err != nil {
+		log.L(ctx).Error("could not apply log level", zap.Error(err))
+	}
+
+	if err := log.SetComponentLevels(config.Live().LogComponentLevels); err != nil {
+		log.L(ctx).Error("could not apply component log levels", zap.Error(err))
+	}
+
+	if len(restart) > 0 {
+		log.L(ctx).Warn("some settings changed but only take effect after a restart", zap.Strings("settings", restart))
+	}
+
+	log.L(ctx).Info("configuration reloaded")
+}
+
+// fileChanged compares the modification time of the config file with the last one seen
+func (w *Watcher) fileChanged(ctx context.Context) bool {
+	if w.path == "" {
+		return false
+	}
+
+	modTime, err := w.fileModTime()
+	if err != nil {
+		log.L(ctx).Warn("could not check config file", zap.String("file", w.path), zap.Error(err))
+		return false
+	}
+
+	if modTime.Equal(w.modTime) {
+		return false
+	}
+
+	w.modTime = modTime
+
+	return true
+}
+
+func (w *Watcher) fileModTime() (time.Time, error) {
+	if w.path == "" {
+		return time.Time{}, nil
+	}
+
+	info, err := os.Stat(w.path)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return info.ModTime(), nil
+}
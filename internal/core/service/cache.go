@@ -0,0 +1,149 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/joseCarlosAndrade/notification-server/internal/core/config"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain"
+	log "github.com/joseCarlosAndrade/notification-server/internal/core/domain/logger"
+	"go.uber.org/zap"
+)
+
+// CacheDependency is the name the cache is registered with in the health monitor
+const CacheDependency = "cache"
+
+// cacheAvailable reports if the cache should be used. while the health monitor reports it down, every
+// read and write goes straight to storage
+func (s *Service) cacheAvailable() bool {
+	if s.cache == nil {
+		return false
+	}
+
+	return s.health == nil || s.health.IsUp(CacheDependency)
+}
+
+// cacheGet decodes the cached json value into dst. cache errors are logged and treated as misses
+func (s *Service) cacheGet(ctx context.Context, key string, dst any) bool {
+	if !s.cacheAvailable() {
+		return false
+	}
+
+	// an entry that could not be invalidated is stale until the invalidation goes through
+	if !s.flushInvalidations(ctx) && s.invalidations.has(key) {
+		return false
+	}
+
+	value, err := s.cache.Get(ctx, key)
+	if errors.Is(err, domain.ErrNotFound) {
+		return false
+	}
+
+	if err != nil {
+		log.L(ctx).Warn("could not read from cache", zap.String("key", key), zap.Error(err))
+		return false
+	}
+
+	if err := json.Unmarshal(value, dst); err != nil {
+		log.L(ctx).Warn("invalid cache entry", zap.String("key", key), zap.Error(err))
+		return false
+	}
+
+	return true
+}
+
+// cacheSet stores value as json with the default ttl. failures are only logged
+func (s *Service) cacheSet(ctx context.Context, key string, value any) {
+	if !s.cacheAvailable() {
+		return
+	}
+
+	s.flushInvalidations(ctx)
+
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		log.L(ctx).Warn("could not encode cache entry", zap.String("key", key), zap.Error(err))
+		return
+	}
+
+	ttl := time.Duration(config.Live().DefaultCacheTTLs) * time.Second
+
+	if err := s.cache.Set(ctx, key, encoded, ttl); err != nil {
+		log.L(ctx).Warn("could not write to cache", zap.String("key", key), zap.Error(err))
+	}
+}
+
+// cacheDelete invalidates an entry. if the cache is down or the delete fails the key is kept and invalidated
+// again before the cache is used, so the old value is not served once the cache is back up
+func (s *Service) cacheDelete(ctx context.Context, key string) {
+	if s.cache == nil {
+		return
+	}
+
+	s.invalidations.add(key)
+
+	if !s.cacheAvailable() {
+		log.L(ctx).Warn("cache is down. invalidation postponed", zap.String("key", key))
+		return
+	}
+
+	s.flushInvalidations(ctx)
+}
+
+// flushInvalidations deletes every pending invalidation from the cache. it reports if none is left
+func (s *Service) flushInvalidations(ctx context.Context) bool {
+	for _, key := range s.invalidations.list() {
+		if err := s.cache.Delete(ctx, key); err != nil {
+			log.L(ctx).Warn("could not invalidate cache entry", zap.String("key", key), zap.Error(err))
+			return false
+		}
+
+		s.invalidations.remove(key)
+	}
+
+	return true
+}
+
+// invalidations holds the cache keys that still have to be deleted
+type invalidations struct {
+	mu   sync.Mutex
+	keys map[string]struct{}
+}
+
+func newInvalidations() *invalidations {
+	return &invalidations{keys: map[string]struct{}{}}
+}
+
+func (i *invalidations) add(key string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.keys[key] = struct{}{}
+}
+
+func (i *invalidations) remove(key string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	delete(i.keys, key)
+}
+
+func (i *invalidations) has(key string) bool {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	_, ok := i.keys[key]
+	return ok
+}
+
+func (i *invalidations) list() []string {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	return slices.Collect(maps.Keys(i.keys))
+}
@@ -0,0 +1,55 @@
+package service
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"github.com/joseCarlosAndrade/notification-server/internal/adapter/memory"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain/models"
+)
+
+// cacheHealth reports the cache up or down. every other dependency is up
+type cacheHealth struct {
+	down atomic.Bool
+}
+
+func (h *cacheHealth) Liveness(ctx context.Context) models.HealthReport { return models.HealthReport{} }
+func (h *cacheHealth) Readiness(ctx context.Context) models.HealthReport {
+	return models.HealthReport{}
+}
+
+func (h *cacheHealth) IsUp(name string) bool {
+	return name != CacheDependency || !h.down.Load()
+}
+
+func TestInvalidationWhileCacheIsDown(t *testing.T) {
+	ctx := context.Background()
+	storage := memory.NewStorage(ctx)
+	health := &cacheHealth{}
+	s := NewService(ctx, storage, memory.NewCache(ctx), storage, storage, health)
+
+	if _, err := s.SavePreferences(ctx, &models.Preferences{Recipient: "ana", MutedServices: []string{"old"}}); err != nil {
+		t.Fatalf("SavePreferences: %v", err)
+	}
+
+	// caches the old preferences
+	if _, err := s.recipientPreferences(ctx, "ana"); err != nil {
+		t.Fatalf("recipientPreferences: %v", err)
+	}
+
+	health.down.Store(true)
+	if _, err := s.SavePreferences(ctx, &models.Preferences{Recipient: "ana", MutedServices: []string{"new"}}); err != nil {
+		t.Fatalf("SavePreferences: %v", err)
+	}
+	health.down.Store(false)
+
+	preferences, err := s.recipientPreferences(ctx, "ana")
+	if err != nil {
+		t.Fatalf("recipientPreferences: %v", err)
+	}
+
+	if len(preferences.MutedServices) != 1 || preferences.MutedServices[0] != "new" {
+		t.Errorf("got muted services %v after the cache came back, want [new]", preferences.MutedServices)
+	}
+}
@@ -0,0 +1,235 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/config"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain"
+	log "github.com/joseCarlosAndrade/notification-server/internal/core/domain/logger"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain/models"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain/port"
+	"go.uber.org/zap"
+)
+
+const (
+	// digestService is the service of summaries collapsing notifications from more than one service
+	digestService = "digest"
+	// digestMaxLines is how many originals are listed in the summary message
+	digestMaxLines = 10
+)
+
+// digestNamespace seeds the deterministic summary ids
+var digestNamespace = uuid.MustParse("6f1d8a52-3c0e-4f4e-9a57-1b7c2f0e9d41")
+
+// matchDigestRule returns the first rule matching the notification or nil. digests are per recipient, so
+// notifications without one are never accumulated
+func matchDigestRule(rules config.DigestRules, notification *models.NotificationRecord) *config.DigestRule {
+	if notification.Recipient == "" {
+		return nil
+	}
+
+	for i := range rules {
+		rule := &rules[i]
+
+		if rule.Service != "" && rule.Service != notification.Service {
+			continue
+		}
+
+		if rule.Category != "" && rule.Category != notification.Category {
+			continue
+		}
+
+		if rule.Priority != "" && models.Priority(rule.Priority) != notification.Priority {
+			continue
+		}
+
+		return rule
+	}
+
+	return nil
+}
+
+// Digester collapses notifications accumulated by the digest rules into one summary per recipient
+// when each rule window closes. rules are read from config.Live, so they can be hot reloaded. it implements
+// port.Runner
+type Digester struct {
+	storage  port.Storage
+	interval time.Duration
+
+	lastWindow map[string]time.Time         // last collapsed window end per rule
+	known      map[string]config.DigestRule // rules seen since startup, to notice the ones removed by a reload
+	done       chan struct{}
+}
+
+var _ port.Runner = (*Digester)(nil)
+
+func NewDigester(ctx context.Context, storageRepository port.Storage, checkInterval time.Duration) Digester {
+	return Digester{
+		storage:    storageRepository,
+		interval:   checkInterval,
+		lastWindow: make(map[string]time.Time),
+		known:      make(map[string]config.DigestRule),
+		done:       make(chan struct{}),
+	}
+}
+
+// Run implements port.Runner interface
+func (d *Digester) Run(ctx context.Context) error {
+	log.L(ctx).Info("digest worker started",
+		zap.Int("rules", len(config.Live().DigestRules)),
+		zap.Duration("interval", d.interval))
+
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.L(ctx).Warn("context canceled. exiting digest worker")
+			return nil
+		case <-d.done:
+			return nil
+		case <-ticker.C:
+		}
+
+		d.collapseClosedWindows(ctx, domain.NewNowTime())
+	}
+}
+
+// collapseClosedWindows collapses the rules whose window closed since the last check. rules removed by a
+// reload are collapsed right away, so the notifications they accumulated are not left pending
+func (d *Digester) collapseClosedWindows(ctx context.Context, now time.Time) {
+	active := make(map[string]bool)
+
+	for _, rule := range config.Live().DigestRules {
+		active[rule.Name] = true
+		d.known[rule.Name] = rule
+
+		// windows are aligned to the interval (every hour, every day at 00:00 UTC), so replicas agree on them
+		windowEnd := now.Truncate(rule.Interval)
+		if !windowEnd.After(d.lastWindow[rule.Name]) {
+			continue
+		}
+
+		if err := d.collapse(ctx, rule, windowEnd); err != nil {
+			log.L(ctx).Error("could not collapse digest", zap.String("rule", rule.Name), zap.Error(err))
+			continue
+		}
+
+		d.lastWindow[rule.Name] = windowEnd
+	}
+
+	for name, rule := range d.known {
+		if active[name] {
+			continue
+		}
+
+		if err := d.collapse(ctx, rule, now); err != nil {
+			log.L(ctx).Error("could not collapse digest of removed rule", zap.String("rule", name), zap.Error(err))
+			continue
+		}
+
+		delete(d.known, name)
+		delete(d.lastWindow, name)
+	}
+}
+
+// Close implements port.Runner interface
+func (d *Digester) Close(ctx context.Context) error {
+	select {
+	case <-d.done:
+	default:
+		close(d.done)
+	}
+
+	return nil
+}
+
+// collapse creates one summary per recipient with every notification of the rule sent before windowEnd
+func (d *Digester) collapse(ctx context.Context, rule config.DigestRule, windowEnd time.Time) error {
+	pending, err := d.storage.GetPendingDigestNotifications(ctx, rule.Name, windowEnd)
+	if err != nil {
+		return err
+	}
+
+	// results are sorted by recipient. notifications without recipient (accumulated before they were excluded
+	// from digests) are never grouped with each other, so each one gets its own summary
+	for start := 0; start < len(pending); {
+		end := start + 1
+		for pending[start].Recipient != "" && end < len(pending) && pending[end].Recipient == pending[start].Recipient {
+			end++
+		}
+
+		group := pending[start:end]
+		start = end
+
+		key := group[0].Recipient
+		if key == "" {
+			key = "id:" + group[0].ID
+		}
+
+		id := uuid.NewSHA1(digestNamespace, fmt.Appendf(nil, "%s|%s|%d", rule.Name, key, windowEnd.Unix()))
+
+		ids := make([]string, 0, len(group))
+		for _, n := range group {
+			ids = append(ids, n.ID)
+		}
+
+		if err := d.storage.StoreDigest(ctx, newDigestRecord(rule, group, windowEnd), id.String(), ids); err != nil {
+			return err
+		}
+
+		log.L(ctx).Info("digest delivered",
+			zap.String("id", id.String()),
+			zap.String("rule", rule.Name),
+			zap.String("recipient", group[0].Recipient),
+			zap.Int("count", len(group)))
+	}
+
+	return nil
+}
+
+// newDigestRecord builds the summary listing the first originals. it has the priority of the rule, or normal
+// when the rule matches any priority
+func newDigestRecord(rule config.DigestRule, group []*models.Notification, windowEnd time.Time) *models.NotificationRecord {
+	priority := models.Priority(rule.Priority)
+	if priority == "" {
+		priority = models.PriorityNormal
+	}
+
+	service := group[0].Service
+	lines := make([]string, 0, digestMaxLines+1)
+
+	for i, n := range group {
+		if n.Service != service {
+			service = digestService
+		}
+
+		if i < digestMaxLines {
+			text := n.Message
+			if n.Title != "" {
+				text = n.Title
+			}
+
+			lines = append(lines, fmt.Sprintf("- [%s] %s", n.Service, text))
+		}
+	}
+
+	if len(group) > digestMaxLines {
+		lines = append(lines, fmt.Sprintf("and %d more", len(group)-digestMaxLines))
+	}
+
+	return &models.NotificationRecord{
+		Service:   service,
+		Recipient: group[0].Recipient,
+		Title:     fmt.Sprintf("%d new notifications", len(group)),
+		Message:   strings.Join(lines, "\n"),
+		SentAt:    &windowEnd,
+		Category:  rule.Category,
+		Priority:  priority,
+	}
+}
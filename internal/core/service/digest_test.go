@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/joseCarlosAndrade/notification-server/internal/adapter/memory"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/config"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain/models"
+)
+
+func TestMatchDigestRule(t *testing.T) {
+	rules := config.DigestRules{{Name: "low", Priority: "low", Interval: time.Hour}}
+
+	tests := []struct {
+		name         string
+		notification *models.NotificationRecord
+		want         bool
+	}{
+		{"Matches", &models.NotificationRecord{Recipient: "ana", Priority: models.PriorityLow}, true},
+		{"OtherPriority", &models.NotificationRecord{Recipient: "ana", Priority: models.PriorityHigh}, false},
+		{"NoRecipient", &models.NotificationRecord{Priority: models.PriorityLow}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchDigestRule(rules, tt.notification) != nil; got != tt.want {
+				t.Errorf("matchDigestRule = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCollapse(t *testing.T) {
+	ctx := context.Background()
+	storage := memory.NewStorage(ctx)
+	windowEnd := time.Now().Truncate(time.Hour)
+	sentAt := windowEnd.Add(-time.Minute)
+
+	for _, recipient := range []string{"ana", "ana", "", ""} {
+		rec := &models.NotificationRecord{
+			Service:    "svc",
+			Recipient:  recipient,
+			Message:    "hi",
+			SentAt:     &sentAt,
+			Priority:   models.PriorityLow,
+			DigestRule: "rule",
+		}
+
+		if _, err := storage.StoreNewNotification(ctx, rec, uuid.NewString()); err != nil {
+			t.Fatalf("StoreNewNotification: %v", err)
+		}
+	}
+
+	digester := NewDigester(ctx, storage, time.Minute)
+	if err := digester.collapse(ctx, config.DigestRule{Name: "rule", Interval: time.Hour}, windowEnd); err != nil {
+		t.Fatalf("collapse: %v", err)
+	}
+
+	digests, err := storage.ListNotifications(ctx, models.NotificationFilter{Service: "svc"})
+	if err != nil {
+		t.Fatalf("ListNotifications: %v", err)
+	}
+
+	// one for ana and one for each notification without recipient
+	if len(digests) != 3 {
+		t.Fatalf("got %d digests, want 3", len(digests))
+	}
+
+	for _, digest := range digests {
+		want := 1
+		if digest.Recipient == "ana" {
+			want = 2
+		}
+
+		if len(digest.DigestOf) != want {
+			t.Errorf("digest of %q has %d notifications, want %d", digest.Recipient, len(digest.DigestOf), want)
+		}
+
+		if digest.Priority != models.PriorityNormal {
+			t.Errorf("digest priority = %q, want %q for a rule matching any priority", digest.Priority,
+				models.PriorityNormal)
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain"
+	log "github.com/joseCarlosAndrade/notification-server/internal/core/domain/logger"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain/models"
+	"go.uber.org/zap"
+)
+
+const (
+	// importBatchSize is how many notifications are restored per storage call
+	importBatchSize = 500
+	// importMaxLineSize is the largest line accepted by an import
+	importMaxLineSize = 1 << 20
+	// csvFlushEvery is how many rows are buffered before they are written
+	csvFlushEvery = 100
+)
+
+// csvHeader are the columns of a csv export. metadata is a json object
+var csvHeader = []string{
+	"id", "service", "recipient", "title", "message", "category", "priority", "status", "isRead", "sentAt",
+	"readAt", "deliverAt", "archivedAt", "collapseKey", "count", "metadata",
+}
+
+// ExportNotifications writes every notification as it is read from the storage, so nothing but the current
+// notification is held in memory
+func (s *Service) ExportNotifications(ctx context.Context, filter models.ExportFilter, format models.ExportFormat,
+	w io.Writer) error {
+	if filter.Service == "" {
+		return fmt.Errorf("%w: service is required", domain.ErrInvalidArgument)
+	}
+
+	if !format.IsValid() {
+		return fmt.Errorf("%w: unknown export format %q", domain.ErrInvalidArgument, format)
+	}
+
+	if !filter.Since.IsZero() && !filter.Until.IsZero() && !filter.Since.Before(filter.Until) {
+		return fmt.Errorf("%w: since must be before until", domain.ErrInvalidArgument)
+	}
+
+	var (
+		write func(n *models.Notification) error
+		flush = func() error { return nil }
+	)
+
+	switch format {
+	case models.ExportCSV:
+		writer := csv.NewWriter(w)
+		if err := writer.Write(csvHeader); err != nil {
+			return fmt.Errorf("could not write csv header: %w", err)
+		}
+
+		rows := 0
+		write = func(n *models.Notification) error {
+			row, err := csvRow(n)
+			if err != nil {
+				return err
+			}
+
+			if err := writer.Write(row); err != nil {
+				return err
+			}
+
+			if rows++; rows%csvFlushEvery == 0 {
+				writer.Flush()
+			}
+
+			return writer.Error()
+		}
+
+		flush = func() error {
+			writer.Flush()
+			return writer.Error()
+		}
+	default:
+		encoder := json.NewEncoder(w)
+		write = func(n *models.Notification) error {
+			return encoder.Encode(n)
+		}
+	}
+
+	exported := 0
+	err := s.storage.ExportNotifications(ctx, filter, func(n *models.Notification) error {
+		if err := write(n); err != nil {
+			return fmt.Errorf("could not write notification %s: %w", n.ID, err)
+		}
+
+		exported++
+
+		return nil
+	})
+
+	if err == nil {
+		err = flush()
+	}
+
+	if err != nil {
+		log.L(ctx).Error("could not export notifications",
+			zap.String("service", filter.Service),
+			zap.Int("exported", exported),
+			zap.Error(err))
+
+		return fmt.Errorf("could not export notifications: %w", err)
+	}
+
+	log.L(ctx).Info("notifications exported",
+		zap.String("service", filter.Service),
+		zap.String("format", string(format)),
+		zap.Int("exported", exported))
+
+	return nil
+}
+
+func csvRow(n *models.Notification) ([]string, error) {
+	metadata := ""
+	if len(n.Metadata) > 0 {
+		encoded, err := json.Marshal(n.Metadata)
+		if err != nil {
+			return nil, err
+		}
+
+		metadata = string(encoded)
+	}
+
+	return []string{
+		n.ID, n.Service, n.Recipient, n.Title, n.Message, n.Category, string(n.Priority), string(n.Status),
+		strconv.FormatBool(n.IsRead), n.SentAt.Format(time.RFC3339Nano), formatOptionalTime(n.ReadAt),
+		formatOptionalTime(n.DeliverAt), formatOptionalTime(n.ArchivedAt), n.CollapseKey, strconv.Itoa(n.Count), metadata,
+	}, nil
+}
+
+func formatOptionalTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+
+	return t.Format(time.RFC3339Nano)
+}
+
+// ImportNotifications restores the notifications in batches. the batches before an invalid line are kept, which
+// is safe since importing them again replaces them
+func (s *Service) ImportNotifications(ctx context.Context, r io.Reader) (models.ImportResult, error) {
+	result := models.ImportResult{}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), importMaxLineSize)
+
+	batch := make([]*models.Notification, 0, importBatchSize)
+
+	restore := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+
+		if err := s.storage.RestoreNotifications(ctx, batch); err != nil {
+			log.L(ctx).Error("could not restore notifications",
+				zap.Int64("imported", result.Imported),
+				zap.Error(err))
+
+			return fmt.Errorf("could not restore notifications: %w", err)
+		}
+
+		result.Imported += int64(len(batch))
+		batch = batch[:0]
+
+		return nil
+	}
+
+	line := 0
+	for scanner.Scan() {
+		line++
+
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var n models.Notification
+		if err := json.Unmarshal(scanner.Bytes(), &n); err != nil {
+			return result, fmt.Errorf("%w: line %d: %w", domain.ErrInvalidArgument, line, err)
+		}
+
+		if n.ID == "" || n.Service == "" || n.SentAt.IsZero() {
+			return result, fmt.Errorf("%w: line %d: _id, service and sentAt are required", domain.ErrInvalidArgument, line)
+		}
+
+		batch = append(batch, &n)
+
+		if len(batch) == importBatchSize {
+			if err := restore(); err != nil {
+				return result, err
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return result, fmt.Errorf("could not read import after line %d: %w", line, err)
+	}
+
+	if err := restore(); err != nil {
+		return result, err
+	}
+
+	log.L(ctx).Info("notifications imported", zap.Int64("imported", result.Imported))
+
+	return result, nil
+}
@@ -0,0 +1,144 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain"
+	log "github.com/joseCarlosAndrade/notification-server/internal/core/domain/logger"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain/models"
+	"go.uber.org/zap"
+)
+
+// MaxListLimit is the max number of notifications returned by a single list call
+const MaxListLimit = 1000
+
+// ListNotifications returns a page of the visible notifications matching the filter, most recent first
+func (s *Service) ListNotifications(ctx context.Context, filter models.NotificationFilter) ([]*models.Notification, error) {
+	if filter.Service == "" {
+		return nil, fmt.Errorf("%w: service is required", domain.ErrInvalidArgument)
+	}
+
+	if filter.Limit < 0 || filter.Offset < 0 {
+		return nil, fmt.Errorf("%w: limit and offset cannot be negative", domain.ErrInvalidArgument)
+	}
+
+	if !filter.Since.IsZero() && !filter.Until.IsZero() && !filter.Since.Before(filter.Until) {
+		return nil, fmt.Errorf("%w: since must be before until", domain.ErrInvalidArgument)
+	}
+
+	if filter.Limit == 0 || filter.Limit > MaxListLimit {
+		filter.Limit = MaxListLimit
+	}
+
+	notifications, err := s.storage.ListNotifications(ctx, filter)
+	if err != nil {
+		log.L(ctx).Error("could not list notifications",
+			zap.String("service", filter.Service),
+			zap.Error(err))
+
+		return nil, fmt.Errorf("could not list notifications: %w", err)
+	}
+
+	return notifications, nil
+}
+
+func (s *Service) MarkNotificationAsRead(ctx context.Context, service, notificationID string) error {
+	if err := s.checkOwner(ctx, service, notificationID); err != nil {
+		return err
+	}
+
+	if err := s.storage.MarkNotificationAsRead(ctx, notificationID); err != nil {
+		log.L(ctx).Error("could not mark notification as read",
+			zap.String("id", notificationID),
+			zap.Error(err))
+
+		return err
+	}
+
+	return nil
+}
+
+func (s *Service) ArchiveNotification(ctx context.Context, service, notificationID string) error {
+	if err := s.checkOwner(ctx, service, notificationID); err != nil {
+		return err
+	}
+
+	if err := s.storage.ArchiveNotification(ctx, notificationID, domain.NewNowTime()); err != nil {
+		log.L(ctx).Error("could not archive notification",
+			zap.String("id", notificationID),
+			zap.Error(err))
+
+		return err
+	}
+
+	log.L(ctx).Info("notification archived", zap.String("id", notificationID))
+
+	return nil
+}
+
+func (s *Service) UnarchiveNotification(ctx context.Context, service, notificationID string) error {
+	if err := s.checkOwner(ctx, service, notificationID); err != nil {
+		return err
+	}
+
+	if err := s.storage.UnarchiveNotification(ctx, notificationID); err != nil {
+		log.L(ctx).Error("could not unarchive notification",
+			zap.String("id", notificationID),
+			zap.Error(err))
+
+		return err
+	}
+
+	log.L(ctx).Info("notification unarchived", zap.String("id", notificationID))
+
+	return nil
+}
+
+func (s *Service) DeleteNotification(ctx context.Context, notificationID string) error {
+	if err := s.storage.DeleteNotification(ctx, notificationID); err != nil {
+		log.L(ctx).Error("could not delete notification",
+			zap.String("id", notificationID),
+			zap.Error(err))
+
+		return err
+	}
+
+	// unlike archiving, deleting loses the audit trail
+	log.L(ctx).Warn("notification deleted", zap.String("id", notificationID))
+
+	return nil
+}
+
+// checkOwner returns domain.ErrNotFound if the notification belongs to another service, so services can't tell
+// the notifications of others from missing ones
+func (s *Service) checkOwner(ctx context.Context, service, notificationID string) error {
+	if service == "" {
+		return fmt.Errorf("%w: service is required", domain.ErrInvalidArgument)
+	}
+
+	n, err := s.storage.GetNotification(ctx, notificationID)
+	if err != nil {
+		return err
+	}
+
+	if n.Service != service {
+		log.L(ctx).Warn("notification of another service",
+			zap.String("id", notificationID),
+			zap.String("owner", n.Service))
+
+		return fmt.Errorf("notification %s: %w", notificationID, domain.ErrNotFound)
+	}
+
+	return nil
+}
+
+// Subscribe streams the notifications delivered by this replica that match the filter. the returned func must
+// be called to unsubscribe, which closes the channel
+func (s *Service) Subscribe(ctx context.Context, filter models.SubscriptionFilter) (<-chan *models.Notification, func()) {
+	return s.subscriptions.subscribe(ctx, filter)
+}
+
+func (s *Service) PublishNotification(ctx context.Context, notification *models.Notification) {
+	s.subscriptions.publish(notification)
+}
@@ -0,0 +1,51 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/joseCarlosAndrade/notification-server/internal/adapter/memory"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain/models"
+)
+
+func TestNotificationCallsCheckOwner(t *testing.T) {
+	ctx := context.Background()
+	storage := memory.NewStorage(ctx)
+	s := NewService(ctx, storage, nil, storage, storage, nil)
+
+	sentAt := time.Now()
+	id, err := storage.StoreNewNotification(ctx, &models.NotificationRecord{Service: "shop", Message: "hi",
+		SentAt: &sentAt}, uuid.NewString())
+	if err != nil {
+		t.Fatalf("StoreNewNotification: %v", err)
+	}
+
+	if err := s.ArchiveNotification(ctx, "bank", id); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("ArchiveNotification by another service = %v, want domain.ErrNotFound", err)
+	}
+
+	if err := s.MarkNotificationAsRead(ctx, "bank", id); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("MarkNotificationAsRead by another service = %v, want domain.ErrNotFound", err)
+	}
+
+	if err := s.UnarchiveNotification(ctx, "", id); !errors.Is(err, domain.ErrInvalidArgument) {
+		t.Errorf("UnarchiveNotification without service = %v, want domain.ErrInvalidArgument", err)
+	}
+
+	if err := s.ArchiveNotification(ctx, "shop", id); err != nil {
+		t.Errorf("ArchiveNotification by the owner: %v", err)
+	}
+
+	n, err := storage.GetNotification(ctx, id)
+	if err != nil {
+		t.Fatalf("GetNotification: %v", err)
+	}
+
+	if n.ArchivedAt == nil {
+		t.Error("notification was not archived by its owner")
+	}
+}
@@ -0,0 +1,167 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain"
+	log "github.com/joseCarlosAndrade/notification-server/internal/core/domain/logger"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain/models"
+	"go.uber.org/zap"
+)
+
+// defaultChannel is used when the recipient did not choose channels for a priority
+const defaultChannel = "inbox"
+
+func (s *Service) GetPreferences(ctx context.Context, recipient string) (*models.Preferences, error) {
+	return s.preferences.GetPreferences(ctx, recipient)
+}
+
+func (s *Service) SavePreferences(ctx context.Context, preferences *models.Preferences) (*models.Preferences, error) {
+	if err := validatePreferences(preferences); err != nil {
+		return nil, err
+	}
+
+	preferences.Locale = normalizeLocale(preferences.Locale)
+	preferences.UpdatedAt = domain.NewNowTime()
+
+	if err := s.preferences.SavePreferences(ctx, preferences); err != nil {
+		return nil, fmt.Errorf("could not save preferences: %w", err)
+	}
+
+	s.cacheDelete(ctx, preferencesCacheKey(preferences.Recipient))
+
+	log.L(ctx).Info("preferences saved", zap.String("recipient", preferences.Recipient))
+
+	return preferences, nil
+}
+
+func (s *Service) DeletePreferences(ctx context.Context, recipient string) error {
+	if err := s.preferences.DeletePreferences(ctx, recipient); err != nil {
+		return err
+	}
+
+	s.cacheDelete(ctx, preferencesCacheKey(recipient))
+
+	return nil
+}
+
+func preferencesCacheKey(recipient string) string {
+	return "preferences:" + recipient
+}
+
+func validatePreferences(p *models.Preferences) error {
+	if p.Recipient == "" {
+		return fmt.Errorf("%w: recipient is required", domain.ErrInvalidArgument)
+	}
+
+	if p.TimeZone != "" {
+		if _, err := time.LoadLocation(p.TimeZone); err != nil {
+			return fmt.Errorf("%w: unknown time zone %q", domain.ErrInvalidArgument, p.TimeZone)
+		}
+	}
+
+	if p.QuietHours != nil {
+		if _, err := time.Parse("15:04", p.QuietHours.Start); err != nil {
+			return fmt.Errorf("%w: quiet hours start must be HH:MM", domain.ErrInvalidArgument)
+		}
+
+		if _, err := time.Parse("15:04", p.QuietHours.End); err != nil {
+			return fmt.Errorf("%w: quiet hours end must be HH:MM", domain.ErrInvalidArgument)
+		}
+	}
+
+	for priority := range p.Channels {
+		if !priority.IsValid() {
+			return fmt.Errorf("%w: unknown priority %q in channels", domain.ErrInvalidArgument, priority)
+		}
+	}
+
+	return nil
+}
+
+// recipientPreferences returns nil when the notification has no recipient or they never set preferences.
+// they are read on every notification, so both cases are cached (recipients without preferences as null)
+func (s *Service) recipientPreferences(ctx context.Context, recipient string) (*models.Preferences, error) {
+	if recipient == "" {
+		return nil, nil
+	}
+
+	var cached *models.Preferences
+	if s.cacheGet(ctx, preferencesCacheKey(recipient), &cached) {
+		return cached, nil
+	}
+
+	preferences, err := s.preferences.GetPreferences(ctx, recipient)
+	if errors.Is(err, domain.ErrNotFound) {
+		preferences, err = nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("could not get recipient preferences: %w", err)
+	}
+
+	s.cacheSet(ctx, preferencesCacheKey(recipient), preferences)
+
+	return preferences, nil
+}
+
+// decideDelivery applies mutes, quiet hours and channel routing. critical notifications ignore quiet hours
+func decideDelivery(preferences *models.Preferences, notification *models.NotificationRecord, now time.Time) *models.Delivery {
+	if preferences == nil {
+		return &models.Delivery{Decision: models.DecisionDeliver, Channels: []string{defaultChannel}}
+	}
+
+	if slices.Contains(preferences.MutedServices, notification.Service) {
+		return &models.Delivery{Decision: models.DecisionSuppress, Reason: "service muted"}
+	}
+
+	if notification.Category != "" && slices.Contains(preferences.MutedCategories, notification.Category) {
+		return &models.Delivery{Decision: models.DecisionSuppress, Reason: "category muted"}
+	}
+
+	if notification.Priority != models.PriorityCritical && inQuietHours(preferences, now) {
+		return &models.Delivery{Decision: models.DecisionSilent, Reason: "quiet hours"}
+	}
+
+	channels := preferences.Channels[notification.Priority]
+	if len(channels) == 0 {
+		channels = []string{defaultChannel}
+	}
+
+	return &models.Delivery{Decision: models.DecisionDeliver, Channels: channels}
+}
+
+func inQuietHours(preferences *models.Preferences, now time.Time) bool {
+	if preferences.QuietHours == nil {
+		return false
+	}
+
+	location := time.UTC
+	if preferences.TimeZone != "" {
+		if loaded, err := time.LoadLocation(preferences.TimeZone); err == nil {
+			location = loaded
+		}
+	}
+
+	start, errStart := time.Parse("15:04", preferences.QuietHours.Start)
+	end, errEnd := time.Parse("15:04", preferences.QuietHours.End)
+	if errStart != nil || errEnd != nil {
+		return false
+	}
+
+	local := now.In(location)
+	minute := local.Hour()*60 + local.Minute()
+	startMinute := start.Hour()*60 + start.Minute()
+	endMinute := end.Hour()*60 + end.Minute()
+
+	if startMinute <= endMinute {
+		return minute >= startMinute && minute < endMinute
+	}
+
+	// window crosses midnight
+	return minute >= startMinute || minute < endMinute
+}
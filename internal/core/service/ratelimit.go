@@ -0,0 +1,169 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/joseCarlosAndrade/notification-server/internal/core/config"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain/metrics"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain/models"
+)
+
+// tokenBucket refills rate tokens per second up to burst
+type tokenBucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func (b *tokenBucket) take(now time.Time) bool {
+	b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+
+	b.tokens--
+
+	return true
+}
+
+// rateLimiter keeps one token bucket per producing service. limits are read from config.Live on each
+// call, so buckets follow config reloads
+type rateLimiter struct {
+	mu       sync.Mutex
+	buckets  map[string]*tokenBucket
+	stats    map[string]*models.ThrottleStats
+	disabled bool // lets everything through, for the tools that re-ingest in bulk
+}
+
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{
+		buckets: make(map[string]*tokenBucket),
+		stats:   make(map[string]*models.ThrottleStats),
+	}
+}
+
+// serviceLimit returns the limit of the service. a zero rate means unlimited
+func serviceLimit(service string) config.RateLimit {
+	settings := config.Live()
+
+	limit := config.RateLimit{
+		Rate:   settings.RateLimitRate,
+		Burst:  settings.RateLimitBurst,
+		Policy: settings.RateLimitPolicy,
+	}
+
+	if override, ok := settings.RateLimitOverrides[service]; ok {
+		limit.Rate = override.Rate
+		limit.Burst = override.Burst
+
+		if override.Policy != "" {
+			limit.Policy = override.Policy
+		}
+	}
+
+	if limit.Burst <= 0 {
+		limit.Burst = max(int(limit.Rate), 1)
+	}
+
+	return limit
+}
+
+// allow takes a token from the service bucket and reports if it did. excess notifications get the service
+// policy applied: nil is returned if the notification must still be stored (sampled), domain.ErrThrottled if it
+// must be dropped and domain.ErrThrottled wrapped in domain.ErrDeadLetter if it must be dead lettered
+func (l *rateLimiter) allow(service string, now time.Time) (bool, error) {
+	if l.disabled {
+		return false, nil
+	}
+
+	limit := serviceLimit(service)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	stats, ok := l.stats[service]
+	if !ok {
+		stats = &models.ThrottleStats{Service: service}
+		l.stats[service] = stats
+	}
+
+	if limit.Rate <= 0 {
+		stats.Allowed++
+		return false, nil
+	}
+
+	bucket, ok := l.buckets[service]
+	if !ok {
+		bucket = &tokenBucket{tokens: float64(limit.Burst), last: now}
+		l.buckets[service] = bucket
+	}
+
+	bucket.rate = limit.Rate
+	bucket.burst = float64(limit.Burst)
+
+	if bucket.take(now) {
+		stats.Allowed++
+		return true, nil
+	}
+
+	stats.Throttled++
+
+	switch limit.Policy {
+	case config.RateLimitDeadLetter:
+		stats.DeadLettered++
+		metrics.R().RecordThrottled(service, config.RateLimitDeadLetter)
+
+		return false, fmt.Errorf("%w: %w: service %s", domain.ErrDeadLetter, domain.ErrThrottled, service)
+	case config.RateLimitSample:
+		if stats.Throttled%uint64(max(config.Live().RateLimitSampleEvery, 1)) == 0 {
+			stats.Sampled++
+			metrics.R().RecordThrottled(service, config.RateLimitSample)
+
+			return false, nil
+		}
+	}
+
+	stats.Dropped++
+	metrics.R().RecordThrottled(service, config.RateLimitDrop)
+
+	return false, fmt.Errorf("%w: service %s", domain.ErrThrottled, service)
+}
+
+// refund gives back the token taken by allow, when the notification could not be stored. retries of the same
+// notification then take a single token
+func (l *rateLimiter) refund(service string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if bucket, ok := l.buckets[service]; ok {
+		bucket.tokens = min(bucket.burst, bucket.tokens+1)
+	}
+
+	if stats, ok := l.stats[service]; ok && stats.Allowed > 0 {
+		stats.Allowed--
+	}
+}
+
+// snapshot copies the counters sorted by service
+func (l *rateLimiter) snapshot() []models.ThrottleStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	final := make([]models.ThrottleStats, 0, len(l.stats))
+	for _, stats := range l.stats {
+		final = append(final, *stats)
+	}
+
+	sort.Slice(final, func(i, j int) bool {
+		return final[i].Service < final[j].Service
+	})
+
+	return final
+}
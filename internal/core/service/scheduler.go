@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain"
+	log "github.com/joseCarlosAndrade/notification-server/internal/core/domain/logger"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain/models"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain/port"
+	"go.uber.org/zap"
+)
+
+// schedulerBatchSize is the max number of notifications claimed per tick
+const schedulerBatchSize = 100
+
+// Scheduler promotes pending notifications once their deliverAt is due and streams them to the subscribers.
+// it implements port.Runner. pending notifications live in storage, so nothing is lost on restarts, and claims
+// are leased so only one replica promotes each notification
+type Scheduler struct {
+	storage       port.Storage
+	notifications port.Service
+	owner         string
+	interval      time.Duration
+	lease         time.Duration
+
+	done chan struct{}
+}
+
+var _ port.Runner = (*Scheduler)(nil)
+
+func NewScheduler(ctx context.Context, storageRepository port.Storage, notifications port.Service,
+	interval, lease time.Duration) Scheduler {
+	return Scheduler{
+		storage:       storageRepository,
+		notifications: notifications,
+		owner:         newOwnerID(),
+		interval:      interval,
+		lease:         lease,
+		done:          make(chan struct{}),
+	}
+}
+
+// newOwnerID identifies this replica on leases
+func newOwnerID() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+
+	return fmt.Sprintf("%s-%s", hostname, uuid.NewString())
+}
+
+// Run implements port.Runner interface
+func (s *Scheduler) Run(ctx context.Context) error {
+	log.L(ctx).Info("scheduler started",
+		zap.String("owner", s.owner),
+		zap.Duration("interval", s.interval))
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.L(ctx).Warn("context canceled. exiting scheduler")
+			return nil
+		case <-s.done:
+			return nil
+		case <-ticker.C:
+		}
+
+		// keeps promoting while full batches are claimed, so a backlog is drained before waiting again
+		for {
+			promoted, err := s.promoteDue(ctx)
+			if err != nil {
+				log.L(ctx).Error("could not promote due notifications", zap.Error(err))
+				break
+			}
+
+			if promoted < schedulerBatchSize {
+				break
+			}
+		}
+	}
+}
+
+// Close implements port.Runner interface
+func (s *Scheduler) Close(ctx context.Context) error {
+	select {
+	case <-s.done:
+	default:
+		close(s.done)
+	}
+
+	return nil
+}
+
+// promoteDue claims a batch of due notifications and delivers them. the delivery decision taken when they were
+// received is kept, so silent ones are still delivered silently. returns how many were claimed
+func (s *Scheduler) promoteDue(ctx context.Context) (int, error) {
+	now := domain.NewNowTime()
+
+	claimed, err := s.storage.ClaimDueNotifications(ctx, s.owner, now, s.lease, schedulerBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, notification := range claimed {
+		deliveredAt := domain.NewNowTime()
+
+		err := s.storage.PromoteNotification(ctx, notification.ID, s.owner, deliveredAt)
+		if errors.Is(err, domain.ErrConflict) {
+			// lease expired and another replica took over
+			log.L(ctx).Warn("lost lease on pending notification", zap.String("id", notification.ID))
+			continue
+		}
+
+		if err != nil {
+			// lease will expire and the notification will be claimed again
+			log.L(ctx).Error("could not promote notification", zap.String("id", notification.ID), zap.Error(err))
+			continue
+		}
+
+		delay := deliveredAt.Sub(*notification.DeliverAt)
+
+		notification.Status = models.StatusDelivered
+		notification.SentAt = deliveredAt
+		s.notifications.PublishNotification(ctx, notification)
+
+		if notification.Delivery != nil && notification.Delivery.Decision != models.DecisionDeliver {
+			log.L(ctx).Info("scheduled notification delivered without push",
+				zap.String("id", notification.ID),
+				zap.String("decision", string(notification.Delivery.Decision)),
+				zap.String("reason", notification.Delivery.Reason),
+				zap.Duration("delay", delay))
+
+			continue
+		}
+
+		log.L(ctx).Info("scheduled notification delivered",
+			zap.String("id", notification.ID),
+			zap.Duration("delay", delay))
+	}
+
+	return len(claimed), nil
+}
@@ -5,45 +5,200 @@ import (
 	"fmt"
 
 	"github.com/google/uuid"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/config"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain"
 	log "github.com/joseCarlosAndrade/notification-server/internal/core/domain/logger"
 	"github.com/joseCarlosAndrade/notification-server/internal/core/domain/models"
 	"github.com/joseCarlosAndrade/notification-server/internal/core/domain/port"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
+var tracer = otel.Tracer(config.AppTraceName)
+
 // Service implements the port.Service interface
 type Service struct {
-	storage port.Storage
-	cache   port.Cache
+	storage     port.Storage
+	cache       port.Cache
+	templates   port.TemplateStorage
+	preferences port.PreferencesStorage
+	health      port.Health // used to bypass the cache while it is down. may be nil
+
+	parsedTemplates *templateCache
+	invalidations   *invalidations // cache keys that could not be invalidated yet
+	limiter         *rateLimiter
+	subscriptions   *subscriptions
 }
 
-func NewService(ctx context.Context, storageRepository port.Storage, cacheRepository port.Cache) Service {
+// NewService creates the service. cacheRepository may be nil to run without cache
+func NewService(ctx context.Context, storageRepository port.Storage, cacheRepository port.Cache,
+	templateRepository port.TemplateStorage, preferencesRepository port.PreferencesStorage, health port.Health) Service {
 	return Service{
-		storage: storageRepository,
-		cache:   cacheRepository,
+		storage:         storageRepository,
+		cache:           cacheRepository,
+		templates:       templateRepository,
+		preferences:     preferencesRepository,
+		health:          health,
+		parsedTemplates: &templateCache{},
+		invalidations:   newInvalidations(),
+		limiter:         newRateLimiter(),
+		subscriptions:   newSubscriptions(),
 	}
 }
 
-func (s *Service) SaveNewNotification(ctx context.Context, notification *models.NotificationRecord) error {
-	id, err := uuid.NewV7()
+func (s *Service) SaveNewNotification(ctx context.Context, notification *models.NotificationRecord) (_ string, err error) {
+	ctx, span := tracer.Start(ctx, "SaveNewNotification", trace.WithAttributes(
+		attribute.String("notification.service", notification.Service),
+		attribute.String("notification.recipient", notification.Recipient),
+	))
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+
+		span.End()
+	}()
+
+	// throttles before any work is done for the notification
+	tokenTaken, err := s.limiter.allow(notification.Service, domain.NewNowTime())
+	if err != nil {
+		log.L(ctx).Warn("notification throttled",
+			zap.String("service", notification.Service),
+			zap.Error(err))
+
+		return "", err
+	}
+
+	// a notification that was not stored gives its token back, so retrying it does not take another one
+	if tokenTaken {
+		defer func() {
+			if err != nil {
+				s.limiter.refund(notification.Service)
+			}
+		}()
+	}
+
+	preferences, err := s.recipientPreferences(ctx, notification.Recipient)
 	if err != nil {
-		return fmt.Errorf("could not generate id: %w", err)
+		log.L(ctx).Error("could not load recipient preferences",
+			zap.String("recipient", notification.Recipient),
+			zap.Error(err))
+
+		return "", err
+	}
+
+	if notification.TemplateID != "" {
+		recipientLocale := ""
+		if preferences != nil {
+			recipientLocale = preferences.Locale
+		}
+
+		if err := s.renderNotification(ctx, notification, recipientLocale); err != nil {
+			log.L(ctx).Error("could not render notification template",
+				zap.String("templateId", notification.TemplateID),
+				zap.Error(err))
+
+			return "", err
+		}
+	}
+
+	if notification.Priority == "" {
+		notification.Priority = models.PriorityNormal
+	}
+
+	// a deliverAt that is already due is delivered right away
+	if notification.DeliverAt != nil && !notification.DeliverAt.After(domain.NewNowTime()) {
+		notification.DeliverAt = nil
+	}
+
+	// suppressed notifications are stored only for audit, so they skip scheduling and digests
+	notification.Delivery = decideDelivery(preferences, notification, domain.NewNowTime())
+	if notification.Delivery.Decision == models.DecisionSuppress {
+		notification.DeliverAt = nil
+	}
+
+	// scheduled notifications are delivered on their own. the others may be accumulated into a digest
+	if notification.DeliverAt == nil && notification.Delivery.Decision != models.DecisionSuppress {
+		if rule := matchDigestRule(config.Live().DigestRules, notification); rule != nil {
+			notification.DigestRule = rule.Name
+		}
+	}
+
+	newID := notification.ID
+	if newID == "" {
+		generated, err := uuid.NewV7()
+		if err != nil {
+			return "", fmt.Errorf("could not generate id: %w", err)
+		}
+
+		newID = generated.String()
 	}
 
-	err = s.storage.StoreNewNotification(ctx, notification, id.String())
+	id, err := s.storage.StoreNewNotification(ctx, notification, newID)
 	if err != nil {
 		log.L(ctx).Error("could not store new notification",
-			zap.String("id", id.String()),
+			zap.String("id", newID),
 			zap.Error(err))
 
-		return fmt.Errorf("could not store new notification: %w", err)
+		return "", fmt.Errorf("could not store new notification: %w", err)
+	}
+
+	// subscribers only get what the recipient can see right away. collapsed ones are sent with the id they
+	// were collapsed into
+	if notification.InitialStatus().IsVisible() {
+		published := notification.AsNotification(id)
+		s.subscriptions.publish(&published)
+	}
+
+	if id != newID {
+		log.L(ctx).Info("notification collapsed into unread one",
+			zap.String("id", id),
+			zap.String("collapseKey", notification.CollapseKey))
+
+		return id, nil
 	}
 
 	// todo: store in cache
 
+	if notification.Delivery.Decision != models.DecisionDeliver {
+		log.L(ctx).Info("notification stored without delivery",
+			zap.String("id", id),
+			zap.String("decision", string(notification.Delivery.Decision)),
+			zap.String("reason", notification.Delivery.Reason))
+	}
+
+	if notification.DigestRule != "" {
+		log.L(ctx).Info("notification accumulated for digest",
+			zap.String("id", id),
+			zap.String("rule", notification.DigestRule))
+
+		return id, nil
+	}
+
+	if notification.DeliverAt != nil {
+		log.L(ctx).Info("notification scheduled",
+			zap.String("id", id),
+			zap.Time("deliverAt", *notification.DeliverAt))
+
+		return id, nil
+	}
+
 	log.L(ctx).Info("notification successfully stored",
-		zap.String("id", id.String()))
+		zap.String("id", id))
 
+	return id, nil
+}
+
+// DisableRateLimit lets every notification through whatever the service limits. meant for the tools that
+// re-ingest in bulk, like replays, which would otherwise have their records dropped
+func (s *Service) DisableRateLimit() {
+	s.limiter.disabled = true
+}
 
-	return nil
+func (s *Service) GetThrottleStats(ctx context.Context) []models.ThrottleStats {
+	return s.limiter.snapshot()
 }
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"sync"
+
+	log "github.com/joseCarlosAndrade/notification-server/internal/core/domain/logger"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain/models"
+	"go.uber.org/zap"
+)
+
+// subscriptionBuffer is how many notifications a subscriber may fall behind before new ones are dropped for it
+const subscriptionBuffer = 64
+
+type subscription struct {
+	ctx    context.Context
+	filter models.SubscriptionFilter
+	ch     chan *models.Notification
+}
+
+// subscriptions fans out delivered notifications to the subscribers. slow subscribers never block the
+// delivery: notifications that don't fit in their buffer are dropped
+type subscriptions struct {
+	mu     sync.Mutex
+	nextID int
+	subs   map[int]*subscription
+}
+
+func newSubscriptions() *subscriptions {
+	return &subscriptions{subs: make(map[int]*subscription)}
+}
+
+func (s *subscriptions) subscribe(ctx context.Context, filter models.SubscriptionFilter) (<-chan *models.Notification, func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := s.nextID
+	s.nextID++
+
+	sub := &subscription{ctx: ctx, filter: filter, ch: make(chan *models.Notification, subscriptionBuffer)}
+	s.subs[id] = sub
+
+	var once sync.Once
+	unsubscribe := func() {
+		once.Do(func() {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+
+			delete(s.subs, id)
+			close(sub.ch)
+		})
+	}
+
+	return sub.ch, unsubscribe
+}
+
+func (s *subscriptions) publish(notification *models.Notification) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, sub := range s.subs {
+		if !sub.filter.Matches(notification) {
+			continue
+		}
+
+		// every subscriber gets its own copy
+		n := *notification
+
+		select {
+		case sub.ch <- &n:
+		default:
+			log.L(sub.ctx).Warn("subscriber is too slow. notification dropped", zap.String("id", notification.ID))
+		}
+	}
+}
@@ -0,0 +1,238 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"text/template"
+
+	"github.com/joseCarlosAndrade/notification-server/internal/core/config"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain"
+	log "github.com/joseCarlosAndrade/notification-server/internal/core/domain/logger"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain/models"
+	"go.uber.org/zap"
+)
+
+// maxVersionAttempts is how many times a template upload retries when another replica took the same version
+const maxVersionAttempts = 3
+
+// parsedTemplate holds the compiled title and body of a template version
+type parsedTemplate struct {
+	title *template.Template
+	body  *template.Template
+}
+
+// templateCache keeps compiled templates. versions are immutable, so entries never need invalidation
+type templateCache struct {
+	entries sync.Map // map[string]*parsedTemplate
+}
+
+func (c *templateCache) get(t *models.Template) (*parsedTemplate, error) {
+	key := fmt.Sprintf("%s:%s:%d", t.ID, t.Locale, t.Version)
+
+	if p, ok := c.entries.Load(key); ok {
+		return p.(*parsedTemplate), nil
+	}
+
+	p, err := parseTemplate(t)
+	if err != nil {
+		return nil, err
+	}
+
+	c.entries.Store(key, p)
+
+	return p, nil
+}
+
+func parseTemplate(t *models.Template) (*parsedTemplate, error) {
+	title, err := template.New("title").Option("missingkey=error").Parse(t.Title)
+	if err != nil {
+		return nil, fmt.Errorf("%w: title: %w", domain.ErrInvalidTemplate, err)
+	}
+
+	body, err := template.New("body").Option("missingkey=error").Parse(t.Body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: body: %w", domain.ErrInvalidTemplate, err)
+	}
+
+	return &parsedTemplate{title: title, body: body}, nil
+}
+
+func (p *parsedTemplate) render(variables map[string]any) (string, string, error) {
+	if variables == nil {
+		variables = map[string]any{}
+	}
+
+	var title, body bytes.Buffer
+
+	if err := p.title.Execute(&title, variables); err != nil {
+		return "", "", fmt.Errorf("title: %w", err)
+	}
+
+	if err := p.body.Execute(&body, variables); err != nil {
+		return "", "", fmt.Errorf("body: %w", err)
+	}
+
+	return title.String(), body.String(), nil
+}
+
+// validateTemplate parses the template and renders it with the example variables, as the consumer would. every
+// variable the template uses must be in the example, otherwise notifications missing it would only fail once
+// consumed
+func validateTemplate(t *models.Template) error {
+	if t.ID == "" || t.Locale == "" {
+		return fmt.Errorf("%w: id and locale are required", domain.ErrInvalidTemplate)
+	}
+
+	if strings.TrimSpace(t.Body) == "" {
+		return fmt.Errorf("%w: body cannot be empty", domain.ErrInvalidTemplate)
+	}
+
+	p, err := parseTemplate(t)
+	if err != nil {
+		return err
+	}
+
+	if _, _, err := p.render(t.Example); err != nil {
+		return fmt.Errorf("%w: could not render with the example variables: %w", domain.ErrInvalidTemplate, err)
+	}
+
+	return nil
+}
+
+func (s *Service) SaveTemplate(ctx context.Context, t *models.Template) (*models.Template, error) {
+	t.Locale = normalizeLocale(t.Locale)
+
+	if err := validateTemplate(t); err != nil {
+		log.L(ctx).Warn("rejected invalid template", zap.String("templateId", t.ID), zap.Error(err))
+		return nil, err
+	}
+
+	// another replica may take the same version number between the read and the insert, so retry on conflicts
+	for range maxVersionAttempts {
+		latest, err := s.templates.GetLatestTemplate(ctx, t.ID, t.Locale)
+		switch {
+		case errors.Is(err, domain.ErrNotFound):
+			t.Version = 1
+		case err != nil:
+			return nil, fmt.Errorf("could not get latest template version: %w", err)
+		default:
+			t.Version = latest.Version + 1
+		}
+
+		t.CreatedAt = domain.NewNowTime()
+
+		err = s.templates.StoreTemplate(ctx, t)
+		if errors.Is(err, domain.ErrConflict) {
+			continue
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("could not store template: %w", err)
+		}
+
+		log.L(ctx).Info("template successfully stored",
+			zap.String("templateId", t.ID),
+			zap.String("locale", t.Locale),
+			zap.Int("version", t.Version))
+
+		return t, nil
+	}
+
+	return nil, fmt.Errorf("could not store template after %d attempts: %w", maxVersionAttempts, domain.ErrConflict)
+}
+
+func (s *Service) GetTemplate(ctx context.Context, templateID, locale string, version int) (*models.Template, error) {
+	locale = normalizeLocale(locale)
+
+	if version > 0 {
+		return s.templates.GetTemplateVersion(ctx, templateID, locale, version)
+	}
+
+	return s.templates.GetLatestTemplate(ctx, templateID, locale)
+}
+
+func (s *Service) ListTemplateVersions(ctx context.Context, templateID string) ([]*models.Template, error) {
+	return s.templates.ListTemplateVersions(ctx, templateID)
+}
+
+// renderNotification fills the record title and message from its template using the best matching locale.
+// the recipient locale (from their preferences) wins over the one sent in the record
+func (s *Service) renderNotification(ctx context.Context, notification *models.NotificationRecord, recipientLocale string) error {
+	t, err := s.resolveTemplate(ctx, notification.TemplateID, recipientLocale, notification.Locale)
+	if err != nil {
+		return fmt.Errorf("%w: %w", domain.ErrTemplateRender, err)
+	}
+
+	parsed, err := s.parsedTemplates.get(t)
+	if err != nil {
+		return fmt.Errorf("%w: %w", domain.ErrTemplateRender, err)
+	}
+
+	title, body, err := parsed.render(notification.Variables)
+	if err != nil {
+		return fmt.Errorf("%w: %s@%s v%d: %w", domain.ErrTemplateRender, t.ID, t.Locale, t.Version, err)
+	}
+
+	notification.Title = title
+	notification.Message = body
+	notification.Locale = t.Locale
+
+	log.L(ctx).Debug("rendered notification from template",
+		zap.String("templateId", t.ID),
+		zap.String("locale", t.Locale),
+		zap.Int("version", t.Version))
+
+	return nil
+}
+
+// resolveTemplate tries each locale (pt-br), then its language (pt), then the default locale
+func (s *Service) resolveTemplate(ctx context.Context, templateID string, locales ...string) (*models.Template, error) {
+	for _, candidate := range localeCandidates(locales...) {
+		t, err := s.templates.GetLatestTemplate(ctx, templateID, candidate)
+		if errors.Is(err, domain.ErrNotFound) {
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		return t, nil
+	}
+
+	return nil, fmt.Errorf("template %s has no version for locales %q: %w", templateID, locales, domain.ErrNotFound)
+}
+
+func localeCandidates(locales ...string) []string {
+	candidates := make([]string, 0, 2*len(locales)+1)
+	seen := make(map[string]bool)
+
+	add := func(locale string) {
+		if locale != "" && !seen[locale] {
+			seen[locale] = true
+			candidates = append(candidates, locale)
+		}
+	}
+
+	for _, locale := range locales {
+		locale = normalizeLocale(locale)
+		add(locale)
+
+		if lang, _, found := strings.Cut(locale, "-"); found {
+			add(lang)
+		}
+	}
+
+	add(normalizeLocale(config.App.DefaultLocale))
+
+	return candidates
+}
+
+// normalizeLocale lower cases locales and uses '-' as separator (pt_BR -> pt-br)
+func normalizeLocale(locale string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+}
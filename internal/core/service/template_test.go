@@ -0,0 +1,38 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain/models"
+)
+
+func TestValidateTemplate(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		example map[string]any
+		valid   bool
+	}{
+		{"NoVariables", "hello", nil, true},
+		{"ExampleHasVariables", "hello {{.name}}", map[string]any{"name": "ana"}, true},
+		{"NoExample", "hello {{.name}}", nil, false},
+		{"ExampleMissesVariable", "hello {{.name}}, {{.order}}", map[string]any{"name": "ana"}, false},
+		{"ExampleMissesConditional", "hello{{if .vip}} vip{{end}}", map[string]any{}, false},
+		{"InvalidSyntax", "hello {{.name", map[string]any{"name": "ana"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateTemplate(&models.Template{ID: "welcome", Locale: "en", Body: tt.body, Example: tt.example})
+			if (err == nil) != tt.valid {
+				t.Fatalf("validateTemplate = %v, want valid %v", err, tt.valid)
+			}
+
+			if err != nil && !errors.Is(err, domain.ErrInvalidTemplate) {
+				t.Errorf("validateTemplate = %v, want domain.ErrInvalidTemplate", err)
+			}
+		})
+	}
+}
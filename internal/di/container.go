@@ -3,22 +3,33 @@ package di
 import (
 	"context"
 	"errors"
-	"fmt"
-	"time"
+	"net/http"
+	"slices"
 
+	"github.com/joseCarlosAndrade/notification-server/internal/adapter/amqp"
+	"github.com/joseCarlosAndrade/notification-server/internal/adapter/file"
+	grpcserver "github.com/joseCarlosAndrade/notification-server/internal/adapter/grpc/server"
 	"github.com/joseCarlosAndrade/notification-server/internal/adapter/http/server"
+	"github.com/joseCarlosAndrade/notification-server/internal/adapter/memory"
 	"github.com/joseCarlosAndrade/notification-server/internal/adapter/mongo"
+	"github.com/joseCarlosAndrade/notification-server/internal/adapter/nats"
+	"github.com/joseCarlosAndrade/notification-server/internal/adapter/otlp"
+	"github.com/joseCarlosAndrade/notification-server/internal/adapter/prometheus"
 	"github.com/joseCarlosAndrade/notification-server/internal/adapter/redis"
 	"github.com/joseCarlosAndrade/notification-server/internal/adapter/redpanda"
+	"github.com/joseCarlosAndrade/notification-server/internal/adapter/schemaregistry"
 	"github.com/joseCarlosAndrade/notification-server/internal/core/config"
 	log "github.com/joseCarlosAndrade/notification-server/internal/core/domain/logger"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain/metrics"
 	"github.com/joseCarlosAndrade/notification-server/internal/core/domain/port"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/eventsource"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/health"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/reload"
 	"github.com/joseCarlosAndrade/notification-server/internal/core/service"
 	"go.uber.org/zap"
 )
 
 type Shutdown func(context.Context) error
-type HealthCheck func(context.Context) error
 
 type Container struct {
 	eventsHub            port.EventsHub
@@ -29,63 +40,151 @@ type Container struct {
 
 	// TODO: BEFORE CONTINUING, CHECK OUT THE EMAIL DISPATCHER SERVICE TO SEE HOW THEY MANAGE KAFKA LISTENING
 
-	cleanUpFuncs map[string]Shutdown
-	healthProbeFuncs map[string]HealthCheck
+	// lifecycle starts and closes every component in dependency order
+	lifecycle *lifecycle
 }
 
 func NewContainer(ctx context.Context) Container {
 	log.L(ctx).Info("initializing container")
 
+	lc := newLifecycle(config.App.ShutdownTimeout, config.App.StartupTimeout)
+
 	// init dependencies
 
-	cleanUps := make(map[string]Shutdown, 0)
-	healthProbes := make(map[string]HealthCheck, 0)
+	// metrics and tracing first, so every dependency emits through them. tracing is closed last, so the
+	// spans of the shutdown are flushed too
+	recorder := initMetrics(ctx)
+	lc.add("tracing", nil, initTracing(ctx))
+
+	// health monitor. dependencies register themselves below
+	monitor := initHealthMonitor(ctx)
 
 	// storage
 	storage := initStorage(ctx)
-	cleanUps["storage"] = storage.Close
-	healthProbes["storage"] = storage.IsHealthy
+	lc.add("storage", storage.Run, storage.Close, "tracing")
+	lc.setReady("storage", storage.IsHealthy)
+	monitor.Register("storage", storage.IsHealthy, true)
 	
+	// everything built on the service uses the storage and, if enabled, the cache
+	core := []string{"storage"}
 
-	// cache
+	// cache. it is not critical: while it is down the service bypasses it and the app runs degraded, so nothing
+	// waits for it to be ready
 	cache := initCache(ctx)
-	cleanUps["cache"] = cache.Close
-	healthProbes["cache"] = cache.IsHealthy
+	if cache != nil {
+		lc.add("cache", cache.Run, cache.Close, "tracing")
+		monitor.Register(service.CacheDependency, cache.IsHealthy, false)
+
+		core = append(core, "cache")
+	}
+
+	// init service with dependencies. the storage also holds the templates and preferences
+	notificationService := initNotificationService(ctx, storage, cache, storage, storage, monitor)
 
-	// init service with dependencies
-	service := initNotificationService(ctx, storage, cache)
+	// schema registry for avro and protobuf payloads. not critical: while it is down only json payloads are processed
+	registry := initSchemaRegistry(ctx)
+	if registry != nil {
+		monitor.Register("schemaRegistry", registry.IsHealthy, false)
+	}
 
 	// init consumer
-	consumer := initEventsHub(ctx, &service)
+	consumer := initEventsHub(ctx, &notificationService, registry)
+	lc.add("eventsHub", consumer.Run, consumer.Close, core...)
+	lc.setTimeout("eventsHub", config.App.DrainTimeout+config.App.ShutdownTimeout) // drain, then commit and close
+	lc.setReady("eventsHub", consumer.IsHealthy)
+
+	// init controller. it is registered after the consumer so it stops taking requests first
+	controller := initAPIController(ctx, &notificationService, monitor, recorder.Handler())
+	lc.add("apiController", controller.Run, controller.Close, core...)
+
+	grpcController := initGRPCController(ctx, &notificationService, monitor)
+	lc.add("grpcController", grpcController.Run, grpcController.Close, core...)
 
-	// init controller
-	controller := initAPIController(ctx, &service)
+	// init background workers
+	scheduler := initScheduler(ctx, storage, notificationService)
+	lc.add("scheduler", scheduler.Run, scheduler.Close, "storage")
 
-	// append every shutdown
-	cleanUps["eventsHub"] = consumer.Close
-	cleanUps["apiController"] = controller.Close
+	// always running, since digest rules can be added by a reload
+	digester := initDigester(ctx, storage)
+	lc.add("digester", digester.Run, digester.Close, "storage")
+
+	// hot reloads the settings read through config.Live
+	watcher := initConfigWatcher(ctx)
+	lc.add("configWatcher", watcher.Run, watcher.Close)
 
 	// append health probe for the main services
-	healthProbes["eventsHub"] = consumer.IsHealthy
-	healthProbes["apiController"] = controller.IsHealthy
+	monitor.Register("eventsHub", consumer.IsHealthy, true)
+	monitor.Register("apiController", controller.IsHealthy, true)
+	monitor.Register("grpcController", grpcController.IsHealthy, true)
+
+	// the monitor probes everything, so it depends on everything
+	lc.add("healthMonitor", monitor.Run, monitor.Close,
+		slices.Concat(core, []string{"eventsHub", "apiController", "grpcController"})...)
+
+	if err := lc.resolve(); err != nil {
+		panic(err)
+	}
 
 	// return container
 	return Container{
 		controller: controller,
-		eventsHub: consumer,
-		storage: storage,
-		cache: cache,
-		cleanUpFuncs: cleanUps,
-		healthProbeFuncs: healthProbes,
+		eventsHub:  consumer,
+		storage:    storage,
+		cache:      cache,
+		lifecycle:  lc,
 	}	
 }
 
 // init dependencies. if anything crucial fails, panic
 
-func initStorage(ctx context.Context) port.Storage {
+func initMetrics(ctx context.Context) *prometheus.Recorder {
+	recorder := prometheus.NewRecorder()
+	metrics.SetRecorder(recorder)
+
+	log.L(ctx).Debug("successfully initialized metrics")
+
+	return recorder
+}
+
+func initTracing(ctx context.Context) Shutdown {
+	shutdown, err := otlp.InitTracing(ctx, config.App.OtelExporterEndpoint, config.App.OtelExporterInsecure)
+	if err != nil {
+		panic(err)
+	}
+
+	log.L(ctx).Debug("successfully initialized tracing")
+
+	return Shutdown(shutdown)
+}
+
+func initHealthMonitor(ctx context.Context) *health.Monitor {
+	monitor := health.NewMonitor(ctx, config.App.HealthCheckInterval, config.App.HealthCheckTimeout,
+		config.App.HealthFailureThreshold)
+
+	log.L(ctx).Debug("successfully initialized health monitor")
+
+	return monitor
+}
+
+// storageBackend is a storage that also holds the templates and preferences
+type storageBackend interface {
+	port.Storage
+	port.TemplateStorage
+	port.PreferencesStorage
+}
+
+func initStorage(ctx context.Context) storageBackend {
+	if config.App.StorageBackend == config.BackendMemory {
+		return memory.NewStorage(ctx)
+	}
+
 	storage, err := mongo.NewStorage(ctx, config.App.MongoURI, 
-			config.App.MongoNotificationsDB, 
-			config.App.MongoNotificationsCollection)
+		config.App.MongoNotificationsDB,
+		mongo.Collections{
+			Notifications: config.App.MongoNotificationsCollection,
+			Templates:     config.App.MongoTemplatesCollection,
+			Preferences:   config.App.MongoPreferencesCollection,
+		})
 
 	if err != nil {
 		panic(err)
@@ -96,82 +195,158 @@ func initStorage(ctx context.Context) port.Storage {
 	return &storage
 }
 
+// initCache returns nil when the cache is disabled
 func initCache(ctx context.Context) port.Cache {
-	cache := redis.NewCache(ctx)
+	if !config.App.UseCache {
+		log.L(ctx).Info("cache disabled")
+		return nil
+	}
+
+	if config.App.CacheBackend == config.BackendMemory {
+		return memory.NewCache(ctx)
+	}
+
+	cache := redis.NewCache(ctx, config.App.RedisAddr, config.App.RedisPassword, config.App.RedisDB)
 
 	log.L(ctx).Debug("successfully initialized cache")
 	return &cache
 }
 
-func initNotificationService(ctx context.Context, storage port.Storage, cache port.Cache) port.Service {
-	service := service.NewService(ctx, storage, cache)
+// initSchemaRegistry returns nil when no registry is configured
+func initSchemaRegistry(ctx context.Context) port.SchemaRegistry {
+	if config.App.SchemaRegistryURL == "" {
+		log.L(ctx).Info("schema registry disabled. only json payloads are accepted")
+		return nil
+	}
+
+	registry := schemaregistry.NewRegistry(ctx, config.App.SchemaRegistryURL, config.App.SchemaRegistryUsername,
+		config.App.SchemaRegistryPassword, config.App.SchemaRegistryTimeout)
+
+	log.L(ctx).Debug("successfully initialized schema registry")
+
+	return registry
+}
+
+func initNotificationService(ctx context.Context, storage port.Storage, cache port.Cache,
+	templates port.TemplateStorage, preferences port.PreferencesStorage, health port.Health) port.Service {
+	service := service.NewService(ctx, storage, cache, templates, preferences, health)
 
 	return &service
 }
 
-func initEventsHub(ctx context.Context, service *port.Service) port.EventsHub {
-	eventsHub, err := redpanda.NewEventsHub(ctx, service, config.App.RedpandaBrokers, config.App.NotificationTopic, config.App.KafkaConsumerGroup)
+// initEventsHub builds the configured event source. every source shares the same processor. registry may be nil
+func initEventsHub(ctx context.Context, service *port.Service, registry port.SchemaRegistry) port.EventsHub {
+	processor := eventsource.NewProcessor(ctx, service, registry, config.App.EventRetryAttempts,
+		config.App.EventRetryBackoff)
+
+	var (
+		eventsHub port.EventsHub
+		err       error
+	)
+
+	switch config.App.EventSource {
+	case config.SourceNATS:
+		var hub nats.EventsHub
+		hub, err = nats.NewEventsHub(ctx, processor, config.App.NATSURL, config.App.NATSStream,
+			config.App.NATSSubject, config.App.NATSConsumer, config.App.NATSDeadLetterSubject, config.App.DrainTimeout)
+		eventsHub = &hub
+	case config.SourceAMQP:
+		var hub amqp.EventsHub
+		hub, err = amqp.NewEventsHub(ctx, processor, config.App.AMQPURL, config.App.AMQPQueue,
+			config.App.AMQPDeadLetterQueue, config.App.AMQPPrefetch, config.App.DrainTimeout)
+		eventsHub = &hub
+	case config.SourceFile:
+		eventsHub, err = file.NewEventsHub(ctx, processor, config.App.EventFile, config.App.EventDeadLetterFile,
+			config.App.DrainTimeout)
+	default:
+		var hub redpanda.EventsHub
+		hub, err = redpanda.NewEventsHub(ctx, processor, config.App.RedpandaBrokers, config.App.NotificationTopic,
+			config.App.KafkaConsumerGroup, config.App.DeadLetterTopic, config.App.DrainTimeout)
+		eventsHub = &hub
+	}
+
 	if err != nil {
 		panic(err)
 	}
 
-	log.L(ctx).Debug("successfully initialized eventshub")
+	log.L(ctx).Debug("successfully initialized eventshub", zap.String("source", config.App.EventSource))
 
-	return &eventsHub
+	return eventsHub
 }
 
-func initAPIController(ctx context.Context, service *port.Service) port.Controller {
-	controller := server.NewController(ctx, service)
+func initAPIController(ctx context.Context, service *port.Service, health port.Health,
+	metricsHandler http.Handler) port.Controller {
+	controller := server.NewController(ctx, service, health, server.IngestionConfig{
+		Tokens:   config.App.IngestionTokens,
+		MaxBatch: config.App.IngestionMaxBatch,
+	}, config.App.AdminTokens, config.App.APIPort, metricsHandler)
 
 	log.L(ctx).Debug("successfully initialized api controller")
 
 	return &controller
 }
 
+func initGRPCController(ctx context.Context, service *port.Service, health port.Health) port.Controller {
+	controller := grpcserver.NewController(ctx, service, health, config.App.IngestionTokens,
+		config.App.IngestionMaxBatch, config.App.AdminTokens, config.App.GRPCPort)
+
+	log.L(ctx).Debug("successfully initialized grpc controller")
+
+	return controller
+}
+
+func initScheduler(ctx context.Context, storage port.Storage, notifications port.Service) port.Runner {
+	scheduler := service.NewScheduler(ctx, storage, notifications, config.App.SchedulerInterval,
+		config.App.SchedulerLease)
+
+	log.L(ctx).Debug("successfully initialized scheduler")
+
+	return &scheduler
+}
+
+func initDigester(ctx context.Context, storage port.Storage) port.Runner {
+	digester := service.NewDigester(ctx, storage, config.App.DigestCheckInterval)
+
+	log.L(ctx).Debug("successfully initialized digester")
+
+	return &digester
+}
+
+func initConfigWatcher(ctx context.Context) port.Runner {
+	watcher := reload.NewWatcher(ctx, config.App.ConfigFile, config.App.ConfigPollInterval)
+
+	log.L(ctx).Debug("successfully initialized configuration watcher")
+
+	return &watcher
+}
+
 // implementing Run interface
 
 // Run starts the app. Blocking
 func (c *Container) Run(ctx context.Context) error {
 	log.L(ctx).Info("starting application container")
 
-	errCh := make(chan error, 3) // channel holds up errors
-
-	// spawn go func to consume events. each rourine pipes the error returned to errCh unless its a context.Canceled, which is alredy handled
-	go func() {
-		log.L(ctx).Info("events hub is running")
-
-		err := c.eventsHub.Run(ctx)
-		if err != nil && !errors.Is(err, context.Canceled){
-			errCh <- fmt.Errorf("Could not run eventsHub: %w", err)
-		}
-	}()
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
 
-	go func() {
-		log.L(ctx).Info("api controller is running")
+	errCh := make(chan error, len(c.lifecycle.components)) // channel holds up errors
 
-		err := c.controller.Run(ctx)
-		if err != nil && !errors.Is(err, context.Canceled) {
-			errCh <- fmt.Errorf("Could not run controller: %w", err)
-		}
-	}()
+	// each component pipes the error returned to errCh unless its a context.Canceled, which is alredy handled
+	if err := c.lifecycle.start(runCtx, errCh); err != nil {
+		log.L(ctx).Error("could not start container. Exiting App", zap.Error(err))
 
-	// start health probe
-	go func () {
-		time.Sleep(time.Second*5) // wait 5 seconds to start health check
-		err := c.initHealthCheck(ctx)
-		if err != nil && !errors.Is(err, context.Canceled) {
-			errCh <- fmt.Errorf("healthcheck error: %w", err)
-		}
-	}()
+		cancel()
+		return errors.Join(err, c.Close(context.Background()))
+	}
 
 	// wait for either an err is returned or context is canceled (main canceled the code)
 	select {
-	case err := <- errCh:  // error in the Run process
+	case err := <-errCh: // error in the Run process
 		log.L(ctx).Error("error running container. Exiting App", zap.Error(err))
 
-		_ = c.Close(ctx)
-		return err
-	case <- ctx.Done(): // context canceled
+		cancel()
+		return errors.Join(err, c.Close(context.Background()))
+	case <-ctx.Done(): // context canceled
 		ctx := context.Background()
 		log.L(ctx).Warn("context cancelled. Exiting App")
 
@@ -179,61 +354,17 @@ func (c *Container) Run(ctx context.Context) error {
 	}
 }
 
+// Close shuts every component down in reverse dependency order: api intake, consumer, workers, cache and
+// then storage. the returned error joins every failure
 func (c *Container) Close(ctx context.Context) error {
 	log.L(ctx).Info("trying to close and clean up resources")
 
-	errs := make([]error, 0)
-
-	for name, f := range c.cleanUpFuncs {
-		ctxWithTimeout, cancel := context.WithTimeout(ctx, time.Second*10)
-		defer cancel()
-
-		// tries to close the dependencies within 10 seconds. if its not successful, it cancels the context
-		if err := f(ctxWithTimeout); err != nil {
-			log.L(ctx).Error("could not gracefully shut down this dependency", zap.String(name, err.Error()))
-			errs = append(errs, err)
-
-			continue
-		}
-
-		log.L(ctx).Info("sucessfully claned up this dependency", zap.String("name", name))
-	}
-
-	// todo: somehow log or trace all errors here
-	if len(errs) > 0 {
-		log.L(ctx).Warn("could not properly clean up all resources")
+	err := c.lifecycle.stop(ctx)
+	if err != nil {
+		log.L(ctx).Warn("could not properly clean up all resources", zap.Error(err))
 	} else {
 		log.L(ctx).Info("all resources cleaned up")
 	}
 
-
-	return nil
+	return err
 }
-
-
-// initHealthCheck checks each 5 seconds for health status. TODO: maybe try for max attempts
-func (c * Container) initHealthCheck(ctx context.Context) error {
-	log.L(ctx).Info("starting healthcheck")
-	
-	for {
-		// avoid checking with canceled context
-		select {
-		case <-ctx.Done():
-			log.L(ctx).Warn("context canceled. exiting healthcheck")
-			return nil
-		default:
-		}
-
-		time.Sleep(time.Second*5)
-		// log.L(ctx).Debug("checking services health")
-
-		for name, checkHealth := range c.healthProbeFuncs {
-			ctx, cancel := context.WithTimeout(ctx, time.Second*5)
-			defer cancel()
-			// log.L(ctx).Debug("checking", zap.String("name", name))
-			if err := checkHealth(ctx); err != nil {
-				return fmt.Errorf("healthcheck failed for %s. error: %w", name, err)
-			}
-		}
-	}
-}
\ No newline at end of file
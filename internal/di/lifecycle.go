@@ -0,0 +1,231 @@
+package di
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	log "github.com/joseCarlosAndrade/notification-server/internal/core/domain/logger"
+	"go.uber.org/zap"
+)
+
+// readyPollInterval is how often a component that is not ready yet is probed again on startup
+const readyPollInterval = 100 * time.Millisecond
+
+// component is a piece of the application with its own lifecycle. run may be nil for dependencies that only
+// need to be closed, like tracing
+type component struct {
+	name      string
+	run       func(context.Context) error
+	close     Shutdown
+	dependsOn []string
+	timeout   time.Duration // how long close may take
+
+	ready   func(context.Context) error // readiness probe, nil if the component is ready once started
+	isReady bool
+	exited  chan struct{} // closed when run returns
+	err     error         // returned by run, set before exited is closed
+}
+
+// lifecycle starts the components once their dependencies are ready and closes them in reverse order, so nothing
+// is closed while something that uses it is still running
+type lifecycle struct {
+	components map[string]*component
+	registered []string // registration order, used to break ties so the order is always the same
+	order      []string // topological order, set by resolve
+
+	defaultTimeout time.Duration
+	startupTimeout time.Duration // how long each component may take to be ready
+}
+
+func newLifecycle(defaultTimeout, startupTimeout time.Duration) *lifecycle {
+	return &lifecycle{
+		components:     make(map[string]*component),
+		defaultTimeout: defaultTimeout,
+		startupTimeout: startupTimeout,
+	}
+}
+
+// add registers a component. dependsOn are the names of the components it uses while running
+func (l *lifecycle) add(name string, run func(context.Context) error, close Shutdown, dependsOn ...string) {
+	if _, ok := l.components[name]; !ok {
+		l.registered = append(l.registered, name)
+	}
+
+	l.components[name] = &component{
+		name:      name,
+		run:       run,
+		close:     close,
+		dependsOn: dependsOn,
+		timeout:   l.defaultTimeout,
+	}
+}
+
+// setTimeout overrides the close timeout of a component
+func (l *lifecycle) setTimeout(name string, timeout time.Duration) {
+	if c, ok := l.components[name]; ok && timeout > 0 {
+		c.timeout = timeout
+	}
+}
+
+// setReady sets the readiness probe of a component. its dependents are only started once the probe passes
+func (l *lifecycle) setReady(name string, ready func(context.Context) error) {
+	if c, ok := l.components[name]; ok {
+		c.ready = ready
+	}
+}
+
+// resolve sorts the components so every one comes after its dependencies. it fails on unknown
+// dependencies and cycles
+func (l *lifecycle) resolve() error {
+	pending := make(map[string]int, len(l.components)) // number of dependencies not sorted yet
+	dependents := make(map[string][]string, len(l.components))
+
+	for _, name := range l.registered {
+		c := l.components[name]
+		pending[name] = len(c.dependsOn)
+
+		for _, dependency := range c.dependsOn {
+			if _, ok := l.components[dependency]; !ok {
+				return fmt.Errorf("could not resolve %s: unknown dependency %s", name, dependency)
+			}
+
+			dependents[dependency] = append(dependents[dependency], name)
+		}
+	}
+
+	order := make([]string, 0, len(l.components))
+
+	for len(order) < len(l.components) {
+		next := ""
+
+		for _, name := range l.registered {
+			if count, ok := pending[name]; ok && count == 0 {
+				next = name
+				break
+			}
+		}
+
+		if next == "" {
+			return errors.New("could not resolve components: dependency cycle")
+		}
+
+		delete(pending, next)
+		order = append(order, next)
+
+		for _, dependent := range dependents[next] {
+			pending[dependent]--
+		}
+	}
+
+	l.order = order
+
+	return nil
+}
+
+// start runs every component in its own routine, in dependency order. each component is only started once
+// its dependencies are ready, and start fails if one is not ready within the startup timeout. errors other than
+// context.Canceled are sent to errCh, which must hold one error per component
+func (l *lifecycle) start(ctx context.Context, errCh chan<- error) error {
+	for _, name := range l.order {
+		c := l.components[name]
+
+		for _, dependency := range c.dependsOn {
+			if err := l.waitReady(ctx, l.components[dependency]); err != nil {
+				return fmt.Errorf("could not start %s: %w", name, err)
+			}
+		}
+
+		if c.run == nil {
+			continue
+		}
+
+		log.L(ctx).Info("starting component", zap.String("name", name))
+
+		c.exited = make(chan struct{})
+
+		go func() {
+			defer close(c.exited)
+
+			err := c.run(ctx)
+			if err != nil && !errors.Is(err, context.Canceled) {
+				c.err = err
+				errCh <- fmt.Errorf("could not run %s: %w", name, err)
+			}
+		}()
+	}
+
+	return nil
+}
+
+// waitReady probes the component until it is ready, its run fails or the startup timeout expires. components
+// whose run returns nil right away (like the storage) are still probed
+func (l *lifecycle) waitReady(ctx context.Context, c *component) error {
+	if c.ready == nil || c.isReady {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, l.startupTimeout)
+	defer cancel()
+
+	ticker := time.NewTicker(readyPollInterval)
+	defer ticker.Stop()
+
+	exited := c.exited
+
+	for {
+		err := c.ready(ctx)
+		if err == nil {
+			c.isReady = true
+			log.L(ctx).Info("component ready", zap.String("name", c.name))
+
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%s was not ready within %s: %w", c.name, l.startupTimeout, err)
+		case <-exited:
+			if c.err != nil {
+				return fmt.Errorf("%s failed before it was ready: %w", c.name, c.err)
+			}
+
+			exited = nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// stop closes every component in reverse dependency order. each close has its own timeout, so one stuck
+// dependency doesn't take the time of the others. every error is returned
+func (l *lifecycle) stop(ctx context.Context) error {
+	errs := make([]error, 0)
+
+	for i := len(l.order) - 1; i >= 0; i-- {
+		c := l.components[l.order[i]]
+		if c.close == nil {
+			continue
+		}
+
+		if err := l.stopComponent(ctx, c); err != nil {
+			log.L(ctx).Error("could not gracefully shut down this dependency",
+				zap.String("name", c.name),
+				zap.Error(err))
+
+			errs = append(errs, fmt.Errorf("could not close %s: %w", c.name, err))
+			continue
+		}
+
+		log.L(ctx).Info("successfully cleaned up this dependency", zap.String("name", c.name))
+	}
+
+	return errors.Join(errs...)
+}
+
+func (l *lifecycle) stopComponent(ctx context.Context, c *component) error {
+	ctxTimeout, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	return c.close(ctxTimeout)
+}
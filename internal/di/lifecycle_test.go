@@ -0,0 +1,81 @@
+package di
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestStartWaitsForDependencies(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var ready atomic.Bool
+	started := make(chan bool, 1)
+
+	l := newLifecycle(time.Second, time.Second)
+	l.add("storage", func(ctx context.Context) error {
+		time.Sleep(200 * time.Millisecond)
+		ready.Store(true)
+		<-ctx.Done()
+		return ctx.Err()
+	}, nil)
+	l.setReady("storage", func(context.Context) error {
+		if !ready.Load() {
+			return errors.New("not ready")
+		}
+		return nil
+	})
+	l.add("consumer", func(ctx context.Context) error {
+		started <- ready.Load()
+		return nil
+	}, nil, "storage")
+
+	if err := l.resolve(); err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+
+	if err := l.start(ctx, make(chan error, 2)); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+
+	if !<-started {
+		t.Error("consumer started before the storage was ready")
+	}
+}
+
+func TestStartFailsWhenDependenciesAreNotReady(t *testing.T) {
+	tests := []struct {
+		name string
+		run  func(ctx context.Context) error
+	}{
+		{"Timeout", func(ctx context.Context) error { <-ctx.Done(); return ctx.Err() }},
+		{"RunFailed", func(ctx context.Context) error { return errors.New("could not connect") }},
+		{"RunReturnedWithoutBeingReady", func(ctx context.Context) error { return nil }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			l := newLifecycle(time.Second, 300*time.Millisecond)
+			l.add("storage", tt.run, nil)
+			l.setReady("storage", func(context.Context) error { return errors.New("not ready") })
+			l.add("consumer", func(ctx context.Context) error {
+				t.Error("consumer started without its dependency")
+				return nil
+			}, nil, "storage")
+
+			if err := l.resolve(); err != nil {
+				t.Fatalf("resolve: %v", err)
+			}
+
+			if err := l.start(ctx, make(chan error, 2)); err == nil {
+				t.Error("start succeeded, want an error")
+			}
+		})
+	}
+}
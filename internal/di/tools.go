@@ -0,0 +1,116 @@
+package di
+
+import (
+	"context"
+	"errors"
+	"os"
+
+	"github.com/joseCarlosAndrade/notification-server/internal/adapter/amqp"
+	"github.com/joseCarlosAndrade/notification-server/internal/adapter/file"
+	"github.com/joseCarlosAndrade/notification-server/internal/adapter/nats"
+	"github.com/joseCarlosAndrade/notification-server/internal/adapter/redpanda"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/config"
+	log "github.com/joseCarlosAndrade/notification-server/internal/core/domain/logger"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain/models"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/domain/port"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/eventsource"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/health"
+	"github.com/joseCarlosAndrade/notification-server/internal/core/service"
+	"go.uber.org/zap"
+)
+
+// Tools holds the dependencies of the command line tools. they share the configuration of the server but run
+// none of its components, so nothing is consumed nor served
+type Tools struct {
+	Storage  port.Storage
+	Cache    port.Cache // nil when the cache is disabled
+	Service  port.Service
+	Registry port.SchemaRegistry // nil when no registry is configured
+}
+
+// NewTools connects to the configured dependencies. like NewContainer, it panics if anything crucial fails
+func NewTools(ctx context.Context) *Tools {
+	storage := initStorage(ctx)
+	cache := initCache(ctx)
+
+	// the tools re-ingest in bulk on purpose, so the producers rate limits don't apply
+	notificationService := service.NewService(ctx, storage, cache, storage, storage, nil)
+	notificationService.DisableRateLimit()
+
+	return &Tools{
+		Storage:  storage,
+		Cache:    cache,
+		Service:  &notificationService,
+		Registry: initSchemaRegistry(ctx),
+	}
+}
+
+// NewReplayer builds a replayer over the configured brokers with the processor the server uses
+func (t *Tools) NewReplayer(ctx context.Context) (*redpanda.Replayer, error) {
+	processor := eventsource.NewProcessor(ctx, &t.Service, t.Registry, config.App.EventRetryAttempts,
+		config.App.EventRetryBackoff)
+
+	return redpanda.NewReplayer(ctx, processor, config.App.RedpandaBrokers)
+}
+
+// Health probes every configured dependency once, with the names and criticality the server uses. the event
+// source is only checked for reachability: nothing is consumed
+func (t *Tools) Health(ctx context.Context) models.HealthReport {
+	monitor := health.NewMonitor(ctx, config.App.HealthCheckInterval, config.App.HealthCheckTimeout, 1)
+
+	monitor.Register("storage", t.Storage.IsHealthy, true)
+
+	if t.Cache != nil {
+		monitor.Register(service.CacheDependency, t.Cache.IsHealthy, false)
+	}
+
+	if t.Registry != nil {
+		monitor.Register("schemaRegistry", t.Registry.IsHealthy, false)
+	}
+
+	monitor.Register("eventsHub", pingEventSource, true)
+
+	return monitor.Probe(ctx)
+}
+
+// pingEventSource checks the configured event source without consuming from it
+func pingEventSource(ctx context.Context) error {
+	switch config.App.EventSource {
+	case config.SourceNATS:
+		return nats.Ping(ctx, config.App.NATSURL, config.App.NATSStream)
+	case config.SourceAMQP:
+		return amqp.Ping(ctx, config.App.AMQPURL, config.App.AMQPQueue)
+	case config.SourceFile:
+		if config.App.EventFile == file.Stdin {
+			return nil
+		}
+
+		_, err := os.Stat(config.App.EventFile)
+		return err
+	}
+
+	topics := config.App.TopicMappings.Topics()
+	for _, topic := range []string{config.App.NotificationTopic, config.App.DeadLetterTopic} {
+		if topic != "" {
+			topics = append(topics, topic)
+		}
+	}
+
+	return redpanda.Ping(ctx, config.App.RedpandaBrokers, topics...)
+}
+
+// Close disconnects from the dependencies
+func (t *Tools) Close(ctx context.Context) error {
+	var err error
+
+	if t.Cache != nil {
+		err = t.Cache.Close(ctx)
+	}
+
+	err = errors.Join(err, t.Storage.Close(ctx))
+	if err != nil {
+		log.L(ctx).Warn("could not close tools dependencies", zap.Error(err))
+	}
+
+	return err
+}